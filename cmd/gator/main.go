@@ -3,7 +3,7 @@
 //
 // Usage:
 //
-//	gator <command> [arguments...]
+//	gator [--config <path>] [--profile <name>] [--db-url <url>] [--set <key>=<value>] <command> [arguments...]
 //
 // Available commands include login, register, addfeed, follow, browse, and more.
 // Run 'gator' without arguments to see the full help.
@@ -11,16 +11,15 @@ package main
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
-	"log"
 	"os"
+	"os/signal"
+	"syscall"
 
-	_ "github.com/lib/pq"
 	"github.com/nhdewitt/blog-aggregator/internal/app"
 	"github.com/nhdewitt/blog-aggregator/internal/commands"
 	"github.com/nhdewitt/blog-aggregator/internal/config"
-	"github.com/nhdewitt/blog-aggregator/internal/database"
+	"github.com/nhdewitt/blog-aggregator/internal/store"
 )
 
 func main() {
@@ -31,34 +30,66 @@ func main() {
 }
 
 func run() error {
-	// Load configuration
-	c, err := config.Read()
+	// Split the global flags off the command
+	opts, args, err := config.ParseArgs(os.Args[1:])
 	if err != nil {
-		log.Fatal("Error reading config:", err)
+		return err
+	}
+	if len(args) < 1 {
+		commands.PrintHelp()
+		return nil
+	}
+
+	cmd := commands.Command{
+		Name:		args[0],
+		Args:			args[1:],
 	}
 
-	// Initialize database
-	d, err := sql.Open("postgres", c.DBUrl)
-	dbQueries := database.New(d)
-	defer d.Close()
+	// Load configuration: defaults, config file, GATOR_* variables, then flags
+	c, err := config.Load(opts)
+	if err != nil {
+		return fmt.Errorf("Error reading config: %w", err)
+	}
 
-	
-	// Create application state
+	// Cancel the command's context on Ctrl-C or a service stop.
+	// A second signal falls through to the default handler and exits immediately.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	context.AfterFunc(ctx, stop)
+
+	// Walk new users through creating a config file
+	if commands.NeedsSetup(&c, cmd) {
+		if err := commands.Setup(ctx, &c); err != nil {
+			return err
+		}
+	}
+
+	// Create application state. config and migrate run without the fetcher
+	// and the store, so they work while db_url or the fetch settings are wrong.
 	s := &app.State{
 		Cfg: &c,
-		Db: dbQueries,
 	}
 
-	// Parse command line arguments
-	if len(os.Args) < 2 {
-		commands.PrintHelp()
-		return nil
-	}
+	if commands.NeedsDatabase(cmd) {
+		// Create the shared HTTP fetcher
+		fetcher, err := app.NewFetcher(c.Fetch)
+		if err != nil {
+			return err
+		}
+		s.Fetcher = fetcher
 
-	cmd := commands.Command{
-		Name:		os.Args[1],
-		Args:			os.Args[2:],
+		// Open the database named by db_url, Postgres or a local SQLite file
+		dbURL, err := c.DatabaseURL()
+		if err != nil {
+			return err
+		}
+		db, err := store.Open(ctx, dbURL)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		s.Db = db
 	}
 
-	return commands.Execute(context.Background(), s, cmd)
+	return commands.Execute(ctx, s, cmd)
 }
\ No newline at end of file
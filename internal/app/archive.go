@@ -0,0 +1,285 @@
+// Package app contains shared application services and state management.
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/nhdewitt/blog-aggregator/internal/database"
+)
+
+// Backup archive format identifiers. Readers accept any version up to archiveVersion.
+// Version 1 archives held the database models as encoding/json wrote them;
+// version 2 holds the archive row types in archive_rows.go and adds feed URL history.
+const (
+	archiveFormat	= "gator-backup"
+	archiveVersion	= 2
+)
+
+// archiveHeader is the first line of a backup archive.
+type archiveHeader struct {
+	Format		string		`json:"format"`
+	Version		int			`json:"version"`
+	CreatedAt	time.Time	`json:"created_at"`
+}
+
+// archiveRecord is every line after the header: one row of one table.
+type archiveRecord struct {
+	Table	string			`json:"table"`
+	Row		json.RawMessage	`json:"row"`
+}
+
+// archiveTables lists the tables in a backup in the order they are written and
+// restored, parents before the rows that refer to them.
+var archiveTables = []string{"users", "folders", "feeds", "feed_url_history", "feed_follows", "posts", "filter_rules", "post_reads", "saved_posts"}
+
+// WriteBackup saves users, feeds with their URL history, follows, posts and every user's folders,
+// filter rules, read markers and saved posts to a gzip-compressed JSON-lines archive at path.
+// The first line is a header with the format version; each following line holds
+// one row. Rows are read in a single transaction so the archive is consistent.
+//
+// Returns the number of rows written per table, or an error. A partially
+// written file is removed.
+func WriteBackup(ctx context.Context, s *State, path string) ([]RestoreCount, error) {
+	var snap Snapshot
+	err := s.InTx(ctx, func(q Store) error {
+		return collectAll(ctx, q, &snap)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("Error creating backup: %w", err)
+	}
+
+	counts, err := writeArchive(f, &snap)
+	if closeErr := f.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("Error writing backup: %w", closeErr)
+	}
+	if err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	return counts, nil
+}
+
+// writeArchive encodes snap as a compressed archive to w.
+func writeArchive(w io.Writer, snap *Snapshot) ([]RestoreCount, error) {
+	gz := gzip.NewWriter(w)
+	buf := bufio.NewWriter(gz)
+	encoder := json.NewEncoder(buf)
+
+	err := encoder.Encode(archiveHeader{Format: archiveFormat, Version: archiveVersion, CreatedAt: time.Now().UTC()})
+	if err != nil {
+		return nil, fmt.Errorf("Error writing backup: %w", err)
+	}
+
+	tables := map[string][]any{
+		"users": archived(snap.Users, archiveUserFrom),
+		"folders": archived(snap.Folders, archiveFolderFrom),
+		"feeds": archived(snap.Feeds, archiveFeedFrom),
+		"feed_url_history": archived(snap.FeedURLHistory, archiveFeedURLChangeFrom),
+		"feed_follows": archived(snap.FeedFollows, archiveFeedFollowFrom),
+		"posts": archived(snap.Posts, archivePostFrom),
+		"filter_rules": archived(snap.FilterRules, archiveFilterRuleFrom),
+		"post_reads": archived(snap.PostReads, archivePostReadFrom),
+		"saved_posts": archived(snap.SavedPosts, archiveSavedPostFrom),
+	}
+
+	var counts []RestoreCount
+	for _, table := range archiveTables {
+		for _, row := range tables[table] {
+			data, err := json.Marshal(row)
+			if err != nil {
+				return nil, fmt.Errorf("Error encoding %s: %w", table, err)
+			}
+			if err := encoder.Encode(archiveRecord{Table: table, Row: data}); err != nil {
+				return nil, fmt.Errorf("Error writing backup: %w", err)
+			}
+		}
+		counts = append(counts, RestoreCount{Table: table, Restored: len(tables[table])})
+	}
+
+	if err := buf.Flush(); err != nil {
+		return nil, fmt.Errorf("Error writing backup: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("Error writing backup: %w", err)
+	}
+	return counts, nil
+}
+
+// RestoreFile restores either a backup archive written by WriteBackup or a
+// reset snapshot, telling them apart by the gzip header. Rows keep their
+// original IDs and rows that already exist are skipped, so restoring the same
+// file again changes nothing. Everything happens in a single transaction.
+//
+// Returns per-table counts, or an error if the file is unreadable, from a
+// newer version of gator, or the database rejects a row.
+func RestoreFile(ctx context.Context, s *State, path string) ([]RestoreCount, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("Error opening %s: %w", path, err)
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	magic, err := reader.Peek(2)
+	if err != nil || !bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		// Not compressed, so it should be a reset snapshot
+		snap, err := ReadSnapshot(path)
+		if err != nil {
+			return nil, err
+		}
+		return RestoreSnapshot(ctx, s, snap)
+	}
+
+	gz, err := gzip.NewReader(reader)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading backup: %w", err)
+	}
+	defer gz.Close()
+
+	decoder := json.NewDecoder(gz)
+	var header archiveHeader
+	if err := decoder.Decode(&header); err != nil {
+		return nil, fmt.Errorf("Error reading backup header: %w", err)
+	}
+	if header.Format != archiveFormat {
+		return nil, fmt.Errorf("%s is not a gator backup", path)
+	}
+	if header.Version < 1 || header.Version > archiveVersion {
+		return nil, fmt.Errorf("Unsupported backup version %d", header.Version)
+	}
+
+	var r *restorer
+	err = s.InTx(ctx, func(q Store) error {
+		r = newRestorer(q)
+		for {
+			var record archiveRecord
+			err := decoder.Decode(&record)
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("Error reading backup: %w", err)
+			}
+
+			row, err := decodeArchiveRow(record, header.Version)
+			if err != nil {
+				return err
+			}
+			if err := r.restore(ctx, row); err != nil {
+				return err
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.counts, nil
+}
+
+// decodeArchiveRow turns an archive record from a backup of the given
+// version into the model type for its table.
+func decodeArchiveRow(record archiveRecord, version int) (any, error) {
+	if version == 1 {
+		return decodeLegacyRow(record)
+	}
+
+	var row any
+	var err error
+	switch record.Table {
+	case "users":
+		row, err = decodeArchived[archiveUser](record.Row)
+	case "folders":
+		row, err = decodeArchived[archiveFolder](record.Row)
+	case "feeds":
+		row, err = decodeArchived[archiveFeed](record.Row)
+	case "feed_url_history":
+		row, err = decodeArchived[archiveFeedURLChange](record.Row)
+	case "feed_follows":
+		row, err = decodeArchived[archiveFeedFollow](record.Row)
+	case "posts":
+		row, err = decodeArchived[archivePost](record.Row)
+	case "filter_rules":
+		row, err = decodeArchived[archiveFilterRule](record.Row)
+	case "post_reads":
+		row, err = decodeArchived[archivePostRead](record.Row)
+	case "saved_posts":
+		row, err = decodeArchived[archiveSavedPost](record.Row)
+	default:
+		return nil, fmt.Errorf("Unknown table %q in backup", record.Table)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error decoding %s row: %w", record.Table, err)
+	}
+	return row, nil
+}
+
+// decodeLegacyRow decodes a record from a version 1 archive, whose rows are
+// the database models as they were when that version was current.
+func decodeLegacyRow(record archiveRecord) (any, error) {
+	var row any
+	var err error
+	switch record.Table {
+	case "users":
+		row, err = decodeRow[database.User](record.Row)
+	case "folders":
+		row, err = decodeRow[database.Folder](record.Row)
+	case "feeds":
+		row, err = decodeRow[database.Feed](record.Row)
+	case "feed_follows":
+		row, err = decodeRow[database.FeedFollow](record.Row)
+	case "posts":
+		row, err = decodeRow[database.Post](record.Row)
+	case "filter_rules":
+		row, err = decodeRow[database.FilterRule](record.Row)
+	case "post_reads":
+		row, err = decodeRow[database.PostRead](record.Row)
+	case "saved_posts":
+		row, err = decodeRow[database.SavedPost](record.Row)
+	default:
+		return nil, fmt.Errorf("Unknown table %q in backup", record.Table)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error decoding %s row: %w", record.Table, err)
+	}
+	return row, nil
+}
+
+// decodeRow unmarshals one archived row into T.
+func decodeRow[T any](data json.RawMessage) (T, error) {
+	var row T
+	err := json.Unmarshal(data, &row)
+	return row, err
+}
+
+// decodeArchived unmarshals one archived row into the archive row type T and
+// returns the model it converts to.
+func decodeArchived[T interface{ model() any }](data json.RawMessage) (any, error) {
+	row, err := decodeRow[T](data)
+	if err != nil {
+		return nil, err
+	}
+	return row.model(), nil
+}
+
+// archived converts model rows to their archive row types, as []any for writeArchive.
+func archived[T, R any](rows []T, convert func(T) R) []any {
+	out := make([]any, len(rows))
+	for i, row := range rows {
+		out[i] = convert(row)
+	}
+	return out
+}
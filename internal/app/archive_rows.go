@@ -0,0 +1,352 @@
+// Package app contains shared application services and state management.
+package app
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nhdewitt/blog-aggregator/internal/database"
+)
+
+// The archive row types fix the layout of each table in a backup, apart from
+// the models sqlc generates, so regenerating them or renaming a column can't
+// change what an existing archive means. NULL columns are pointers, left out
+// of the row when NULL. Each type converts to its database model with model.
+
+type archiveUser struct {
+	ID				uuid.UUID	`json:"id"`
+	CreatedAt		time.Time	`json:"created_at"`
+	UpdatedAt		time.Time	`json:"updated_at"`
+	Name			string		`json:"name"`
+	PasswordHash	*string		`json:"password_hash,omitempty"`
+	Role			string		`json:"role"`
+}
+
+func archiveUserFrom(v database.User) archiveUser {
+	return archiveUser{
+		ID: v.ID,
+		CreatedAt: v.CreatedAt,
+		UpdatedAt: v.UpdatedAt,
+		Name: v.Name,
+		PasswordHash: stringPtr(v.PasswordHash),
+		Role: v.Role,
+	}
+}
+
+func (r archiveUser) model() any {
+	return database.User{
+		ID: r.ID,
+		CreatedAt: r.CreatedAt,
+		UpdatedAt: r.UpdatedAt,
+		Name: r.Name,
+		PasswordHash: nullString(r.PasswordHash),
+		Role: r.Role,
+	}
+}
+
+type archiveFolder struct {
+	ID			uuid.UUID	`json:"id"`
+	CreatedAt	time.Time	`json:"created_at"`
+	UpdatedAt	time.Time	`json:"updated_at"`
+	Name		string		`json:"name"`
+	UserID		uuid.UUID	`json:"user_id"`
+}
+
+func archiveFolderFrom(v database.Folder) archiveFolder {
+	return archiveFolder{ID: v.ID, CreatedAt: v.CreatedAt, UpdatedAt: v.UpdatedAt, Name: v.Name, UserID: v.UserID}
+}
+
+func (r archiveFolder) model() any {
+	return database.Folder{ID: r.ID, CreatedAt: r.CreatedAt, UpdatedAt: r.UpdatedAt, Name: r.Name, UserID: r.UserID}
+}
+
+type archiveFeed struct {
+	ID					uuid.UUID	`json:"id"`
+	CreatedAt			time.Time	`json:"created_at"`
+	UpdatedAt			time.Time	`json:"updated_at"`
+	LastFetchedAt		*time.Time	`json:"last_fetched_at,omitempty"`
+	Name				string		`json:"name"`
+	URL					string		`json:"url"`
+	UserID				uuid.UUID	`json:"user_id"`
+	FetchFullText		bool		`json:"fetch_full_text"`
+	FetchInterval		*int32		`json:"fetch_interval,omitempty"`	// seconds
+	AdaptiveInterval	bool		`json:"adaptive_interval"`
+	NextFetchAt			*time.Time	`json:"next_fetch_at,omitempty"`
+	TTLMinutes			*int32		`json:"ttl_minutes,omitempty"`
+	SkipHours			*string		`json:"skip_hours,omitempty"`
+	SkipDays			*string		`json:"skip_days,omitempty"`
+	CacheMaxAge			*int32		`json:"cache_max_age,omitempty"`	// seconds
+	RetryAfter			*time.Time	`json:"retry_after,omitempty"`
+	ParseWarnings		*string		`json:"parse_warnings,omitempty"`
+	RetentionMaxAge		*int32		`json:"retention_max_age,omitempty"`	// seconds
+	RetentionMaxPosts	*int32		`json:"retention_max_posts,omitempty"`
+}
+
+func archiveFeedFrom(v database.Feed) archiveFeed {
+	return archiveFeed{
+		ID: v.ID,
+		CreatedAt: v.CreatedAt,
+		UpdatedAt: v.UpdatedAt,
+		LastFetchedAt: timePtr(v.LastFetchedAt),
+		Name: v.Name,
+		URL: v.Url,
+		UserID: v.UserID,
+		FetchFullText: v.FetchFullText,
+		FetchInterval: int32Ptr(v.FetchInterval),
+		AdaptiveInterval: v.AdaptiveInterval,
+		NextFetchAt: timePtr(v.NextFetchAt),
+		TTLMinutes: int32Ptr(v.TtlMinutes),
+		SkipHours: stringPtr(v.SkipHours),
+		SkipDays: stringPtr(v.SkipDays),
+		CacheMaxAge: int32Ptr(v.CacheMaxAge),
+		RetryAfter: timePtr(v.RetryAfter),
+		ParseWarnings: stringPtr(v.ParseWarnings),
+		RetentionMaxAge: int32Ptr(v.RetentionMaxAge),
+		RetentionMaxPosts: int32Ptr(v.RetentionMaxPosts),
+	}
+}
+
+func (r archiveFeed) model() any {
+	return database.Feed{
+		ID: r.ID,
+		CreatedAt: r.CreatedAt,
+		UpdatedAt: r.UpdatedAt,
+		LastFetchedAt: nullTime(r.LastFetchedAt),
+		Name: r.Name,
+		Url: r.URL,
+		UserID: r.UserID,
+		FetchFullText: r.FetchFullText,
+		FetchInterval: nullInt32(r.FetchInterval),
+		AdaptiveInterval: r.AdaptiveInterval,
+		NextFetchAt: nullTime(r.NextFetchAt),
+		TtlMinutes: nullInt32(r.TTLMinutes),
+		SkipHours: nullString(r.SkipHours),
+		SkipDays: nullString(r.SkipDays),
+		CacheMaxAge: nullInt32(r.CacheMaxAge),
+		RetryAfter: nullTime(r.RetryAfter),
+		ParseWarnings: nullString(r.ParseWarnings),
+		RetentionMaxAge: nullInt32(r.RetentionMaxAge),
+		RetentionMaxPosts: nullInt32(r.RetentionMaxPosts),
+	}
+}
+
+type archiveFeedURLChange struct {
+	ID			uuid.UUID	`json:"id"`
+	ChangedAt	time.Time	`json:"changed_at"`
+	FeedID		uuid.UUID	`json:"feed_id"`
+	OldURL		string		`json:"old_url"`
+	NewURL		string		`json:"new_url"`
+	Merged		bool		`json:"merged"`
+}
+
+func archiveFeedURLChangeFrom(v database.FeedUrlHistory) archiveFeedURLChange {
+	return archiveFeedURLChange{ID: v.ID, ChangedAt: v.ChangedAt, FeedID: v.FeedID, OldURL: v.OldUrl, NewURL: v.NewUrl, Merged: v.Merged}
+}
+
+func (r archiveFeedURLChange) model() any {
+	return database.FeedUrlHistory{ID: r.ID, ChangedAt: r.ChangedAt, FeedID: r.FeedID, OldUrl: r.OldURL, NewUrl: r.NewURL, Merged: r.Merged}
+}
+
+type archiveFeedFollow struct {
+	ID			uuid.UUID	`json:"id"`
+	CreatedAt	time.Time	`json:"created_at"`
+	UpdatedAt	time.Time	`json:"updated_at"`
+	UserID		uuid.UUID	`json:"user_id"`
+	FeedID		uuid.UUID	`json:"feed_id"`
+	FolderID	*uuid.UUID	`json:"folder_id,omitempty"`
+	DisplayName	*string		`json:"display_name,omitempty"`
+}
+
+func archiveFeedFollowFrom(v database.FeedFollow) archiveFeedFollow {
+	return archiveFeedFollow{
+		ID: v.ID,
+		CreatedAt: v.CreatedAt,
+		UpdatedAt: v.UpdatedAt,
+		UserID: v.UserID,
+		FeedID: v.FeedID,
+		FolderID: uuidPtr(v.FolderID),
+		DisplayName: stringPtr(v.DisplayName),
+	}
+}
+
+func (r archiveFeedFollow) model() any {
+	return database.FeedFollow{
+		ID: r.ID,
+		CreatedAt: r.CreatedAt,
+		UpdatedAt: r.UpdatedAt,
+		UserID: r.UserID,
+		FeedID: r.FeedID,
+		FolderID: nullUUID(r.FolderID),
+		DisplayName: nullString(r.DisplayName),
+	}
+}
+
+type archivePost struct {
+	ID			uuid.UUID	`json:"id"`
+	CreatedAt	time.Time	`json:"created_at"`
+	UpdatedAt	time.Time	`json:"updated_at"`
+	Title		string		`json:"title"`
+	URL			string		`json:"url"`
+	Description	*string		`json:"description,omitempty"`
+	PublishedAt	time.Time	`json:"published_at"`
+	FeedID		uuid.UUID	`json:"feed_id"`
+	Content		*string		`json:"content,omitempty"`
+	Author		*string		`json:"author,omitempty"`
+}
+
+func archivePostFrom(v database.Post) archivePost {
+	return archivePost{
+		ID: v.ID,
+		CreatedAt: v.CreatedAt,
+		UpdatedAt: v.UpdatedAt,
+		Title: v.Title,
+		URL: v.Url,
+		Description: stringPtr(v.Description),
+		PublishedAt: v.PublishedAt,
+		FeedID: v.FeedID,
+		Content: stringPtr(v.Content),
+		Author: stringPtr(v.Author),
+	}
+}
+
+func (r archivePost) model() any {
+	return database.Post{
+		ID: r.ID,
+		CreatedAt: r.CreatedAt,
+		UpdatedAt: r.UpdatedAt,
+		Title: r.Title,
+		Url: r.URL,
+		Description: nullString(r.Description),
+		PublishedAt: r.PublishedAt,
+		FeedID: r.FeedID,
+		Content: nullString(r.Content),
+		Author: nullString(r.Author),
+	}
+}
+
+type archiveFilterRule struct {
+	ID			uuid.UUID	`json:"id"`
+	CreatedAt	time.Time	`json:"created_at"`
+	UpdatedAt	time.Time	`json:"updated_at"`
+	UserID		uuid.UUID	`json:"user_id"`
+	FeedID		*uuid.UUID	`json:"feed_id,omitempty"`
+	Pattern		string		`json:"pattern"`
+	IsRegex		bool		`json:"is_regex"`
+	Field		string		`json:"field"`
+	Action		string		`json:"action"`
+}
+
+func archiveFilterRuleFrom(v database.FilterRule) archiveFilterRule {
+	return archiveFilterRule{
+		ID: v.ID,
+		CreatedAt: v.CreatedAt,
+		UpdatedAt: v.UpdatedAt,
+		UserID: v.UserID,
+		FeedID: uuidPtr(v.FeedID),
+		Pattern: v.Pattern,
+		IsRegex: v.IsRegex,
+		Field: v.Field,
+		Action: v.Action,
+	}
+}
+
+func (r archiveFilterRule) model() any {
+	return database.FilterRule{
+		ID: r.ID,
+		CreatedAt: r.CreatedAt,
+		UpdatedAt: r.UpdatedAt,
+		UserID: r.UserID,
+		FeedID: nullUUID(r.FeedID),
+		Pattern: r.Pattern,
+		IsRegex: r.IsRegex,
+		Field: r.Field,
+		Action: r.Action,
+	}
+}
+
+type archivePostRead struct {
+	UserID	uuid.UUID	`json:"user_id"`
+	PostID	uuid.UUID	`json:"post_id"`
+	ReadAt	time.Time	`json:"read_at"`
+}
+
+func archivePostReadFrom(v database.PostRead) archivePostRead {
+	return archivePostRead{UserID: v.UserID, PostID: v.PostID, ReadAt: v.ReadAt}
+}
+
+func (r archivePostRead) model() any {
+	return database.PostRead{UserID: r.UserID, PostID: r.PostID, ReadAt: r.ReadAt}
+}
+
+type archiveSavedPost struct {
+	UserID	uuid.UUID	`json:"user_id"`
+	PostID	uuid.UUID	`json:"post_id"`
+	SavedAt	time.Time	`json:"saved_at"`
+}
+
+func archiveSavedPostFrom(v database.SavedPost) archiveSavedPost {
+	return archiveSavedPost{UserID: v.UserID, PostID: v.PostID, SavedAt: v.SavedAt}
+}
+
+func (r archiveSavedPost) model() any {
+	return database.SavedPost{UserID: r.UserID, PostID: r.PostID, SavedAt: r.SavedAt}
+}
+
+// stringPtr and the functions below convert between nullable columns and
+// the pointers archive rows use for them.
+func stringPtr(v sql.NullString) *string {
+	if !v.Valid {
+		return nil
+	}
+	return &v.String
+}
+
+func nullString(p *string) sql.NullString {
+	if p == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: *p, Valid: true}
+}
+
+func int32Ptr(v sql.NullInt32) *int32 {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Int32
+}
+
+func nullInt32(p *int32) sql.NullInt32 {
+	if p == nil {
+		return sql.NullInt32{}
+	}
+	return sql.NullInt32{Int32: *p, Valid: true}
+}
+
+func timePtr(v sql.NullTime) *time.Time {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Time
+}
+
+func nullTime(p *time.Time) sql.NullTime {
+	if p == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: *p, Valid: true}
+}
+
+func uuidPtr(v uuid.NullUUID) *uuid.UUID {
+	if !v.Valid {
+		return nil
+	}
+	return &v.UUID
+}
+
+func nullUUID(p *uuid.UUID) uuid.NullUUID {
+	if p == nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: *p, Valid: true}
+}
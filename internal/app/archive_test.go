@@ -0,0 +1,309 @@
+package app_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nhdewitt/blog-aggregator/internal/app"
+	"github.com/nhdewitt/blog-aggregator/internal/config"
+	"github.com/nhdewitt/blog-aggregator/internal/database"
+	"github.com/nhdewitt/blog-aggregator/internal/store"
+)
+
+// newEmptyState returns a State with an empty in-memory store.
+func newEmptyState(t *testing.T) *app.State {
+	t.Helper()
+	return &app.State{Cfg: &config.Config{}, Db: store.NewMemory()}
+}
+
+// sqliteState returns a State backed by a freshly migrated SQLite file.
+func sqliteState(t *testing.T) *app.State {
+	t.Helper()
+	ctx := context.Background()
+	dbURL := "sqlite://" + filepath.Join(t.TempDir(), "gator.db")
+	m, err := store.OpenMigrator(ctx, dbURL)
+	if err != nil {
+		t.Fatalf("Error opening database: %v", err)
+	}
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatalf("Error migrating database: %v", err)
+	}
+	m.Close()
+
+	db, err := store.Open(ctx, dbURL)
+	if err != nil {
+		t.Fatalf("Error opening store: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return &app.State{Cfg: &config.Config{}, Db: db}
+}
+
+// dumpRows returns every row of every table as sorted JSON lines, so two
+// databases can be compared regardless of row order.
+func dumpRows(t *testing.T, s *app.State) string {
+	t.Helper()
+	ctx := context.Background()
+	var lines []string
+	add := func(table string, rows any, err error) {
+		if err != nil {
+			t.Fatalf("Error listing %s: %v", table, err)
+		}
+		data, err := json.Marshal(rows)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var raw []json.RawMessage
+		json.Unmarshal(data, &raw)
+		for _, row := range raw {
+			lines = append(lines, table+" "+string(row))
+		}
+	}
+	users, err := s.Db.GetUsers(ctx)
+	add("users", users, err)
+	folders, err := s.Db.ListFolders(ctx)
+	add("folders", folders, err)
+	feeds, err := s.Db.ListFeeds(ctx)
+	add("feeds", feeds, err)
+	history, err := s.Db.ListFeedURLHistory(ctx)
+	add("feed_url_history", history, err)
+	follows, err := s.Db.ListFeedFollows(ctx)
+	add("feed_follows", follows, err)
+	posts, err := s.Db.ListPosts(ctx)
+	add("posts", posts, err)
+	reads, err := s.Db.ListPostReads(ctx)
+	add("post_reads", reads, err)
+	rules, err := s.Db.ListFilterRules(ctx)
+	add("filter_rules", rules, err)
+	saved, err := s.Db.ListSavedPosts(ctx)
+	add("saved_posts", saved, err)
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
+
+func TestBackupRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	src, _, bob := resetFixture(t)
+	path := filepath.Join(t.TempDir(), "gator.backup")
+
+	// Per-user state rides along with the shared tables
+	_, err := src.Db.CreateFolder(ctx, database.CreateFolderParams{
+		ID: uuid.New(),
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+		Name: "Tech",
+		UserID: bob.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = src.Db.CreateFilterRule(ctx, database.CreateFilterRuleParams{
+		ID: uuid.New(),
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+		UserID: bob.ID,
+		Pattern: "sponsored",
+		Field: app.RuleFieldTitle,
+		Action: app.RuleActionHide,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	feeds, err := src.Db.ListFeeds(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = src.Db.RecordFeedURLChange(ctx, database.RecordFeedURLChangeParams{
+		ID: uuid.New(),
+		FeedID: feeds[0].ID,
+		OldUrl: "http://a.example/rss",
+		NewUrl: feeds[0].Url,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	counts, err := app.WriteBackup(ctx, src, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	written := make(map[string]int)
+	for _, c := range counts {
+		written[c.Table] = c.Restored
+	}
+	if written["users"] != 2 || written["folders"] != 1 || written["posts"] != 3 || written["filter_rules"] != 1 || written["saved_posts"] != 1 || written["feed_url_history"] != 1 {
+		t.Errorf("WriteBackup counts = %+v", counts)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("backup file = %v, %v; want permissions 600", info, err)
+	}
+
+	// Restoring into another backend keeps every row and ID as it was
+	want := dumpRows(t, src)
+	for name, dst := range map[string]*app.State{"memory": newEmptyState(t), "sqlite": sqliteState(t)} {
+		if _, err := app.RestoreFile(ctx, dst, path); err != nil {
+			t.Fatalf("%s: RestoreFile: %v", name, err)
+		}
+		if got := dumpRows(t, dst); got != want {
+			t.Errorf("%s: restored rows differ\ngot:\n%s\nwant:\n%s", name, got, want)
+		}
+
+		// A second restore finds everything in place
+		counts, err := app.RestoreFile(ctx, dst, path)
+		if err != nil {
+			t.Fatalf("%s: second RestoreFile: %v", name, err)
+		}
+		for _, c := range counts {
+			if c.Restored != 0 {
+				t.Errorf("%s: second restore inserted %d %s", name, c.Restored, c.Table)
+			}
+		}
+	}
+}
+
+func TestBackupRowFormat(t *testing.T) {
+	ctx := context.Background()
+	src, _, _ := resetFixture(t)
+	path := filepath.Join(t.TempDir(), "gator.backup")
+	if _, err := app.WriteBackup(ctx, src, path); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoder := json.NewDecoder(gz)
+	var header struct{ Version int }
+	if err := decoder.Decode(&header); err != nil || header.Version != 2 {
+		t.Fatalf("header = %+v, %v; want version 2", header, err)
+	}
+
+	// Rows use fixed snake_case keys, and NULL columns are left out rather
+	// than written as sql.Null* objects
+	want := map[string][]string{
+		"users": {"created_at", "id", "name", "role", "updated_at"},
+		"feeds": {"adaptive_interval", "created_at", "fetch_full_text", "id", "name", "updated_at", "url", "user_id"},
+		"posts": {"created_at", "feed_id", "id", "published_at", "title", "updated_at", "url"},
+	}
+	for {
+		var record struct {
+			Table	string
+			Row		map[string]json.RawMessage
+		}
+		if err := decoder.Decode(&record); err != nil {
+			break
+		}
+		keys, ok := want[record.Table]
+		if !ok {
+			continue
+		}
+		var got []string
+		for key := range record.Row {
+			got = append(got, key)
+		}
+		sort.Strings(got)
+		if strings.Join(got, " ") != strings.Join(keys, " ") {
+			t.Errorf("%s row keys = %v, want %v", record.Table, got, keys)
+		}
+	}
+}
+
+func TestRestoreVersion1Backup(t *testing.T) {
+	ctx := context.Background()
+	var b bytes.Buffer
+	gz := gzip.NewWriter(&b)
+	gz.Write([]byte(strings.Join([]string{
+		`{"format":"gator-backup","version":1}`,
+		`{"table":"users","row":{"ID":"6f1c7a52-5b57-4c6e-9d0e-8f0a1c2b3d4e","Name":"alice","PasswordHash":{"String":"hash","Valid":true},"Role":"admin"}}`,
+	}, "\n")))
+	gz.Close()
+	path := filepath.Join(t.TempDir(), "old.backup")
+	if err := os.WriteFile(path, b.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	s := newEmptyState(t)
+	if _, err := app.RestoreFile(ctx, s, path); err != nil {
+		t.Fatal(err)
+	}
+	users, err := s.Db.GetUsers(ctx)
+	if err != nil || len(users) != 1 || users[0].Name != "alice" || users[0].PasswordHash.String != "hash" {
+		t.Errorf("users after restoring a version 1 backup = %+v, %v", users, err)
+	}
+}
+
+func TestRestoreFileRejects(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	gzipped := func(lines ...string) []byte {
+		var b bytes.Buffer
+		gz := gzip.NewWriter(&b)
+		gz.Write([]byte(strings.Join(lines, "\n")))
+		gz.Close()
+		return b.Bytes()
+	}
+	header := `{"format":"gator-backup","version":1}`
+	user := `{"table":"users","row":{"ID":"6f1c7a52-5b57-4c6e-9d0e-8f0a1c2b3d4e","Name":"alice","Role":"admin"}}`
+
+	tests := []struct {
+		name	string
+		data	[]byte
+		want	string
+	}{
+		{"not a backup", []byte("hello"), "Error decoding snapshot"},
+		{"other gzip file", gzipped(`{"format":"something-else","version":1}`), "is not a gator backup"},
+		{"newer version", gzipped(`{"format":"gator-backup","version":99}`), "Unsupported backup version 99"},
+		{"unknown table", gzipped(header, user, `{"table":"secrets","row":{}}`), `Unknown table "secrets"`},
+		{"bad row", gzipped(header, user, `{"table":"feeds","row":[]}`), "Error decoding feeds row"},
+		{"truncated", gzipped(header, user, `{"table":"feeds","row":{`), "Error reading backup"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(dir, strings.ReplaceAll(tt.name, " ", "-"))
+		if err := os.WriteFile(path, tt.data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		s := newEmptyState(t)
+		_, err := app.RestoreFile(ctx, s, path)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: RestoreFile = %v, want an error containing %q", tt.name, err, tt.want)
+			continue
+		}
+		// Nothing from a rejected file is kept, even rows before the bad one
+		if users, _ := s.Db.GetUsers(ctx); len(users) != 0 {
+			t.Errorf("%s: %d users restored from a rejected file", tt.name, len(users))
+		}
+	}
+}
+
+func TestRestoreSkipsOrphanedRows(t *testing.T) {
+	for name, s := range map[string]*app.State{"memory": newEmptyState(t), "sqlite": sqliteState(t)} {
+		snap := &app.Snapshot{Version: 1, Posts: []database.Post{{
+			ID: uuid.New(),
+			Title: "Lost",
+			Url: "https://gone.example/lost",
+			PublishedAt: time.Now().UTC(),
+			FeedID: uuid.New(),
+		}}}
+		counts, err := app.RestoreSnapshot(context.Background(), s, snap)
+		if err != nil {
+			t.Fatalf("%s: RestoreSnapshot: %v", name, err)
+		}
+		if len(counts) != 1 || counts[0] != (app.RestoreCount{Table: "posts", Skipped: 1}) {
+			t.Errorf("%s: RestoreSnapshot counts = %+v, want the post without a feed skipped", name, counts)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+// Package app contains shared application services and state management.
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nhdewitt/blog-aggregator/internal/database"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// sessionTTL is how long a login stays valid.
+const sessionTTL = 30 * 24 * time.Hour
+
+// User roles. Admins can run destructive and user management commands.
+const (
+	RoleAdmin	= "admin"
+	RoleUser	= "user"
+)
+
+// minPasswordLength is the shortest password accepted when one is set.
+const minPasswordLength = 8
+
+// Errors returned while authenticating.
+var (
+	ErrNotLoggedIn		= errors.New("not logged in, run 'gator login <username>'")
+	ErrSessionExpired	= errors.New("session expired or logged out, run 'gator login <username>'")
+	ErrBadCredentials	= errors.New("invalid username or password")
+	ErrPasswordTooShort	= fmt.Errorf("password must be at least %d characters", minPasswordLength)
+	ErrAdminOnly		= errors.New("this command requires an admin user")
+)
+
+// IsAdmin reports whether user has the admin role.
+func IsAdmin(user database.User) bool {
+	return user.Role == RoleAdmin
+}
+
+// HashPassword hashes a password with bcrypt for storage.
+// Returns ErrPasswordTooShort if the password is shorter than minPasswordLength.
+func HashPassword(password string) (string, error) {
+	if len(password) < minPasswordLength {
+		return "", ErrPasswordTooShort
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("Error hashing password: %w", err)
+	}
+	return string(hash), nil
+}
+
+// CheckPassword reports whether password matches the user's stored hash.
+// Users without a password never match.
+func CheckPassword(user database.User, password string) bool {
+	if !user.PasswordHash.Valid {
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(user.PasswordHash.String), []byte(password)) == nil
+}
+
+// CreateSession starts a new session for user and removes expired ones.
+//
+// Returns the session token to hand to the client. Only a hash of the token
+// is stored, so a copy of the database can't be used to log in.
+func CreateSession(ctx context.Context, s *State, user database.User) (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("Error generating session token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+
+	now := time.Now().UTC()
+	if err := s.Db.DeleteExpiredSessions(ctx, now); err != nil {
+		return "", fmt.Errorf("Error removing expired sessions: %w", err)
+	}
+
+	err := s.Db.CreateSession(ctx, database.CreateSessionParams{
+		ID: uuid.New(),
+		ExpiresAt: now.Add(sessionTTL),
+		UserID: user.ID,
+		TokenHash: hashToken(token),
+	})
+	if err != nil {
+		return "", fmt.Errorf("Error creating session: %w", err)
+	}
+	return token, nil
+}
+
+// SessionUser returns the user a session token belongs to and records that the
+// session was used.
+//
+// Returns ErrNotLoggedIn if token is empty, or ErrSessionExpired if the session
+// is unknown, expired or was logged out.
+func SessionUser(ctx context.Context, s *State, token string) (database.User, error) {
+	if token == "" {
+		return database.User{}, ErrNotLoggedIn
+	}
+
+	hash := hashToken(token)
+	user, err := s.Db.GetUserForSession(ctx, database.GetUserForSessionParams{
+		TokenHash: hash,
+		Now: time.Now().UTC(),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.User{}, ErrSessionExpired
+	}
+	if err != nil {
+		return database.User{}, fmt.Errorf("Error checking session: %w", err)
+	}
+
+	if err := s.Db.TouchSession(ctx, hash); err != nil {
+		return database.User{}, fmt.Errorf("Error updating session: %w", err)
+	}
+	return user, nil
+}
+
+// EndSession deletes the session identified by token, if it exists.
+func EndSession(ctx context.Context, s *State, token string) error {
+	if token == "" {
+		return nil
+	}
+	if err := s.Db.DeleteSession(ctx, hashToken(token)); err != nil {
+		return fmt.Errorf("Error ending session: %w", err)
+	}
+	return nil
+}
+
+// hashToken returns the hex SHA-256 of a session token as stored in the database.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
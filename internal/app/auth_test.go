@@ -0,0 +1,101 @@
+package app_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nhdewitt/blog-aggregator/internal/app"
+	"github.com/nhdewitt/blog-aggregator/internal/database"
+)
+
+func TestPasswords(t *testing.T) {
+	if _, err := app.HashPassword("short"); !errors.Is(err, app.ErrPasswordTooShort) {
+		t.Errorf("HashPassword of a short password = %v, want ErrPasswordTooShort", err)
+	}
+	hash, err := app.HashPassword("correct-horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := database.User{PasswordHash: sql.NullString{String: hash, Valid: true}}
+
+	tests := []struct {
+		user		database.User
+		password	string
+		want		bool
+	}{
+		{user, "correct-horse", true},
+		{user, "Correct-horse", false},
+		{user, "", false},
+		{database.User{}, "", false},
+		{database.User{}, "correct-horse", false},
+	}
+	for _, tt := range tests {
+		if got := app.CheckPassword(tt.user, tt.password); got != tt.want {
+			t.Errorf("CheckPassword(%q) with hash %v = %v, want %v", tt.password, tt.user.PasswordHash.Valid, got, tt.want)
+		}
+	}
+}
+
+func TestSessions(t *testing.T) {
+	ctx := context.Background()
+	s, user := newTestState(t)
+
+	token, err := app.CreateSession(ctx, s, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := app.CreateSession(ctx, s, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token == other {
+		t.Fatal("two sessions got the same token")
+	}
+
+	// An expired session, stored the way CreateSession stores them
+	expired := "expired-token"
+	sum := sha256.Sum256([]byte(expired))
+	err = s.Db.CreateSession(ctx, database.CreateSessionParams{
+		ID: uuid.New(),
+		ExpiresAt: time.Now().UTC().Add(-time.Minute),
+		UserID: user.ID,
+		TokenHash: hex.EncodeToString(sum[:]),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := app.SessionUser(ctx, s, token); err != nil || got.ID != user.ID {
+		t.Fatalf("SessionUser = %v, %v; want %s", got.Name, err, user.Name)
+	}
+	if err := app.EndSession(ctx, s, token); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name	string
+		token	string
+		wantErr	error
+	}{
+		{"no token", "", app.ErrNotLoggedIn},
+		{"logged out", token, app.ErrSessionExpired},
+		{"unknown", "made-up-token", app.ErrSessionExpired},
+		{"expired", expired, app.ErrSessionExpired},
+	}
+	for _, tt := range tests {
+		if _, err := app.SessionUser(ctx, s, tt.token); !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: SessionUser error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+
+	// Ending one session leaves the user's others alone
+	if _, err := app.SessionUser(ctx, s, other); err != nil {
+		t.Errorf("SessionUser of the other session: %v", err)
+	}
+}
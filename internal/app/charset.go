@@ -0,0 +1,125 @@
+// Package app contains shared application services and state management.
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// xmlDeclEncoding matches the encoding attribute of an XML declaration.
+var xmlDeclEncoding = regexp.MustCompile(`^\s*<\?xml[^>]*?\sencoding\s*=\s*["']([A-Za-z0-9._:-]+)["']`)
+
+// feedToUTF8 transcodes a feed document to UTF-8.
+//
+// The encoding is taken from a byte order mark if present, then the charset
+// parameter of the Content-Type header, then the XML declaration, defaulting
+// to UTF-8. Mislabeled documents are handled with two fallbacks: a document
+// that is valid UTF-8 and declares UTF-8 itself wins over a conflicting header,
+// and a document labeled UTF-8 that isn't valid UTF-8 is read as Windows-1252.
+//
+// Returns the UTF-8 document, or an error if the declared encoding is unknown.
+func feedToUTF8(body []byte, contentType string) ([]byte, error) {
+	if enc, rest := bomEncoding(body); enc != nil {
+		return transcode(rest, enc)
+	}
+
+	headerLabel := contentTypeCharset(contentType)
+	declLabel := xmlDeclaredEncoding(body)
+
+	label := headerLabel
+	if label == "" {
+		label = declLabel
+	}
+	if label == "" {
+		label = "utf-8"
+	}
+
+	// Trust a self-consistent UTF-8 document over a server default like ISO-8859-1
+	if headerLabel != "" && isUTF8Label(declLabel) && utf8.Valid(body) {
+		label = declLabel
+	}
+
+	enc, err := htmlindex.Get(label)
+	if err != nil {
+		return nil, fmt.Errorf("Unsupported encoding %q", label)
+	}
+
+	if enc == unicode.UTF8 {
+		if utf8.Valid(body) {
+			return body, nil
+		}
+		// Most often a Latin-1/Windows-1252 document that doesn't say so
+		return transcode(body, charmap.Windows1252)
+	}
+	return transcode(body, enc)
+}
+
+// identityCharsetReader is used as xml.Decoder.CharsetReader once a document has
+// already been transcoded to UTF-8, so the original encoding declaration is ignored.
+func identityCharsetReader(label string, input io.Reader) (io.Reader, error) {
+	return input, nil
+}
+
+// bomEncoding detects a UTF-8 or UTF-16 byte order mark.
+// Returns the encoding and the document without the mark, or nil if there is none.
+func bomEncoding(body []byte) (encoding.Encoding, []byte) {
+	switch {
+	case bytes.HasPrefix(body, []byte{0xEF, 0xBB, 0xBF}):
+		return unicode.UTF8, body[3:]
+	case bytes.HasPrefix(body, []byte{0xFE, 0xFF}):
+		return unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM), body[2:]
+	case bytes.HasPrefix(body, []byte{0xFF, 0xFE}):
+		return unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM), body[2:]
+	}
+	return nil, body
+}
+
+// contentTypeCharset returns the charset parameter of a Content-Type header, if any.
+func contentTypeCharset(contentType string) string {
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(params["charset"])
+}
+
+// xmlDeclaredEncoding returns the encoding named in the document's XML declaration, if any.
+func xmlDeclaredEncoding(body []byte) string {
+	head := body[:min(len(body), 512)]
+	m := xmlDeclEncoding.FindSubmatch(head)
+	if m == nil {
+		return ""
+	}
+	return string(m[1])
+}
+
+// isUTF8Label reports whether an encoding label names UTF-8.
+func isUTF8Label(label string) bool {
+	switch strings.ToLower(label) {
+	case "utf-8", "utf8":
+		return true
+	}
+	return false
+}
+
+// transcode decodes body from enc into UTF-8.
+func transcode(body []byte, enc encoding.Encoding) ([]byte, error) {
+	if enc == unicode.UTF8 {
+		return body, nil
+	}
+	out, err := enc.NewDecoder().Bytes(body)
+	if err != nil {
+		return nil, fmt.Errorf("Error decoding document: %v", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,57 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+func TestFeedToUTF8(t *testing.T) {
+	const text = "Café – naïve"
+
+	latin1, _ := charmap.ISO8859_1.NewEncoder().String("Café naïve")
+	win1252, _ := charmap.Windows1252.NewEncoder().String(text)
+	sjis, _ := japanese.ShiftJIS.NewEncoder().String("日本語")
+	utf16le, _ := unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewEncoder().String(`<?xml version="1.0"?><rss>` + text + `</rss>`)
+	utf16be, _ := unicode.UTF16(unicode.BigEndian, unicode.UseBOM).NewEncoder().String(`<rss>` + text + `</rss>`)
+
+	decl := func(enc string) string {
+		return `<?xml version="1.0" encoding="` + enc + `"?>`
+	}
+
+	tests := []struct {
+		name		string
+		body		string
+		contentType	string
+		want		string
+		wantErr		string
+	}{
+		{"plain UTF-8", "<rss>" + text + "</rss>", "", "<rss>" + text + "</rss>", ""},
+		{"UTF-8 BOM", "\xEF\xBB\xBF<rss>" + text + "</rss>", "text/xml; charset=iso-8859-1", "<rss>" + text + "</rss>", ""},
+		{"UTF-16LE BOM", utf16le, "", `<?xml version="1.0"?><rss>` + text + `</rss>`, ""},
+		{"UTF-16BE BOM", utf16be, "", `<rss>` + text + `</rss>`, ""},
+		{"declared Latin-1", decl("ISO-8859-1") + latin1, "", decl("ISO-8859-1") + "Café naïve", ""},
+		{"header charset", "<rss>" + win1252 + "</rss>", "application/rss+xml; charset=windows-1252", "<rss>" + text + "</rss>", ""},
+		{"header beats declaration", decl("utf-8") + latin1, "text/xml; charset=iso-8859-1", decl("utf-8") + "Café naïve", ""},
+		{"self-consistent UTF-8 beats header", decl("UTF-8") + text, "text/xml; charset=iso-8859-1", decl("UTF-8") + text, ""},
+		{"Shift_JIS", decl("Shift_JIS") + sjis, "", decl("Shift_JIS") + "日本語", ""},
+		{"unlabeled Windows-1252", "<rss>" + win1252 + "</rss>", "", "<rss>" + text + "</rss>", ""},
+		{"mislabeled UTF-8", decl("utf-8") + win1252, "", decl("utf-8") + text, ""},
+		{"unknown encoding", decl("klingon") + text, "", "", `Unsupported encoding "klingon"`},
+	}
+	for _, tt := range tests {
+		got, err := feedToUTF8([]byte(tt.body), tt.contentType)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: error = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || string(got) != tt.want {
+			t.Errorf("%s: feedToUTF8 = %q, %v; want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
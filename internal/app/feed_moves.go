@@ -0,0 +1,87 @@
+// Package app contains shared application services and state management.
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/nhdewitt/blog-aggregator/internal/database"
+)
+
+// moveFeed records that dbFeed now lives at newURL after a permanent redirect.
+//
+// If no other feed uses newURL, the feed's URL is simply updated. Otherwise the
+// feed is merged into the existing one: its followers (unless they already follow
+// the target), posts, filter rules and URL history are carried over and the old
+// feed row is deleted. Either way the change is logged in feed_url_history, all
+// in a single transaction.
+//
+// Returns the feed the posts now belong to and whether a merge happened,
+// or an error if the database could not be updated.
+func moveFeed(ctx context.Context, s *State, dbFeed database.Feed, newURL string) (database.Feed, bool, error) {
+	var target database.Feed
+	var merged bool
+
+	err := s.InTx(ctx, func(q Store) error {
+		existing, err := q.FindFeedsByURL(ctx, newURL)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			if err := q.SetFeedURL(ctx, database.SetFeedURLParams{ID: dbFeed.ID, Url: newURL}); err != nil {
+				return fmt.Errorf("Error updating feed URL: %w", err)
+			}
+			target = dbFeed
+			target.Url = newURL
+		case err != nil:
+			return fmt.Errorf("Error finding feed %s: %w", newURL, err)
+		default:
+			if err := mergeFeed(ctx, q, dbFeed.ID, existing.ID); err != nil {
+				return err
+			}
+			target = existing
+			merged = true
+		}
+
+		err = q.RecordFeedURLChange(ctx, database.RecordFeedURLChangeParams{
+			ID: uuid.New(),
+			FeedID: target.ID,
+			OldUrl: dbFeed.Url,
+			NewUrl: newURL,
+			Merged: merged,
+		})
+		if err != nil {
+			return fmt.Errorf("Error recording feed URL change: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return dbFeed, false, err
+	}
+	return target, merged, nil
+}
+
+// mergeFeed moves everything that belongs to the feed fromID onto toID and
+// then deletes fromID. Follows of users who already follow toID are dropped.
+func mergeFeed(ctx context.Context, q Store, fromID, toID uuid.UUID) error {
+	if err := q.MoveFeedFollows(ctx, database.MoveFeedFollowsParams{FromFeedID: fromID, ToFeedID: toID}); err != nil {
+		return fmt.Errorf("Error moving follows: %w", err)
+	}
+	if err := q.MoveFeedPosts(ctx, database.MoveFeedPostsParams{FromFeedID: fromID, ToFeedID: toID}); err != nil {
+		return fmt.Errorf("Error moving posts: %w", err)
+	}
+	if err := q.MoveFeedFilterRules(ctx, database.MoveFeedFilterRulesParams{
+		FromFeedID: uuid.NullUUID{UUID: fromID, Valid: true},
+		ToFeedID: uuid.NullUUID{UUID: toID, Valid: true},
+	}); err != nil {
+		return fmt.Errorf("Error moving filter rules: %w", err)
+	}
+	if err := q.MoveFeedURLHistory(ctx, database.MoveFeedURLHistoryParams{FromFeedID: fromID, ToFeedID: toID}); err != nil {
+		return fmt.Errorf("Error moving URL history: %w", err)
+	}
+	if err := q.DeleteFeed(ctx, fromID); err != nil {
+		return fmt.Errorf("Error deleting merged feed: %w", err)
+	}
+	return nil
+}
@@ -3,45 +3,69 @@ package app
 
 import (
 	"context"
-	"encoding/xml"
+	"errors"
 	"fmt"
 	"html"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// fetchResult holds a parsed feed together with the caching hints sent by the server.
+type fetchResult struct {
+	Feed		*RSSFeed
+	MaxAge		time.Duration	// Cache-Control max-age, zero if absent
+	Warnings	[]string		// repairs needed to parse a malformed document
+	MovedTo		string			// new URL if the feed was permanently redirected, else empty
+}
+
+// HTTPStatusError is returned when a feed server answers with a non-2xx status.
+// RetryAfter is set when the server sent a usable Retry-After header.
+type HTTPStatusError struct {
+	StatusCode	int
+	Status		string
+	RetryAfter	time.Time
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("HTTP Status %d %s", e.StatusCode, e.Status)
+}
+
 // fetchFeed retrievves and parses an RSS feed from the given URL.
-// It sends an HTTP GET request with a custom User-Agent header, reads the response,
-// and unmarshals the XML into an RSSFeed struct.
+// It sends an HTTP GET request through the shared Fetcher, reads the response,
+// converts it to UTF-8 from whatever encoding the server or document declares,
+// and unmarshals the XML into an RSSFeed struct. Malformed documents are parsed
+// leniently by parseFeed and the repairs reported as warnings.
 //
 // The function automatically unescapes HTML entities in the feed title, description,
-// and all item titles and descriptions to ensure proper display of special characters.
+// and all item titles, descriptions and authors to ensure proper display of special characters.
 //
 // Parameters:
 //		- ctx: Context for request cancellation and timeout control
 //		- feedURL: The URL of the RSS feed to fetch
 //
-// Returns the parsed RSSFeed struct or an error if the request fails,
-// returns a non-2xx status code, or if XML parsing fails.
-func fetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
-	// Create HTTP request with context for cancellation support
-	req, err := http.NewRequestWithContext(ctx, "GET", feedURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("Error creating request: %v", err)
-	}
-	req.Header.Set("User-Agent", "gator")
-	
-	// Execute the HTTP request
-	client := http.Client{}
-	resp, err := client.Do(req)
+// Returns the parsed RSSFeed with the server's Cache-Control max-age, any parse
+// warnings and the target of a permanent redirect, or an error if the request
+// fails, returns a non-2xx status code, or if nothing could be parsed from the
+// document. Non-2xx responses are reported as *HTTPStatusError, including any
+// Retry-After time.
+func (f *Fetcher) fetchFeed(ctx context.Context, feedURL string) (*fetchResult, error) {
+	// Execute the HTTP request with context for cancellation support
+	resp, err := f.get(ctx, feedURL)
 	if err != nil {
-		return nil, fmt.Errorf("Error receiving response: %v", err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	// Check for successful HTTP status
 	if resp.StatusCode < 200 || resp.StatusCode > 299 {
-		return nil, fmt.Errorf("HTTP Status %d %s", resp.StatusCode, resp.Status)
+		return nil, &HTTPStatusError{
+			StatusCode: resp.StatusCode,
+			Status: resp.Status,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		}
 	}
 
 	// Read the response body
@@ -50,11 +74,16 @@ func fetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
 		return nil, fmt.Errorf("Error reading response: %v", err)
 	}
 
-	// Parse the XML into RSSFeed struct
-	var rss RSSFeed
-	err = xml.Unmarshal(body, &rss)
+	// Convert the document to UTF-8 based on its declared or detected encoding
+	body, err = feedToUTF8(body, resp.Header.Get("Content-Type"))
 	if err != nil {
-		return nil, fmt.Errorf("Error unmarshaling XML: %v", err)
+		return nil, err
+	}
+
+	// Parse the XML into RSSFeed struct, falling back to lenient parsing for malformed feeds
+	rss, warnings, err := parseFeed(body)
+	if err != nil {
+		return nil, err
 	}
 
 	// Unescape HTML entities in feed content for proper display
@@ -63,7 +92,93 @@ func fetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
 	for i := range rss.Channel.Item {
 		rss.Channel.Item[i].Title = html.UnescapeString(rss.Channel.Item[i].Title)
 		rss.Channel.Item[i].Description = html.UnescapeString(rss.Channel.Item[i].Description)
+		rss.Channel.Item[i].Author = html.UnescapeString(rss.Channel.Item[i].Author)
+		rss.Channel.Item[i].Creator = html.UnescapeString(rss.Channel.Item[i].Creator)
+	}
+
+	return &fetchResult{
+		Feed: rss,
+		Warnings: warnings,
+		MaxAge: parseMaxAge(resp.Header.Get("Cache-Control")),
+		MovedTo: permanentRedirect(resp),
+	}, nil
+}
+
+// permanentRedirect follows the redirect chain that produced resp from the
+// original request and returns the last URL reached only through permanent
+// redirects (301 or 308). A temporary redirect anywhere in the chain ends it,
+// since the publisher only asked for the URL to change up to that point.
+//
+// Returns an empty string if the first redirect wasn't permanent or there was none.
+func permanentRedirect(resp *http.Response) string {
+	// Walk back from the final request; the last entry is the original request
+	var chain []*http.Request
+	for req := resp.Request; req != nil; {
+		chain = append(chain, req)
+		if req.Response == nil {
+			break
+		}
+		req = req.Response.Request
+	}
+
+	var movedTo string
+	for i := len(chain) - 2; i >= 0; i-- {
+		switch chain[i].Response.StatusCode {
+		case http.StatusMovedPermanently, http.StatusPermanentRedirect:
+			movedTo = chain[i].URL.String()
+		default:
+			return movedTo
+		}
+	}
+	return movedTo
+}
+
+// maxRetryAfter caps how long a server can put off the next fetch, so a huge
+// or mistaken Retry-After can't shelve a feed indefinitely.
+const maxRetryAfter = 7 * 24 * time.Hour
+
+// parseRetryAfter reads a Retry-After header given either as a number of
+// seconds or as an HTTP date, limited to maxRetryAfter from now. Returns the
+// zero time if the header is missing or invalid.
+func parseRetryAfter(header string) time.Time {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return time.Time{}
+	}
+	now := time.Now().UTC()
+	limit := now.Add(maxRetryAfter)
+	// ParseInt reports a number too large for int64 as ErrRange with the maximum value
+	seconds, err := strconv.ParseInt(header, 10, 64)
+	if (err == nil || errors.Is(err, strconv.ErrRange)) && seconds >= 0 {
+		if seconds > int64(maxRetryAfter/time.Second) {
+			return limit
+		}
+		return now.Add(time.Duration(seconds) * time.Second)
 	}
+	if t, err := http.ParseTime(header); err == nil {
+		if t.After(limit) {
+			return limit
+		}
+		return t.UTC()
+	}
+	return time.Time{}
+}
 
-	return &rss, nil
+// parseMaxAge reads the max-age directive from a Cache-Control header.
+// Returns zero if there is none or the response must not be cached.
+func parseMaxAge(header string) time.Duration {
+	var maxAge time.Duration
+	for _, directive := range strings.Split(header, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(name) {
+		case "no-cache", "no-store":
+			return 0
+		case "max-age":
+			seconds, err := strconv.Atoi(strings.Trim(value, `"`))
+			if err == nil && seconds > 0 {
+				maxAge = time.Duration(seconds) * time.Second
+			}
+		}
+	}
+	return maxAge
 }
\ No newline at end of file
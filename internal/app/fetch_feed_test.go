@@ -0,0 +1,65 @@
+package app
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nhdewitt/blog-aggregator/internal/config"
+)
+
+func TestPermanentRedirect(t *testing.T) {
+	// Each path redirects with the given status to the next one
+	hops := map[string]struct {
+		status	int
+		next	string
+	}{
+		"/moved":		{http.StatusMovedPermanently, "/feed"},
+		"/moved308":	{http.StatusPermanentRedirect, "/feed"},
+		"/temp":		{http.StatusFound, "/feed"},
+		"/moved-twice":	{http.StatusMovedPermanently, "/moved"},
+		"/then-temp":	{http.StatusMovedPermanently, "/temp"},
+		"/temp-then":	{http.StatusTemporaryRedirect, "/moved"},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hop, ok := hops[r.URL.Path]; ok {
+			http.Redirect(w, r, hop.next, hop.status)
+			return
+		}
+		io.WriteString(w, `<rss><channel><title>Blog</title></channel></rss>`)
+	}))
+	defer srv.Close()
+
+	f, err := NewFetcher(config.FetchConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path	string
+		want	string
+	}{
+		{"/feed", ""},
+		{"/moved", "/feed"},
+		{"/moved308", "/feed"},
+		{"/temp", ""},
+		{"/moved-twice", "/feed"},
+		{"/then-temp", "/temp"},
+		{"/temp-then", ""},
+	}
+	for _, tt := range tests {
+		result, err := f.fetchFeed(context.Background(), srv.URL+tt.path)
+		if err != nil {
+			t.Fatalf("fetchFeed %s: %v", tt.path, err)
+		}
+		want := tt.want
+		if want != "" {
+			want = srv.URL + want
+		}
+		if result.MovedTo != want {
+			t.Errorf("fetchFeed %s moved to %q, want %q", tt.path, result.MovedTo, want)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+// Package app contains shared application services and state management.
+package app
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/nhdewitt/blog-aggregator/internal/config"
+)
+
+// Defaults used when the fetch section of the config leaves a setting empty.
+const (
+	defaultFetchTimeout   = 30 * time.Second
+	defaultConnectTimeout = 10 * time.Second
+	defaultReadTimeout    = 20 * time.Second
+	defaultMaxBodyBytes   = 10 << 20
+	defaultMaxRedirects   = 5
+	defaultUserAgent      = "gator/1.0"
+	defaultContactURL     = "https://github.com/nhdewitt/blog-aggregator"
+)
+
+// ErrBodyTooLarge is returned when a response is bigger than the configured maximum.
+var ErrBodyTooLarge = errors.New("response body too large")
+
+// Fetcher downloads feeds and articles with a single shared HTTP client.
+// It applies connect and read timeouts, a response size limit, a redirect limit,
+// gzip and brotli decoding, a descriptive User-Agent and optional proxy.
+type Fetcher struct {
+	client			*http.Client
+	userAgent		string
+	maxBodyBytes	int64
+}
+
+// NewFetcher builds a Fetcher from the fetch section of the config.
+//
+// Returns an error if a duration or the proxy URL cannot be parsed.
+func NewFetcher(cfg config.FetchConfig) (*Fetcher, error) {
+	timeout, err := parseDurationOr(cfg.Timeout, defaultFetchTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid fetch timeout: %w", err)
+	}
+	connectTimeout, err := parseDurationOr(cfg.ConnectTimeout, defaultConnectTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid fetch connect_timeout: %w", err)
+	}
+	readTimeout, err := parseDurationOr(cfg.ReadTimeout, defaultReadTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid fetch read_timeout: %w", err)
+	}
+
+	proxy := http.ProxyFromEnvironment
+	if cfg.Proxy != "" {
+		proxyURL, err := url.Parse(cfg.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid fetch proxy: %w", err)
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+
+	maxRedirects := cfg.MaxRedirects
+	if maxRedirects == 0 {
+		maxRedirects = defaultMaxRedirects
+	}
+
+	maxBodyBytes := cfg.MaxBodyBytes
+	if maxBodyBytes == 0 {
+		maxBodyBytes = defaultMaxBodyBytes
+	}
+
+	userAgent := cfg.UserAgent
+	if userAgent == "" {
+		userAgent = defaultUserAgent
+	}
+	contactURL := cfg.ContactURL
+	if contactURL == "" {
+		contactURL = defaultContactURL
+	}
+
+	transport := &http.Transport{
+		Proxy: proxy,
+		DialContext: (&net.Dialer{
+			Timeout: connectTimeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSHandshakeTimeout: connectTimeout,
+		ResponseHeaderTimeout: readTimeout,
+		MaxIdleConns: 20,
+		IdleConnTimeout: 90 * time.Second,
+		// Compression is negotiated and decoded by the Fetcher so brotli can be offered too
+		DisableCompression: true,
+	}
+
+	return &Fetcher{
+		client: &http.Client{
+			Transport: transport,
+			Timeout: timeout,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) >= maxRedirects {
+					return fmt.Errorf("stopped after %d redirects", maxRedirects)
+				}
+				return nil
+			},
+		},
+		userAgent: fmt.Sprintf("%s (+%s)", userAgent, contactURL),
+		maxBodyBytes: maxBodyBytes,
+	}, nil
+}
+
+// get sends a GET request for rawURL and returns the response with its body
+// decompressed and limited to the configured maximum size.
+// The caller must close the response body.
+func (f *Fetcher) get(ctx context.Context, rawURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Error creating request: %v", err)
+	}
+	req.Header.Set("User-Agent", f.userAgent)
+	req.Header.Set("Accept-Encoding", "gzip, br")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Error receiving response: %v", err)
+	}
+
+	if resp.ContentLength > f.maxBodyBytes {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: %d bytes", ErrBodyTooLarge, resp.ContentLength)
+	}
+
+	body, err := decodeBody(resp)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	resp.Body = &limitedBody{
+		Reader: io.LimitReader(body, f.maxBodyBytes+1),
+		decoder: body,
+		closer: resp.Body,
+		remaining: f.maxBodyBytes,
+	}
+	return resp, nil
+}
+
+// decodeBody wraps the response body in a decompressor matching its Content-Encoding.
+// Closing the decompressor releases it but leaves the response body open.
+func decodeBody(resp *http.Response) (io.ReadCloser, error) {
+	switch strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))) {
+	case "", "identity":
+		return io.NopCloser(resp.Body), nil
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("Error decoding gzip response: %v", err)
+		}
+		return gz, nil
+	case "br":
+		return io.NopCloser(brotli.NewReader(resp.Body)), nil
+	default:
+		return nil, fmt.Errorf("Unsupported Content-Encoding %q", resp.Header.Get("Content-Encoding"))
+	}
+}
+
+// limitedBody reads a decompressed response body and fails with ErrBodyTooLarge
+// once more than the allowed number of bytes has been read. Closing it closes
+// the decompressor and then the underlying connection body.
+type limitedBody struct {
+	io.Reader
+	decoder		io.Closer
+	closer		io.Closer
+	remaining	int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.Reader.Read(p)
+	b.remaining -= int64(n)
+	if b.remaining < 0 {
+		return n, ErrBodyTooLarge
+	}
+	return n, err
+}
+
+func (b *limitedBody) Close() error {
+	decodeErr := b.decoder.Close()
+	if err := b.closer.Close(); err != nil {
+		return err
+	}
+	return decodeErr
+}
+
+// parseDurationOr parses s as a duration, returning fallback if s is empty.
+func parseDurationOr(s string, fallback time.Duration) (time.Duration, error) {
+	if s == "" {
+		return fallback, nil
+	}
+	return time.ParseDuration(s)
+}
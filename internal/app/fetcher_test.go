@@ -0,0 +1,156 @@
+package app
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/nhdewitt/blog-aggregator/internal/config"
+)
+
+func TestNewFetcherRejectsBadConfig(t *testing.T) {
+	tests := []struct {
+		cfg		config.FetchConfig
+		want	string
+	}{
+		{config.FetchConfig{Timeout: "soon"}, "Invalid fetch timeout"},
+		{config.FetchConfig{ConnectTimeout: "10"}, "Invalid fetch connect_timeout"},
+		{config.FetchConfig{ReadTimeout: "-"}, "Invalid fetch read_timeout"},
+		{config.FetchConfig{Proxy: "http://[::1"}, "Invalid fetch proxy"},
+	}
+	for _, tt := range tests {
+		_, err := NewFetcher(tt.cfg)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("NewFetcher(%+v) error = %v, want %q", tt.cfg, err, tt.want)
+		}
+	}
+}
+
+func TestFetcherGet(t *testing.T) {
+	const text = "<rss>hello</rss>"
+	var gzipped, brotlied bytes.Buffer
+	gz := gzip.NewWriter(&gzipped)
+	io.WriteString(gz, text)
+	gz.Close()
+	br := brotli.NewWriter(&brotlied)
+	io.WriteString(br, text)
+	br.Close()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/plain":
+			io.WriteString(w, text)
+		case "/gzip":
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write(gzipped.Bytes())
+		case "/br":
+			w.Header().Set("Content-Encoding", "br")
+			w.Write(brotlied.Bytes())
+		case "/bad-gzip":
+			w.Header().Set("Content-Encoding", "gzip")
+			io.WriteString(w, text)
+		case "/compress":
+			w.Header().Set("Content-Encoding", "compress")
+			io.WriteString(w, text)
+		case "/agent":
+			io.WriteString(w, r.Header.Get("User-Agent")+"|"+r.Header.Get("Accept-Encoding"))
+		case "/big":
+			w.Header().Set("Content-Length", "1000")
+			w.Write(make([]byte, 1000))
+		case "/big-stream":
+			// Without a Content-Length the limit is only hit while reading
+			w.(http.Flusher).Flush()
+			w.Write(make([]byte, 1000))
+		case "/loop":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	f, err := NewFetcher(config.FetchConfig{
+		MaxBodyBytes: 100,
+		MaxRedirects: 3,
+		UserAgent: "tester/2.0",
+		ContactURL: "https://example.com/bot",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path	string
+		want	string
+		wantErr	string
+	}{
+		{"/plain", text, ""},
+		{"/gzip", text, ""},
+		{"/br", text, ""},
+		{"/agent", "tester/2.0 (+https://example.com/bot)|gzip, br", ""},
+		{"/bad-gzip", "", "Error decoding gzip response"},
+		{"/compress", "", `Unsupported Content-Encoding "compress"`},
+		{"/big", "", "response body too large: 1000 bytes"},
+		{"/big-stream", "", "response body too large"},
+		{"/loop", "", "stopped after 3 redirects"},
+	}
+	for _, tt := range tests {
+		got, err := getString(f, srv.URL+tt.path)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("get %s error = %v, want %q", tt.path, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("get %s = %q, %v; want %q", tt.path, got, err, tt.want)
+		}
+	}
+}
+
+// getString fetches rawURL with f and returns the decoded body.
+func getString(f *Fetcher, rawURL string) (string, error) {
+	resp, err := f.get(context.Background(), rawURL)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	return string(body), err
+}
+
+// closeRecorder records that it was closed.
+type closeRecorder struct {
+	closed	*[]string
+	name	string
+	err		error
+}
+
+func (c closeRecorder) Close() error {
+	*c.closed = append(*c.closed, c.name)
+	return c.err
+}
+
+func TestLimitedBodyClosesDecoder(t *testing.T) {
+	decodeErr := errors.New("checksum error")
+	var closed []string
+	body := &limitedBody{
+		Reader: strings.NewReader(""),
+		decoder: closeRecorder{&closed, "decoder", decodeErr},
+		closer: closeRecorder{&closed, "body", nil},
+	}
+
+	if err := body.Close(); !errors.Is(err, decodeErr) {
+		t.Errorf("Close = %v, want the decoder's error", err)
+	}
+	if strings.Join(closed, ",") != "decoder,body" {
+		t.Errorf("closed %v, want the decoder and then the body", closed)
+	}
+}
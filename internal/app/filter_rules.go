@@ -0,0 +1,132 @@
+// Package app contains shared application services and state management.
+package app
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/nhdewitt/blog-aggregator/internal/database"
+)
+
+// Actions a filter rule can take on a matching post.
+const (
+	RuleActionHide      = "hide"
+	RuleActionHighlight = "highlight"
+	RuleActionMarkRead  = "mark_read"
+)
+
+// Post fields a filter rule can be matched against.
+const (
+	RuleFieldAny    = "any"
+	RuleFieldTitle  = "title"
+	RuleFieldAuthor = "author"
+)
+
+// FilterResult describes what a user's rules decided for a single post.
+type FilterResult struct {
+	Hidden		bool
+	Highlighted	bool
+	MarkRead	bool
+}
+
+// FilteredPost holds the post fields that filter rules are matched against.
+type FilteredPost struct {
+	FeedID		uuid.UUID
+	Title		string
+	Description	string
+	Content		string
+	Author		string
+}
+
+// PostFilter applies a user's filter rules to posts.
+// Any output that lists posts for a user should run them through a PostFilter.
+type PostFilter struct {
+	rules		[]compiledRule
+}
+
+// compiledRule is a filter rule with its pattern ready for matching.
+type compiledRule struct {
+	feedID		uuid.NullUUID
+	field		string
+	action		string
+	keyword		string
+	regex		*regexp.Regexp
+}
+
+// NewPostFilter prepares a user's filter rules for matching.
+// Keyword rules match case-insensitively anywhere in the field; regex rules use Go syntax.
+//
+// Returns an error if a rule has an invalid regular expression.
+func NewPostFilter(rules []database.GetFilterRulesForUserRow) (*PostFilter, error) {
+	f := &PostFilter{}
+	for _, rule := range rules {
+		cr := compiledRule{
+			feedID: rule.FeedID,
+			field: rule.Field,
+			action: rule.Action,
+		}
+		if rule.IsRegex {
+			re, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid pattern in rule %s: %w", rule.ID, err)
+			}
+			cr.regex = re
+		} else {
+			cr.keyword = strings.ToLower(rule.Pattern)
+		}
+		f.rules = append(f.rules, cr)
+	}
+	return f, nil
+}
+
+// Apply checks a post against every rule and combines the actions of those that match.
+func (f *PostFilter) Apply(post FilteredPost) FilterResult {
+	var result FilterResult
+	for _, rule := range f.rules {
+		if rule.feedID.Valid && rule.feedID.UUID != post.FeedID {
+			continue
+		}
+		if !rule.matches(post) {
+			continue
+		}
+
+		switch rule.action {
+		case RuleActionHide:
+			result.Hidden = true
+		case RuleActionHighlight:
+			result.Highlighted = true
+		case RuleActionMarkRead:
+			result.MarkRead = true
+		}
+	}
+	return result
+}
+
+// matches reports whether the rule's pattern is found in the post field it targets.
+func (r compiledRule) matches(post FilteredPost) bool {
+	var fields []string
+	switch r.field {
+	case RuleFieldTitle:
+		fields = []string{post.Title}
+	case RuleFieldAuthor:
+		fields = []string{post.Author}
+	default:
+		fields = []string{post.Title, post.Author, post.Description, post.Content}
+	}
+
+	for _, field := range fields {
+		if field == "" {
+			continue
+		}
+		if r.regex != nil {
+			if r.regex.MatchString(field) {
+				return true
+			}
+		} else if strings.Contains(strings.ToLower(field), r.keyword) {
+			return true
+		}
+	}
+	return false
+}
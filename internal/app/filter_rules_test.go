@@ -0,0 +1,81 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/nhdewitt/blog-aggregator/internal/database"
+)
+
+func TestPostFilterApply(t *testing.T) {
+	feed := uuid.New()
+	otherFeed := uuid.New()
+	rule := func(action, field, pattern string, isRegex bool, feedID uuid.NullUUID) database.GetFilterRulesForUserRow {
+		return database.GetFilterRulesForUserRow{
+			ID: uuid.New(),
+			FeedID: feedID,
+			Pattern: pattern,
+			IsRegex: isRegex,
+			Field: field,
+			Action: action,
+		}
+	}
+	post := FilteredPost{
+		FeedID: feed,
+		Title: "Go 1.22 released",
+		Description: "A sponsored look at loops",
+		Author: "Gopher Team",
+	}
+
+	tests := []struct {
+		name	string
+		rules	[]database.GetFilterRulesForUserRow
+		want	FilterResult
+	}{
+		{"no rules", nil, FilterResult{}},
+		{"keyword ignores case", []database.GetFilterRulesForUserRow{
+			rule(RuleActionHide, RuleFieldAny, "SPONSORED", false, uuid.NullUUID{}),
+		}, FilterResult{Hidden: true}},
+		{"title only", []database.GetFilterRulesForUserRow{
+			rule(RuleActionHide, RuleFieldTitle, "sponsored", false, uuid.NullUUID{}),
+		}, FilterResult{}},
+		{"author", []database.GetFilterRulesForUserRow{
+			rule(RuleActionMarkRead, RuleFieldAuthor, "gopher", false, uuid.NullUUID{}),
+		}, FilterResult{MarkRead: true}},
+		{"regex", []database.GetFilterRulesForUserRow{
+			rule(RuleActionHighlight, RuleFieldTitle, `^Go 1\.\d+ released$`, true, uuid.NullUUID{}),
+		}, FilterResult{Highlighted: true}},
+		{"regex is case-sensitive", []database.GetFilterRulesForUserRow{
+			rule(RuleActionHighlight, RuleFieldTitle, `^go`, true, uuid.NullUUID{}),
+		}, FilterResult{}},
+		{"scoped to this feed", []database.GetFilterRulesForUserRow{
+			rule(RuleActionHide, RuleFieldAny, "loops", false, uuid.NullUUID{UUID: feed, Valid: true}),
+		}, FilterResult{Hidden: true}},
+		{"scoped to another feed", []database.GetFilterRulesForUserRow{
+			rule(RuleActionHide, RuleFieldAny, "loops", false, uuid.NullUUID{UUID: otherFeed, Valid: true}),
+		}, FilterResult{}},
+		{"actions combine", []database.GetFilterRulesForUserRow{
+			rule(RuleActionHighlight, RuleFieldTitle, "go", false, uuid.NullUUID{}),
+			rule(RuleActionMarkRead, RuleFieldAny, "loops", false, uuid.NullUUID{}),
+			rule(RuleActionHide, RuleFieldAuthor, "rust", false, uuid.NullUUID{}),
+		}, FilterResult{Highlighted: true, MarkRead: true}},
+	}
+	for _, tt := range tests {
+		f, err := NewPostFilter(tt.rules)
+		if err != nil {
+			t.Fatalf("%s: NewPostFilter: %v", tt.name, err)
+		}
+		if got := f.Apply(post); got != tt.want {
+			t.Errorf("%s: Apply = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNewPostFilterRejectsBadRegex(t *testing.T) {
+	_, err := NewPostFilter([]database.GetFilterRulesForUserRow{
+		{ID: uuid.New(), Pattern: "(unclosed", IsRegex: true, Field: RuleFieldAny, Action: RuleActionHide},
+	})
+	if err == nil {
+		t.Fatal("NewPostFilter accepted an invalid regular expression")
+	}
+}
@@ -0,0 +1,116 @@
+// Package app contains shared application services and state management.
+package app
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"slices"
+	"unicode/utf8"
+)
+
+// entityRef matches the characters after an ampersand that form a complete
+// entity or character reference, such as "amp;", "#38;" or "#x26;".
+var entityRef = regexp.MustCompile(`^(?:[A-Za-z_][A-Za-z0-9._-]*|#[0-9]+|#x[0-9A-Fa-f]+);`)
+
+// feedAutoClose lists the HTML void elements closed automatically when a feed
+// is parsed leniently. It is xml.HTMLAutoClose without "link": in RSS <link>
+// holds the item URL as text, which auto-closing would throw away.
+var feedAutoClose = slices.DeleteFunc(slices.Clone(xml.HTMLAutoClose), func(name string) bool {
+	return name == "link"
+})
+
+// parseFeed decodes a UTF-8 feed document.
+//
+// Well-formed documents are decoded strictly. If that fails, the document is
+// cleaned up (leading BOM and whitespace, control characters, unescaped
+// ampersands) and decoded again in non-strict mode, which understands HTML
+// entities such as &nbsp; and closes unterminated HTML elements. If even that
+// stops partway through, the items read before the error are kept.
+//
+// Returns the feed along with a warning for every repair that was needed,
+// or an error if nothing usable could be read from the document.
+func parseFeed(body []byte) (*RSSFeed, []string, error) {
+	var rss RSSFeed
+	strictErr := newFeedDecoder(body).Decode(&rss)
+	if strictErr == nil {
+		return &rss, nil, nil
+	}
+
+	warnings := []string{fmt.Sprintf("Feed is not well-formed XML (%v), parsed leniently", strictErr)}
+	cleaned, fixes := sanitizeFeed(body)
+	warnings = append(warnings, fixes...)
+
+	rss = RSSFeed{}
+	decoder := newFeedDecoder(cleaned)
+	decoder.Strict = false
+	decoder.AutoClose = feedAutoClose
+	decoder.Entity = xml.HTMLEntity
+	err := decoder.Decode(&rss)
+	if err != nil {
+		// Decode fills the struct as it goes, so whatever came before the error is usable
+		if len(rss.Channel.Item) == 0 && rss.Channel.Title == "" {
+			return nil, warnings, fmt.Errorf("Error unmarshaling XML: %v", strictErr)
+		}
+		warnings = append(warnings, fmt.Sprintf("Stopped reading feed early (%v), kept %d items", err, len(rss.Channel.Item)))
+	}
+	return &rss, warnings, nil
+}
+
+// newFeedDecoder returns a decoder for a document that is already UTF-8.
+func newFeedDecoder(body []byte) *xml.Decoder {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.CharsetReader = identityCharsetReader
+	return decoder
+}
+
+// sanitizeFeed repairs common problems that make a feed invalid XML:
+// a byte order mark or whitespace before the XML declaration, characters
+// that XML doesn't allow, and ampersands that don't start an entity.
+//
+// Returns the cleaned document and a description of each kind of repair made.
+func sanitizeFeed(body []byte) ([]byte, []string) {
+	var fixes []string
+
+	trimmed := bytes.TrimPrefix(body, []byte{0xEF, 0xBB, 0xBF})
+	trimmed = bytes.TrimLeft(trimmed, " \t\r\n")
+	if len(trimmed) != len(body) {
+		fixes = append(fixes, "Removed byte order mark or whitespace before the XML declaration")
+	}
+
+	out := make([]byte, 0, len(trimmed))
+	var ampersands, invalidChars int
+	for i := 0; i < len(trimmed); {
+		r, size := utf8.DecodeRune(trimmed[i:])
+		switch {
+		case r == '&':
+			out = append(out, '&')
+			if !entityRef.Match(trimmed[i+1:]) {
+				out = append(out, "amp;"...)
+				ampersands++
+			}
+		case !isXMLChar(r) || (r == utf8.RuneError && size == 1):
+			invalidChars++
+		default:
+			out = append(out, trimmed[i:i+size]...)
+		}
+		i += size
+	}
+
+	if ampersands > 0 {
+		fixes = append(fixes, fmt.Sprintf("Escaped %d unescaped ampersands", ampersands))
+	}
+	if invalidChars > 0 {
+		fixes = append(fixes, fmt.Sprintf("Removed %d characters not allowed in XML", invalidChars))
+	}
+	return out, fixes
+}
+
+// isXMLChar reports whether r may appear in an XML 1.0 document.
+func isXMLChar(r rune) bool {
+	return r == 0x09 || r == 0x0A || r == 0x0D ||
+		(r >= 0x20 && r <= 0xD7FF) ||
+		(r >= 0xE000 && r <= 0xFFFD) ||
+		(r >= 0x10000 && r <= 0x10FFFF)
+}
@@ -0,0 +1,125 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+func TestParseFeedLeniently(t *testing.T) {
+	item := func(title, link string) string {
+		return "<item><title>" + title + "</title><link>" + link + "</link><description>Hello</description></item>"
+	}
+	doc := func(items ...string) string {
+		return `<?xml version="1.0" encoding="UTF-8"?><rss version="2.0"><channel><title>Blog</title>` + strings.Join(items, "") + `</channel></rss>`
+	}
+	win1252, _ := charmap.Windows1252.NewEncoder().String(doc(item("Café", "https://blog.example/cafe")))
+
+	type wantItem struct {
+		Title	string
+		Link	string
+	}
+	tests := []struct {
+		name		string
+		body		string
+		contentType	string
+		want		[]wantItem
+		warnings	[]string
+	}{
+		{
+			name: "well-formed",
+			body: doc(item("One", "https://blog.example/1")),
+			want: []wantItem{{"One", "https://blog.example/1"}},
+		},
+		{
+			name: "BOM",
+			body: "\xEF\xBB\xBF" + doc(item("One", "https://blog.example/1")),
+			want: []wantItem{{"One", "https://blog.example/1"}},
+		},
+		{
+			name: "BOM and whitespace with a broken item",
+			body: "\xEF\xBB\xBF\n  " + doc(item("One", "https://blog.example/1"), item("Q&A", "https://blog.example/2")),
+			want: []wantItem{{"One", "https://blog.example/1"}, {"Q&A", "https://blog.example/2"}},
+			warnings: []string{"parsed leniently", "Escaped 1 unescaped ampersands"},
+		},
+		{
+			name: "HTML entity",
+			body: doc(item("Fish&nbsp;&amp;&nbsp;chips", "https://blog.example/1")),
+			want: []wantItem{{"Fish\u00a0&\u00a0chips", "https://blog.example/1"}},
+			warnings: []string{"parsed leniently"},
+		},
+		{
+			name: "bare ampersands",
+			body: doc(item("Q&A", "https://blog.example/?a=1&b=2")),
+			want: []wantItem{{"Q&A", "https://blog.example/?a=1&b=2"}},
+			warnings: []string{"Escaped 2 unescaped ampersands"},
+		},
+		{
+			name: "control characters",
+			body: doc(item("Bell\x07", "https://blog.example/1")),
+			want: []wantItem{{"Bell", "https://blog.example/1"}},
+			warnings: []string{"Removed 1 characters not allowed in XML"},
+		},
+		{
+			name: "wrong encoding label",
+			body: win1252,
+			contentType: "application/rss+xml; charset=utf-8",
+			want: []wantItem{{"Café", "https://blog.example/cafe"}},
+		},
+		{
+			name: "unclosed HTML in the channel",
+			body: strings.Replace(doc(item("One", "https://blog.example/1"), item("Two", "https://blog.example/2")),
+				"<title>Blog</title>", "<title>Blog</title><description>A <br> blog</description>", 1),
+			want: []wantItem{{"One", "https://blog.example/1"}, {"Two", "https://blog.example/2"}},
+			warnings: []string{"parsed leniently"},
+		},
+		{
+			name: "truncated",
+			body: strings.TrimSuffix(doc(item("One", "https://blog.example/1"), item("Two", "https://blog.example/2")), "</item></channel></rss>"),
+			want: []wantItem{{"One", "https://blog.example/1"}},
+			warnings: []string{"Stopped reading feed early", "kept 1 items"},
+		},
+	}
+	for _, tt := range tests {
+		body, err := feedToUTF8([]byte(tt.body), tt.contentType)
+		if err != nil {
+			t.Fatalf("%s: feedToUTF8: %v", tt.name, err)
+		}
+		feed, warnings, err := parseFeed(body)
+		if err != nil {
+			t.Errorf("%s: parseFeed: %v", tt.name, err)
+			continue
+		}
+
+		var got []wantItem
+		for _, it := range feed.Channel.Item {
+			got = append(got, wantItem{it.Title, it.Link})
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: items = %q, want %q", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: item %d = %q, want %q", tt.name, i, got[i], tt.want[i])
+			}
+		}
+
+		joined := strings.Join(warnings, "\n")
+		if len(tt.warnings) == 0 && joined != "" {
+			t.Errorf("%s: unexpected warnings:\n%s", tt.name, joined)
+		}
+		for _, want := range tt.warnings {
+			if !strings.Contains(joined, want) {
+				t.Errorf("%s: warnings don't mention %q:\n%s", tt.name, want, joined)
+			}
+		}
+	}
+}
+
+func TestParseFeedRejectsGarbage(t *testing.T) {
+	if _, _, err := parseFeed([]byte("<html><body>Not a feed")); err == nil {
+		t.Error("parseFeed accepted an HTML page")
+	}
+}
@@ -0,0 +1,298 @@
+// Package app contains shared application services and state management.
+package app
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
+)
+
+// Patterns used to judge whether an element is likely to hold article content
+// based on its class and id attributes.
+var (
+	unlikelyCandidates = regexp.MustCompile(`(?i)ad-|banner|breadcrumb|combx|comment|community|cover-wrap|disqus|extra|footer|header|legends|menu|related|remark|replies|rss|share|shoutbox|sidebar|skyscraper|social|sponsor|popup|promo|subscribe`)
+	maybeCandidate     = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow`)
+	positiveCandidate  = regexp.MustCompile(`(?i)article|body|content|entry|hentry|h-entry|main|page|pagination|post|text|blog|story`)
+	negativeCandidate  = regexp.MustCompile(`(?i)-ad-|hidden|^hid$| hid$| hid |^hid |banner|combx|comment|com-|contact|foot|footer|footnote|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|tool|widget`)
+)
+
+// fetchArticle retrieves the page at articleURL and extracts the main article text.
+//
+// Parameters:
+//		- ctx: Context for request cancellation and timeout control
+//		- articleURL: The URL of the post to fetch
+//
+// Returns the extracted article text, or an error if the request fails,
+// returns a non-2xx status code, or no readable content is found.
+func (f *Fetcher) fetchArticle(ctx context.Context, articleURL string) (string, error) {
+	resp, err := f.get(ctx, articleURL)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", fmt.Errorf("HTTP Status %d %s", resp.StatusCode, resp.Status)
+	}
+
+	// Decode the page using its Content-Type, <meta> charset or content sniffing
+	body, err := charset.NewReader(resp.Body, resp.Header.Get("Content-Type"))
+	if err != nil {
+		return "", fmt.Errorf("Error detecting page encoding: %v", err)
+	}
+
+	doc, err := html.Parse(body)
+	if err != nil {
+		return "", fmt.Errorf("Error parsing HTML: %v", err)
+	}
+
+	content := extractArticle(doc)
+	if content == "" {
+		return "", fmt.Errorf("No readable content found")
+	}
+	return content, nil
+}
+
+// extractArticle finds the element most likely to contain the article body using a
+// readability-style scoring pass and returns its text, one paragraph per block.
+//
+// Every paragraph-like element with enough text awards points to its parent and,
+// at half weight, its grandparent. Points are based on text length and comma count,
+// adjusted by class/id hints and scaled down by link density. The best candidate is
+// combined with any siblings that scored close to it.
+func extractArticle(doc *html.Node) string {
+	removeUnlikely(doc)
+
+	scores := make(map[*html.Node]float64)
+	var candidates []*html.Node
+	addCandidate := func(n *html.Node) {
+		if n == nil || n.Type != html.ElementNode {
+			return
+		}
+		if _, ok := scores[n]; !ok {
+			scores[n] = initialScore(n)
+			candidates = append(candidates, n)
+		}
+	}
+
+	walk(doc, func(n *html.Node) {
+		if n.Type != html.ElementNode {
+			return
+		}
+		switch n.DataAtom {
+		case atom.P, atom.Pre, atom.Td, atom.Blockquote:
+		default:
+			return
+		}
+
+		text := strings.TrimSpace(textContent(n))
+		if len(text) < 25 {
+			return
+		}
+
+		score := 1 + float64(strings.Count(text, ","))
+		score += min(float64(len(text))/100, 3)
+
+		parent := n.Parent
+		addCandidate(parent)
+		if parent != nil {
+			scores[parent] += score
+			grandparent := parent.Parent
+			addCandidate(grandparent)
+			if grandparent != nil && grandparent.Type == html.ElementNode {
+				scores[grandparent] += score / 2
+			}
+		}
+	})
+
+	var top *html.Node
+	for _, c := range candidates {
+		scores[c] *= 1 - linkDensity(c)
+		if top == nil || scores[c] > scores[top] {
+			top = c
+		}
+	}
+	if top == nil {
+		return ""
+	}
+
+	// Pull in siblings that look like part of the same article
+	threshold := max(10, scores[top]*0.2)
+	var blocks []string
+	parent := top.Parent
+	if parent == nil {
+		return strings.Join(paragraphs(top), "\n\n")
+	}
+	for sib := parent.FirstChild; sib != nil; sib = sib.NextSibling {
+		if sib == top {
+			blocks = append(blocks, paragraphs(sib)...)
+			continue
+		}
+		if score, ok := scores[sib]; ok && score >= threshold {
+			blocks = append(blocks, paragraphs(sib)...)
+			continue
+		}
+		if sib.Type == html.ElementNode && sib.DataAtom == atom.P {
+			text := strings.TrimSpace(collapseSpace(textContent(sib)))
+			if len(text) > 80 && linkDensity(sib) < 0.25 {
+				blocks = append(blocks, text)
+			}
+		}
+	}
+
+	return strings.Join(blocks, "\n\n")
+}
+
+// removeUnlikely strips elements that never hold article content, such as
+// scripts, navigation and sidebars, from the tree.
+func removeUnlikely(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == html.CommentNode || (c.Type == html.ElementNode && isUnlikely(c)) {
+			n.RemoveChild(c)
+		} else {
+			removeUnlikely(c)
+		}
+		c = next
+	}
+}
+
+// isUnlikely reports whether an element should be removed before scoring.
+func isUnlikely(n *html.Node) bool {
+	switch n.DataAtom {
+	case atom.Script, atom.Style, atom.Noscript, atom.Iframe, atom.Form, atom.Nav,
+		atom.Header, atom.Footer, atom.Aside, atom.Button, atom.Select, atom.Svg:
+		return true
+	case atom.Body, atom.Html, atom.Article, atom.Main:
+		return false
+	}
+	hint := attr(n, "class") + " " + attr(n, "id")
+	return unlikelyCandidates.MatchString(hint) && !maybeCandidate.MatchString(hint)
+}
+
+// initialScore gives an element a starting score based on its tag and class/id hints.
+func initialScore(n *html.Node) float64 {
+	var score float64
+	switch n.DataAtom {
+	case atom.Article:
+		score = 10
+	case atom.Div:
+		score = 5
+	case atom.Pre, atom.Td, atom.Blockquote:
+		score = 3
+	case atom.Address, atom.Ol, atom.Ul, atom.Dl, atom.Dd, atom.Dt, atom.Li:
+		score = -3
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Th:
+		score = -5
+	}
+
+	hint := attr(n, "class") + " " + attr(n, "id")
+	if negativeCandidate.MatchString(hint) {
+		score -= 25
+	}
+	if positiveCandidate.MatchString(hint) {
+		score += 25
+	}
+	return score
+}
+
+// linkDensity returns the fraction of an element's text that sits inside links.
+func linkDensity(n *html.Node) float64 {
+	total := len(textContent(n))
+	if total == 0 {
+		return 0
+	}
+	var linked int
+	walk(n, func(c *html.Node) {
+		if c.Type == html.ElementNode && c.DataAtom == atom.A {
+			linked += len(textContent(c))
+		}
+	})
+	return float64(linked) / float64(total)
+}
+
+// paragraphs splits an element's text into blocks at paragraph-level boundaries.
+func paragraphs(n *html.Node) []string {
+	var blocks []string
+	var sb strings.Builder
+	flush := func() {
+		text := strings.TrimSpace(collapseSpace(sb.String()))
+		if text != "" {
+			blocks = append(blocks, text)
+		}
+		sb.Reset()
+	}
+
+	var visit func(*html.Node)
+	visit = func(c *html.Node) {
+		switch c.Type {
+		case html.TextNode:
+			sb.WriteString(c.Data)
+			return
+		case html.ElementNode:
+			if isBlock(c) {
+				flush()
+				defer flush()
+			} else if c.DataAtom == atom.Br {
+				sb.WriteString(" ")
+			}
+		}
+		for child := c.FirstChild; child != nil; child = child.NextSibling {
+			visit(child)
+		}
+	}
+	visit(n)
+	flush()
+
+	return blocks
+}
+
+// isBlock reports whether an element starts a new paragraph in extracted text.
+func isBlock(n *html.Node) bool {
+	switch n.DataAtom {
+	case atom.P, atom.Div, atom.Pre, atom.Blockquote, atom.Li, atom.Ul, atom.Ol,
+		atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Table, atom.Tr,
+		atom.Section, atom.Article, atom.Figure, atom.Figcaption:
+		return true
+	}
+	return false
+}
+
+// textContent returns the concatenated text of an element and its descendants.
+func textContent(n *html.Node) string {
+	var sb strings.Builder
+	walk(n, func(c *html.Node) {
+		if c.Type == html.TextNode {
+			sb.WriteString(c.Data)
+		}
+	})
+	return sb.String()
+}
+
+// collapseSpace replaces runs of whitespace with a single space.
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// attr returns the value of the named attribute, or an empty string.
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// walk calls fn for n and every node beneath it in document order.
+func walk(n *html.Node, fn func(*html.Node)) {
+	fn(n)
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		walk(c, fn)
+	}
+}
@@ -0,0 +1,56 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestExtractArticle(t *testing.T) {
+	long := "This paragraph is long enough to count, with commas, clauses, and plenty of words to score well."
+
+	tests := []struct {
+		name	string
+		page	string
+		want	string
+	}{
+		{
+			name: "article beside navigation",
+			page: `<body><nav class="menu"><p>` + long + `</p></nav>
+				<div class="post-content"><h1>Title</h1><p>` + long + `</p><p>Second ` + long + `</p></div>
+				<div class="sidebar"><p>` + long + `</p></div></body>`,
+			want: "Title\n\n" + long + "\n\nSecond " + long,
+		},
+		{
+			name: "scripts and comments dropped",
+			page: `<body><article><script>var x = "` + long + `";</script><!-- ` + long + ` --><p>` + long + `</p></article></body>`,
+			want: long,
+		},
+		{
+			name: "line breaks and whitespace collapsed",
+			page: `<body><div id="content"><p>Line one,<br>line   two,
+				and line three of a paragraph.</p></div></body>`,
+			want: "Line one, line two, and line three of a paragraph.",
+		},
+		{
+			name: "link lists lose to prose",
+			page: `<body><div><p><a href="/1">` + long + `</a></p></div><div><p>` + long + `</p></div></body>`,
+			want: long,
+		},
+		{
+			name: "nothing readable",
+			page: `<body><p>Too short.</p></body>`,
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		doc, err := html.Parse(strings.NewReader(tt.page))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := extractArticle(doc); got != tt.want {
+			t.Errorf("%s: extractArticle =\n%q\nwant\n%q", tt.name, got, tt.want)
+		}
+	}
+}
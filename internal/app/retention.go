@@ -0,0 +1,129 @@
+// Package app contains shared application services and state management.
+package app
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nhdewitt/blog-aggregator/internal/config"
+	"github.com/nhdewitt/blog-aggregator/internal/database"
+)
+
+// RetentionPolicy limits the posts kept for a feed. Zero values mean no limit.
+type RetentionPolicy struct {
+	MaxAge		time.Duration
+	MaxPosts	int
+}
+
+// RetentionFromConfig reads the global retention policy from the config.
+// Returns an error if max_age cannot be parsed.
+func RetentionFromConfig(cfg config.RetentionConfig) (RetentionPolicy, error) {
+	policy := RetentionPolicy{MaxPosts: cfg.MaxPostsPerFeed}
+	if cfg.MaxAge != "" {
+		age, err := ParseAge(cfg.MaxAge)
+		if err != nil {
+			return RetentionPolicy{}, fmt.Errorf("Invalid retention max_age: %w", err)
+		}
+		policy.MaxAge = age
+	}
+	return policy, nil
+}
+
+// forFeed applies a feed's own retention settings on top of the global policy.
+// A stored 0 turns the limit off for that feed; NULL keeps the global value.
+func (p RetentionPolicy) forFeed(feed database.Feed) RetentionPolicy {
+	if feed.RetentionMaxAge.Valid {
+		p.MaxAge = time.Duration(feed.RetentionMaxAge.Int32) * time.Second
+	}
+	if feed.RetentionMaxPosts.Valid {
+		p.MaxPosts = int(feed.RetentionMaxPosts.Int32)
+	}
+	return p
+}
+
+// PrunedFeed reports the posts removed from one feed.
+type PrunedFeed struct {
+	URL		string
+	ByAge	int64	// posts older than the maximum age
+	ByCount	int64	// posts beyond the maximum number per feed
+}
+
+// PruneReport summarizes a prune run.
+type PruneReport struct {
+	Feeds	[]PrunedFeed	// only feeds that lost posts
+	Total	int64
+}
+
+// Prune deletes posts that fall outside each feed's retention policy: first
+// those published before the maximum age, then the oldest ones beyond the
+// maximum number of posts. Posts any user has saved are always kept.
+//
+// Returns a report of the posts removed, or an error if the database fails.
+func Prune(ctx context.Context, s *State, global RetentionPolicy) (PruneReport, error) {
+	var report PruneReport
+
+	feeds, err := s.Db.ListFeeds(ctx)
+	if err != nil {
+		return report, fmt.Errorf("Error getting feeds: %w", err)
+	}
+
+	now := time.Now().UTC()
+	for _, feed := range feeds {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		policy := global.forFeed(feed)
+		pruned := PrunedFeed{URL: feed.Url}
+
+		if policy.MaxAge > 0 {
+			pruned.ByAge, err = s.Db.DeleteFeedPostsPublishedBefore(ctx, database.DeleteFeedPostsPublishedBeforeParams{
+				FeedID: feed.ID,
+				PublishedAt: now.Add(-policy.MaxAge),
+			})
+			if err != nil {
+				return report, fmt.Errorf("Error pruning old posts of %s: %w", feed.Url, err)
+			}
+		}
+		if policy.MaxPosts > 0 {
+			pruned.ByCount, err = s.Db.DeleteFeedPostsBeyond(ctx, database.DeleteFeedPostsBeyondParams{
+				FeedID: feed.ID,
+				Keep: int32(policy.MaxPosts),
+			})
+			if err != nil {
+				return report, fmt.Errorf("Error pruning extra posts of %s: %w", feed.Url, err)
+			}
+		}
+
+		if removed := pruned.ByAge + pruned.ByCount; removed > 0 {
+			report.Feeds = append(report.Feeds, pruned)
+			report.Total += removed
+		}
+	}
+	return report, nil
+}
+
+// ParseAge parses an age such as a retention limit or --older-than. In addition
+// to time.ParseDuration units it accepts whole days, e.g. "30d".
+func ParseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		// More days than a time.Duration can hold would wrap around
+		if err != nil || n <= 0 || n > math.MaxInt64/int(24*time.Hour) {
+			return 0, fmt.Errorf("invalid number of days: %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("duration must be positive: %q", s)
+	}
+	return d, nil
+}
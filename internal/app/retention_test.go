@@ -0,0 +1,172 @@
+package app_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nhdewitt/blog-aggregator/internal/app"
+	"github.com/nhdewitt/blog-aggregator/internal/config"
+	"github.com/nhdewitt/blog-aggregator/internal/database"
+)
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		in		string
+		want	time.Duration
+		ok		bool
+	}{
+		{"30d", 30 * 24 * time.Hour, true},
+		{"1d", 24 * time.Hour, true},
+		{"720h", 720 * time.Hour, true},
+		{"90m", 90 * time.Minute, true},
+		{"0d", 0, false},
+		{"-1d", 0, false},
+		{"106751d", 106751 * 24 * time.Hour, true},
+		{"106752d", 0, false},
+		{"1.5d", 0, false},
+		{"0s", 0, false},
+		{"-2h", 0, false},
+		{"soon", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := app.ParseAge(tt.in)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("ParseAge(%q) = %v, %v; want %v, ok %v", tt.in, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestRetentionFromConfig(t *testing.T) {
+	tests := []struct {
+		cfg		config.RetentionConfig
+		want	app.RetentionPolicy
+		ok		bool
+	}{
+		{config.RetentionConfig{}, app.RetentionPolicy{}, true},
+		{config.RetentionConfig{MaxAge: "30d", MaxPostsPerFeed: 100}, app.RetentionPolicy{MaxAge: 30 * 24 * time.Hour, MaxPosts: 100}, true},
+		{config.RetentionConfig{MaxPostsPerFeed: 5}, app.RetentionPolicy{MaxPosts: 5}, true},
+		{config.RetentionConfig{MaxAge: "a while"}, app.RetentionPolicy{}, false},
+	}
+	for _, tt := range tests {
+		got, err := app.RetentionFromConfig(tt.cfg)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("RetentionFromConfig(%+v) = %+v, %v; want %+v, ok %v", tt.cfg, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestPrune(t *testing.T) {
+	ctx := context.Background()
+	day := 24 * time.Hour
+	null := sql.NullInt32{}
+	limit := func(n int32) sql.NullInt32 { return sql.NullInt32{Int32: n, Valid: true} }
+
+	tests := []struct {
+		name		string
+		global		app.RetentionPolicy
+		maxAge		sql.NullInt32	// the feed's own limits, in seconds and posts
+		maxPosts	sql.NullInt32
+		byAge		int64
+		byCount		int64
+	}{
+		{"no limits", app.RetentionPolicy{}, null, null, 0, 0},
+		{"global age", app.RetentionPolicy{MaxAge: 30 * day}, null, null, 1, 0},
+		{"global count", app.RetentionPolicy{MaxPosts: 2}, null, null, 0, 1},
+		{"global age and count", app.RetentionPolicy{MaxAge: 30 * day, MaxPosts: 1}, null, null, 1, 1},
+		{"feed age", app.RetentionPolicy{}, limit(int32(5 * day / time.Second)), null, 2, 0},
+		{"feed turns age off", app.RetentionPolicy{MaxAge: 30 * day}, limit(0), null, 0, 0},
+		{"feed turns count off", app.RetentionPolicy{MaxPosts: 1}, null, limit(0), 0, 0},
+	}
+	for _, tt := range tests {
+		s, user := newTestState(t)
+		feed := addFeed(t, s, user, "https://blog.example/rss")
+		for _, age := range []time.Duration{time.Hour, 10 * day, 40 * day, 100 * day} {
+			_, err := s.Db.UpsertPost(ctx, database.UpsertPostParams{
+				ID: uuid.New(),
+				Title: age.String(),
+				Url: "https://blog.example/" + age.String(),
+				PublishedAt: sql.NullTime{Time: time.Now().UTC().Add(-age), Valid: true},
+				FeedID: feed.ID,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		// The oldest post is saved, so no limit removes it
+		saved, err := s.Db.GetPostByURL(ctx, "https://blog.example/"+(100 * day).String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Db.SavePost(ctx, database.SavePostParams{UserID: user.ID, PostID: saved.ID}); err != nil {
+			t.Fatal(err)
+		}
+		err = s.Db.SetFeedRetention(ctx, database.SetFeedRetentionParams{ID: feed.ID, RetentionMaxAge: tt.maxAge, RetentionMaxPosts: tt.maxPosts})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		report, err := app.Prune(ctx, s, tt.global)
+		if err != nil {
+			t.Fatalf("%s: Prune: %v", tt.name, err)
+		}
+		want := app.PruneReport{Total: tt.byAge + tt.byCount}
+		if want.Total > 0 {
+			want.Feeds = []app.PrunedFeed{{URL: feed.Url, ByAge: tt.byAge, ByCount: tt.byCount}}
+		}
+		if len(report.Feeds) != len(want.Feeds) || report.Total != want.Total || (len(want.Feeds) > 0 && report.Feeds[0] != want.Feeds[0]) {
+			t.Errorf("%s: Prune = %+v, want %+v", tt.name, report, want)
+		}
+		if posts := feedPosts(t, s, feed); len(posts) != 4-int(want.Total) {
+			t.Errorf("%s: %d posts left, want %d", tt.name, len(posts), 4-want.Total)
+		}
+		if _, err := s.Db.GetPostByURL(ctx, saved.Url); err != nil {
+			t.Errorf("%s: saved post was pruned: %v", tt.name, err)
+		}
+	}
+}
+
+func TestUndatedPostsSurvivePrune(t *testing.T) {
+	ctx := context.Background()
+	s, user := newTestState(t)
+	srv := newFeedServer(t)
+	url := srv.serve("/feed.xml", rssDocument("Blog",
+		testItem{"Undated", "https://blog.example/undated", ""},
+		testItem{"Garbled", "https://blog.example/garbled", "last Tuesday"},
+	))
+	feed := addFeed(t, s, user, url)
+
+	before := time.Now().UTC().Add(-time.Second)
+	got, err := app.ScrapeFeed(ctx, s, feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.New != 2 || len(got.Warnings) != 2 {
+		t.Fatalf("first scrape = %+v, want 2 new posts with a warning each", got)
+	}
+	for url, post := range feedPosts(t, s, feed) {
+		if post.PublishedAt.Before(before) {
+			t.Errorf("%s published at %v, want the time it was first seen", url, post.PublishedAt)
+		}
+	}
+
+	// Undated posts age from when they were first seen, so a prune leaves
+	// them alone and the next scrape finds them unchanged
+	report, err := app.Prune(ctx, s, app.RetentionPolicy{MaxAge: 24 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Total != 0 {
+		t.Errorf("Prune removed %d undated posts, want none", report.Total)
+	}
+	got, err = app.ScrapeFeed(ctx, s, feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.New != 0 || got.Skipped != 2 {
+		t.Errorf("second scrape = %+v, want both posts skipped as unchanged", got)
+	}
+}
@@ -6,6 +6,9 @@ type RSSFeed struct {
 		Title		string	`xml:"title"`
 		Link		string	`xml:"link"`
 		Description	string	`xml:"description"`
+		TTL			string	`xml:"ttl"`
+		SkipHours	[]string	`xml:"skipHours>hour"`
+		SkipDays	[]string	`xml:"skipDays>day"`
 		Item		[]RSSItem	`xml:"item"`
 	} `xml:"channel"`
 }
@@ -15,4 +18,6 @@ type RSSItem struct {
 	Link		string	`xml:"link"`
 	Description	string	`xml:"description"`
 	PubDate		string	`xml:"pubDate"`
+	Author		string	`xml:"author"`
+	Creator		string	`xml:"http://purl.org/dc/elements/1.1/ creator"`
 }
\ No newline at end of file
@@ -0,0 +1,223 @@
+// Package app contains shared application services and state management.
+package app
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nhdewitt/blog-aggregator/internal/database"
+)
+
+// Bounds and defaults for adaptive fetch intervals.
+const (
+	minAdaptiveInterval     = 15 * time.Minute
+	maxAdaptiveInterval     = 24 * time.Hour
+	defaultAdaptiveInterval = time.Hour
+	adaptiveSampleSize      = 20
+)
+
+// pollHints are the publisher's requests about how often a feed may be polled,
+// taken from the feed itself (<ttl>, <skipHours>, <skipDays>) and the HTTP response.
+type pollHints struct {
+	TTL			time.Duration
+	SkipHours	map[int]bool		// hours of the day in UTC
+	SkipDays	map[time.Weekday]bool
+	MaxAge		time.Duration
+	RetryAfter	time.Time
+}
+
+// hintsFromFeed collects the polling hints from a fetched feed.
+// Malformed values are ignored.
+func hintsFromFeed(result *fetchResult) pollHints {
+	hints := pollHints{
+		SkipHours: make(map[int]bool),
+		SkipDays: make(map[time.Weekday]bool),
+		MaxAge: result.MaxAge,
+	}
+
+	channel := result.Feed.Channel
+	if ttl, err := strconv.Atoi(strings.TrimSpace(channel.TTL)); err == nil && ttl > 0 {
+		hints.TTL = time.Duration(ttl) * time.Minute
+	}
+	for _, h := range channel.SkipHours {
+		hour, err := strconv.Atoi(strings.TrimSpace(h))
+		if err != nil || hour < 0 || hour > 24 {
+			continue
+		}
+		// Some feeds number hours 1-24 rather than 0-23
+		hints.SkipHours[hour%24] = true
+	}
+	for _, d := range channel.SkipDays {
+		if day, ok := weekdays[strings.ToLower(strings.TrimSpace(d))]; ok {
+			hints.SkipDays[day] = true
+		}
+	}
+
+	return hints
+}
+
+// weekdays maps the day names used in <skipDays> to time.Weekday.
+var weekdays = map[string]time.Weekday{
+	"sunday":		time.Sunday,
+	"monday":		time.Monday,
+	"tuesday":		time.Tuesday,
+	"wednesday":	time.Wednesday,
+	"thursday":		time.Thursday,
+	"friday":		time.Friday,
+	"saturday":		time.Saturday,
+}
+
+// storedHints rebuilds the polling hints saved on a feed by its last fetch,
+// along with any Retry-After the server sent since.
+func storedHints(feed database.Feed) pollHints {
+	hints := pollHints{
+		TTL: time.Duration(feed.TtlMinutes.Int32) * time.Minute,
+		SkipHours: make(map[int]bool),
+		SkipDays: make(map[time.Weekday]bool),
+		MaxAge: time.Duration(feed.CacheMaxAge.Int32) * time.Second,
+		RetryAfter: feed.RetryAfter.Time,
+	}
+	for _, h := range strings.Split(feed.SkipHours.String, ",") {
+		if hour, err := strconv.Atoi(h); err == nil {
+			hints.SkipHours[hour] = true
+		}
+	}
+	for _, d := range strings.Split(feed.SkipDays.String, ",") {
+		if day, ok := weekdays[strings.ToLower(d)]; ok {
+			hints.SkipDays[day] = true
+		}
+	}
+	return hints
+}
+
+// SetFetchInterval changes how often a feed is fetched and reschedules its
+// next fetch from when it was last fetched, so the new interval takes effect
+// at once. A fetch deferred by Retry-After is never brought forward.
+func SetFetchInterval(ctx context.Context, s *State, feed database.Feed, interval sql.NullInt32, adaptive bool) error {
+	feed.FetchInterval = interval
+	feed.AdaptiveInterval = adaptive
+	next, _, err := nextFetch(ctx, s, feed, storedHints(feed), feed.LastFetchedAt.Time)
+	if err != nil {
+		return err
+	}
+	return s.Db.SetFeedFetchInterval(ctx, database.SetFeedFetchIntervalParams{
+		ID: feed.ID,
+		FetchInterval: interval,
+		AdaptiveInterval: adaptive,
+		NextFetchAt: next,
+	})
+}
+
+// nextFetch works out when a feed fetched at the given time should next be
+// fetched and which interval to store for it. Feeds without an interval or
+// polling hints are due again immediately, so they are picked up on the next
+// aggregator tick as before.
+//
+// In adaptive mode the interval is learned from the feed's recent posting
+// frequency; otherwise the configured interval is used as-is. The publisher's
+// <ttl>, Cache-Control max-age and Retry-After can only push the next fetch
+// later, and it is then moved out of any <skipHours> or <skipDays>.
+func nextFetch(ctx context.Context, s *State, feed database.Feed, hints pollHints, fetched time.Time) (sql.NullTime, sql.NullInt32, error) {
+	interval := feed.FetchInterval
+	if feed.AdaptiveInterval {
+		learned, err := adaptiveInterval(ctx, s, feed.ID)
+		if err != nil {
+			return sql.NullTime{}, sql.NullInt32{}, err
+		}
+		interval = sql.NullInt32{Int32: int32(learned / time.Second), Valid: true}
+	}
+
+	now := time.Now().UTC()
+	next := fetched
+	if interval.Valid {
+		next = fetched.Add(time.Duration(interval.Int32) * time.Second)
+	}
+	next = later(next, fetched.Add(hints.TTL))
+	next = later(next, fetched.Add(hints.MaxAge))
+	next = later(next, hints.RetryAfter)
+	next = skipForward(next, hints)
+
+	if !next.After(now) {
+		return sql.NullTime{}, interval, nil
+	}
+	return sql.NullTime{Time: next, Valid: true}, interval, nil
+}
+
+// skipForward moves t to the start of the next hour that is not excluded
+// by the feed's <skipHours> or <skipDays>. If every hour is excluded, t is
+// returned unchanged rather than never fetching the feed again.
+func skipForward(t time.Time, hints pollHints) time.Time {
+	candidate := t
+	for i := 0; i < 24*7; i++ {
+		if !hints.SkipHours[candidate.Hour()] && !hints.SkipDays[candidate.Weekday()] {
+			return candidate
+		}
+		candidate = candidate.Truncate(time.Hour).Add(time.Hour)
+	}
+	return t
+}
+
+// later returns whichever of a and b is later.
+func later(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
+
+// joinHours formats a set of hours for storage, e.g. "0,1,2".
+func joinHours(hours map[int]bool) sql.NullString {
+	var parts []string
+	for hour := 0; hour < 24; hour++ {
+		if hours[hour] {
+			parts = append(parts, strconv.Itoa(hour))
+		}
+	}
+	return sql.NullString{String: strings.Join(parts, ","), Valid: len(parts) > 0}
+}
+
+// joinDays formats a set of weekdays for storage, e.g. "Saturday,Sunday".
+func joinDays(days map[time.Weekday]bool) sql.NullString {
+	var parts []string
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if days[day] {
+			parts = append(parts, day.String())
+		}
+	}
+	return sql.NullString{String: strings.Join(parts, ","), Valid: len(parts) > 0}
+}
+
+// adaptiveInterval estimates how often a feed should be polled from the gaps
+// between its most recent posts. Polling at half the median gap catches most new
+// posts soon after they appear without polling quiet feeds constantly.
+func adaptiveInterval(ctx context.Context, s *State, feedID uuid.UUID) (time.Duration, error) {
+	times, err := s.Db.GetRecentPostTimes(ctx, database.GetRecentPostTimesParams{
+		FeedID: feedID,
+		Limit: adaptiveSampleSize,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var gaps []time.Duration
+	for i := 1; i < len(times); i++ {
+		if times[i].IsZero() {
+			continue
+		}
+		if gap := times[i-1].Sub(times[i]); gap > 0 {
+			gaps = append(gaps, gap)
+		}
+	}
+	if len(gaps) == 0 {
+		return defaultAdaptiveInterval, nil
+	}
+
+	slices.Sort(gaps)
+	interval := gaps[len(gaps)/2] / 2
+	return min(max(interval, minAdaptiveInterval), maxAdaptiveInterval), nil
+}
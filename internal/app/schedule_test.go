@@ -0,0 +1,116 @@
+package app
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestHintsFromFeed(t *testing.T) {
+	feed := &RSSFeed{}
+	feed.Channel.TTL = " 90 "
+	feed.Channel.SkipHours = []string{"0", "7", "24", "25", "noon"}
+	feed.Channel.SkipDays = []string{"Saturday", " sunday ", "Caturday"}
+
+	hints := hintsFromFeed(&fetchResult{Feed: feed, MaxAge: time.Minute})
+	if hints.TTL != 90*time.Minute || hints.MaxAge != time.Minute {
+		t.Errorf("TTL, MaxAge = %v, %v; want 1h30m, 1m", hints.TTL, hints.MaxAge)
+	}
+	if got := joinHours(hints.SkipHours).String; got != "0,7" {
+		t.Errorf("skip hours = %q, want 0,7 (24 is midnight, 25 is out of range)", got)
+	}
+	if got := joinDays(hints.SkipDays).String; got != "Sunday,Saturday" {
+		t.Errorf("skip days = %q, want Sunday,Saturday", got)
+	}
+
+	feed.Channel.TTL = "-5"
+	if hints := hintsFromFeed(&fetchResult{Feed: feed}); hints.TTL != 0 {
+		t.Errorf("TTL for -5 = %v, want none", hints.TTL)
+	}
+}
+
+func TestSkipForward(t *testing.T) {
+	// Friday, March 1 2024
+	friday := time.Date(2024, 3, 1, 7, 30, 0, 0, time.UTC)
+	allHours := make(map[int]bool)
+	for hour := 0; hour < 24; hour++ {
+		allHours[hour] = true
+	}
+
+	tests := []struct {
+		name	string
+		hints	pollHints
+		want	time.Time
+	}{
+		{"nothing skipped", pollHints{}, friday},
+		{"skipped hour", pollHints{SkipHours: map[int]bool{7: true, 8: true}}, time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)},
+		{"skipped weekend", pollHints{SkipDays: map[time.Weekday]bool{time.Friday: true, time.Saturday: true, time.Sunday: true}}, time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)},
+		{"hours and days", pollHints{
+			SkipHours: map[int]bool{0: true, 1: true},
+			SkipDays: map[time.Weekday]bool{time.Friday: true},
+		}, time.Date(2024, 3, 2, 2, 0, 0, 0, time.UTC)},
+		{"everything skipped", pollHints{SkipHours: allHours}, friday},
+	}
+	for _, tt := range tests {
+		if got := skipForward(friday, tt.hints); !got.Equal(tt.want) {
+			t.Errorf("%s: skipForward = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseMaxAge(t *testing.T) {
+	tests := []struct {
+		header	string
+		want	time.Duration
+	}{
+		{"", 0},
+		{"max-age=300", 5 * time.Minute},
+		{"public, MAX-AGE=\"60\"", time.Minute},
+		{"max-age=0", 0},
+		{"max-age=soon", 0},
+		{"max-age=300, no-cache", 0},
+		{"no-store, max-age=300", 0},
+	}
+	for _, tt := range tests {
+		if got := parseMaxAge(tt.header); got != tt.want {
+			t.Errorf("parseMaxAge(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	date := time.Now().UTC().Add(2 * time.Hour).Truncate(time.Second)
+	if got := parseRetryAfter(date.Format(http.TimeFormat)); !got.Equal(date) {
+		t.Errorf("parseRetryAfter of an HTTP date = %v, want %v", got, date)
+	}
+
+	tests := []struct {
+		header	string
+		want	time.Duration	// from now
+		ok		bool
+	}{
+		{"", 0, false},
+		{"120", 2 * time.Minute, true},
+		{" 0 ", 0, true},
+		{"-5", 0, false},
+		{"tomorrow", 0, false},
+		{"604800", maxRetryAfter, true},
+		{"604801", maxRetryAfter, true},
+		{"9223372036", maxRetryAfter, true},
+		{"99999999999999999999999", maxRetryAfter, true},
+		{"-99999999999999999999999", 0, false},
+		{"Fri, 01 Jan 2100 00:00:00 GMT", maxRetryAfter, true},
+	}
+	for _, tt := range tests {
+		got := parseRetryAfter(tt.header)
+		if !tt.ok {
+			if !got.IsZero() {
+				t.Errorf("parseRetryAfter(%q) = %v, want none", tt.header, got)
+			}
+			continue
+		}
+		if until := time.Until(got); until < tt.want-time.Second || until > tt.want {
+			t.Errorf("parseRetryAfter(%q) is %v from now, want %v", tt.header, until, tt.want)
+		}
+	}
+}
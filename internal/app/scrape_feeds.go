@@ -4,8 +4,10 @@ package app
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -18,38 +20,144 @@ const (
 	timeNoLeadingZeroZ = "Mon, 2 Jan 2006 15:04:05 -0700"	// RFC1123Z without leading zero
 )
 
-// ScrapeFeeds fetches the next feed from the database and processes all its posts.
-// It retrieves the RSS feed content, parses each post item, and stores new posts
-// in the database. The feed is marked as fetched after successful processing.
+// failureBackoff is how long a feed that could not be fetched waits before it is tried again.
+const failureBackoff = 5 * time.Minute
+
+// FeedError reports a failure while processing one specific feed, as opposed
+// to a problem that affects every feed such as the database being unreachable.
+type FeedError struct {
+	URL		string
+	Err		error
+}
+
+func (e *FeedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *FeedError) Unwrap() error {
+	return e.Err
+}
+
+// ErrNoFeedsDue is returned by ScrapeFeeds when every feed was fetched recently
+// enough that none is due yet.
+var ErrNoFeedsDue = errors.New("no feeds are due for fetching")
+
+// ScrapeResult summarizes what happened to a feed's items during a scrape.
+type ScrapeResult struct {
+	New			int			// posts that were not stored before
+	Updated		int			// existing posts whose title, description, date or author changed
+	Skipped		int			// existing posts with no changes
+	Failed		int			// items that could not be stored
+	Warnings	[]string	// problems that did not stop the scrape, such as unparseable dates
+}
+
+// ScrapeFeeds fetches the next due feed from the database and processes all its posts
+// with ScrapeFeed. Warnings from processing are logged. Feeds fetched at or after
+// fetchedBefore are skipped, so a pass that starts at fetchedBefore visits each due
+// feed once even if the feed is due again right away.
+//
+// Returns the feed that was picked, along with ErrNoFeedsDue if no feed is due, a
+// *FeedError if the picked feed cannot be fetched, parsed or updated, or another error
+// if the next feed cannot be looked up.
+func ScrapeFeeds(ctx context.Context, s *State, fetchedBefore time.Time) (database.Feed, error) {
+	// Get the next feed that is due
+	nextFeed, err := s.Db.GetNextFeedToFetch(ctx, database.GetNextFeedToFetchParams{
+		Now: time.Now().UTC(),
+		FetchedBefore: fetchedBefore.UTC(),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.Feed{}, ErrNoFeedsDue
+	}
+	if err != nil {
+		return database.Feed{}, fmt.Errorf("Unable to grab next feed to fetch: %w", err)
+	}
+
+	result, err := ScrapeFeed(ctx, s, nextFeed)
+	for _, warning := range result.Warnings {
+		log.Println(warning)
+	}
+	return nextFeed, err
+}
+
+// ScrapeFeed runs the full ingest pipeline for one feed. It retrieves the RSS feed
+// content, parses each post item, and stores new or changed posts in the database.
+// The feed is marked as fetched after successful processing and its next fetch is
+// scheduled from its refresh interval and the publisher's polling hints (<ttl>,
+// <skipHours>, <skipDays>, Cache-Control max-age). A feed that cannot be fetched is
+// retried after failureBackoff, or after Retry-After if the server sent one. When the
+// feed has permanently moved (301/308), its stored URL is updated, or it is merged
+// into the feed that already uses the new URL.
 //
 // This function handles various RSS date formates and gracefully handles parsing errors
-// by logging them and continuing with the next post. HTML entities in titles and
-// descriptions are automatically unescaped.
+// by recording a warning and continuing with the next post. HTML entities in titles and
+// descriptions are automatically unescaped. Malformed feeds are parsed leniently; the
+// repairs are returned as warnings and saved on the feed until it parses cleanly again.
+// For feeds with full text enabled, the article behind each new post is fetched and its
+// main content stored as well.
 //
-// Returns an error if the feed cannot be fetched, parsed, or if db operations fail.
-func ScrapeFeeds(s *State) error {
-	c := context.Background()
+// Returns counts of new, updated and skipped posts along with any warnings, and a
+// *FeedError if the feed cannot be fetched, parsed or updated.
+func ScrapeFeed(ctx context.Context, s *State, dbFeed database.Feed) (ScrapeResult, error) {
+	var scrape ScrapeResult
 
-	// Get the next feed
-	nextFeed, err := s.Db.GetNextFeedToFetch(c)
+	// Fetch and parse the RSS feed
+	result, err := s.Fetcher.fetchFeed(ctx, dbFeed.Url)
 	if err != nil {
-		return fmt.Errorf("Unable to grab next feed to fetch: %w", err)
+		// Back off until the time the server asked for, or for failureBackoff
+		// so one broken feed doesn't hold up the rest
+		params := database.DeferFeedFetchParams{
+			ID: dbFeed.ID,
+			NextFetchAt: sql.NullTime{Time: time.Now().UTC().Add(failureBackoff), Valid: true},
+		}
+		var statusErr *HTTPStatusError
+		if errors.As(err, &statusErr) && !statusErr.RetryAfter.IsZero() {
+			params.NextFetchAt.Time = statusErr.RetryAfter
+			params.RetryAfter = params.NextFetchAt
+		}
+		deferErr := s.Db.DeferFeedFetch(ctx, params)
+		if deferErr != nil {
+			scrape.warn("Could not defer feed %s: %s", dbFeed.Url, deferErr)
+		}
+		return scrape, &FeedError{URL: dbFeed.Url, Err: fmt.Errorf("Unable to fetch feed %s: %w", dbFeed.Url, err)}
+	}
+	feed := result.Feed
+	for _, warning := range result.Warnings {
+		scrape.warn("Feed %s: %s", dbFeed.Url, warning)
 	}
 
-	// Fetch and parse the RSS feed
-	feed, err := fetchFeed(c, nextFeed.Url)
-	if err != nil {
-		return fmt.Errorf("Unable to fetch feed: %w", err)
+	// Follow a permanent redirect by moving the feed to its new URL
+	if result.MovedTo != "" && result.MovedTo != dbFeed.Url {
+		oldURL := dbFeed.Url
+		moved, merged, err := moveFeed(ctx, s, dbFeed, result.MovedTo)
+		switch {
+		case err != nil:
+			scrape.warn("Could not move feed %s to %s: %s", oldURL, result.MovedTo, err)
+		case merged:
+			scrape.warn("Feed %s moved permanently to %s and was merged into the existing feed", oldURL, result.MovedTo)
+			dbFeed = moved
+		default:
+			scrape.warn("Feed %s moved permanently to %s", oldURL, result.MovedTo)
+			dbFeed = moved
+		}
 	}
-	feedID := nextFeed.ID
 
 	// Process each item in the feed
 	for _, item := range feed.Channel.Item {
+		// Stop early if the aggregator is shutting down
+		if err := ctx.Err(); err != nil {
+			return scrape, &FeedError{URL: dbFeed.Url, Err: fmt.Errorf("Stopped processing feed %s: %w", dbFeed.Url, err)}
+		}
+
 		// Handle optional description field
 		description := sql.NullString{
 			String: item.Description,
 			Valid: item.Description != "",
 		}
+		// Prefer <author>, falling back to <dc:creator>
+		author := item.Author
+		if author == "" {
+			author = item.Creator
+		}
 		// Try multiple date formats
 		validTimeStrings := []string{
 			time.RFC1123,			// "Mon 02 Jan 2006 15:04:05 MST"
@@ -64,28 +172,85 @@ func ScrapeFeeds(s *State) error {
 				break
 			}
 		}
+		// An undated post is stored as published when first seen
 		if err != nil {
-			log.Printf("Could not parse pubDate %s from feed %s: %s\n", item.PubDate, feed.Channel.Title, err)
+			scrape.warn("Could not parse pubDate %s from feed %s: %s", item.PubDate, feed.Channel.Title, err)
 		}
 
-		// Create the post in the database
-		err = s.Db.CreatePost(c, database.CreatePostParams{
+		// Create the post in the database, or update it if it changed
+		post, err := s.Db.UpsertPost(ctx, database.UpsertPostParams{
 			ID: uuid.New(),
 			Title: item.Title,
 			Url: item.Link,
 			Description: description,
-			PublishedAt: parsedPubDate.UTC(),
-			FeedID: feedID,
+			PublishedAt: sql.NullTime{Time: parsedPubDate.UTC(), Valid: err == nil},
+			FeedID: dbFeed.ID,
+			Author: sql.NullString{String: author, Valid: author != ""},
 		})
+		if errors.Is(err, sql.ErrNoRows) {
+			scrape.Skipped++
+			continue
+		}
 		if err != nil {
-			log.Printf("Could not create post: %s\n", err)
+			scrape.Failed++
+			scrape.warn("Could not create post: %s", err)
+			continue
+		}
+		if !post.Inserted {
+			scrape.Updated++
+			continue
+		}
+		scrape.New++
+
+		// Store the full article text for new posts if the feed asks for it
+		if dbFeed.FetchFullText {
+			if err := storeArticleContent(ctx, s, post.ID, item.Link); err != nil {
+				scrape.warn("%s", err)
+			}
 		}
 	}
 
-	// Mark the feed as successfully fetched
-	err = s.Db.MarkFeedFetched(c, nextFeed.ID)
+	// Mark the feed as successfully fetched and schedule its next fetch
+	hints := hintsFromFeed(result)
+	nextFetchAt, interval, err := nextFetch(ctx, s, dbFeed, hints, time.Now().UTC())
+	if err != nil {
+		return scrape, &FeedError{URL: dbFeed.Url, Err: fmt.Errorf("Unable to schedule next fetch for %s: %w", dbFeed.Url, err)}
+	}
+	err = s.Db.MarkFeedFetched(ctx, database.MarkFeedFetchedParams{
+		ID: dbFeed.ID,
+		NextFetchAt: nextFetchAt,
+		FetchInterval: interval,
+		TtlMinutes: sql.NullInt32{Int32: int32(hints.TTL / time.Minute), Valid: hints.TTL > 0},
+		SkipHours: joinHours(hints.SkipHours),
+		SkipDays: joinDays(hints.SkipDays),
+		CacheMaxAge: sql.NullInt32{Int32: int32(hints.MaxAge / time.Second), Valid: hints.MaxAge > 0},
+		ParseWarnings: sql.NullString{String: strings.Join(result.Warnings, "\n"), Valid: len(result.Warnings) > 0},
+	})
+	if err != nil {
+		return scrape, &FeedError{URL: dbFeed.Url, Err: fmt.Errorf("Unable to update fetched feed %s: %w", dbFeed.Url, err)}
+	}
+	return scrape, nil
+}
+
+// warn records a non-fatal problem encountered while scraping.
+func (r *ScrapeResult) warn(format string, args ...any) {
+	r.Warnings = append(r.Warnings, fmt.Sprintf(format, args...))
+}
+
+// storeArticleContent fetches the article at postURL and saves its extracted text
+// as the post's content. On failure the post is left without content.
+func storeArticleContent(ctx context.Context, s *State, postID uuid.UUID, postURL string) error {
+	content, err := s.Fetcher.fetchArticle(ctx, postURL)
+	if err != nil {
+		return fmt.Errorf("Could not fetch article %s: %w", postURL, err)
+	}
+
+	err = s.Db.SetPostContent(ctx, database.SetPostContentParams{
+		ID: postID,
+		Content: sql.NullString{String: content, Valid: true},
+	})
 	if err != nil {
-		return fmt.Errorf("Unable to update fetched feed: %w", err)
+		return fmt.Errorf("Could not store article content: %w", err)
 	}
 	return nil
 }
\ No newline at end of file
@@ -0,0 +1,615 @@
+package app_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nhdewitt/blog-aggregator/internal/app"
+	"github.com/nhdewitt/blog-aggregator/internal/config"
+	"github.com/nhdewitt/blog-aggregator/internal/database"
+	"github.com/nhdewitt/blog-aggregator/internal/store"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// newTestState returns a State with an empty in-memory store and one user,
+// who adds the feeds created by addFeed.
+func newTestState(t *testing.T) (*app.State, database.User) {
+	t.Helper()
+	fetcher, err := app.NewFetcher(config.FetchConfig{})
+	if err != nil {
+		t.Fatalf("Error creating fetcher: %v", err)
+	}
+	s := &app.State{Cfg: &config.Config{}, Db: store.NewMemory(), Fetcher: fetcher}
+
+	user, err := s.Db.CreateUser(context.Background(), database.CreateUserParams{
+		ID: uuid.New(),
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+		Name: "alice",
+	})
+	if err != nil {
+		t.Fatalf("Error creating user: %v", err)
+	}
+	return s, user
+}
+
+// addFeed stores a feed for url.
+func addFeed(t *testing.T, s *app.State, user database.User, url string) database.Feed {
+	t.Helper()
+	feed, err := s.Db.AddFeed(context.Background(), database.AddFeedParams{
+		ID: uuid.New(),
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+		Name: url,
+		Url: url,
+		UserID: user.ID,
+	})
+	if err != nil {
+		t.Fatalf("Error adding feed: %v", err)
+	}
+	return feed
+}
+
+// feedPosts returns the stored posts of a feed by URL.
+func feedPosts(t *testing.T, s *app.State, feed database.Feed) map[string]database.Post {
+	t.Helper()
+	posts := make(map[string]database.Post)
+	all, err := s.Db.ListPosts(context.Background())
+	if err != nil {
+		t.Fatalf("Error listing posts: %v", err)
+	}
+	for _, post := range all {
+		if post.FeedID == feed.ID {
+			posts[post.Url] = post
+		}
+	}
+	return posts
+}
+
+// testItem is one <item> of a served feed.
+type testItem struct {
+	Title	string
+	Link	string
+	PubDate	string
+}
+
+// rssDocument builds an RSS 2.0 feed with the given items.
+func rssDocument(title string, items ...testItem) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	b.WriteString(`<rss version="2.0"><channel>`)
+	fmt.Fprintf(&b, "<title>%s</title>", title)
+	for _, item := range items {
+		fmt.Fprintf(&b, "<item><title>%s</title><link>%s</link><pubDate>%s</pubDate></item>", item.Title, item.Link, item.PubDate)
+	}
+	b.WriteString("</channel></rss>")
+	return b.String()
+}
+
+// pubDate formats a time the way RSS feeds usually do.
+func pubDate(t time.Time) string {
+	return t.Format(time.RFC1123Z)
+}
+
+// feedServer serves responses by path over HTTP.
+type feedServer struct {
+	*httptest.Server
+	mu			sync.Mutex
+	handlers	map[string]http.HandlerFunc
+	requests	map[string]int
+}
+
+// newFeedServer starts a feedServer that is shut down when the test ends.
+func newFeedServer(t *testing.T) *feedServer {
+	t.Helper()
+	fs := &feedServer{handlers: make(map[string]http.HandlerFunc), requests: make(map[string]int)}
+	fs.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fs.mu.Lock()
+		handler, ok := fs.handlers[r.URL.Path]
+		fs.requests[r.URL.Path]++
+		fs.mu.Unlock()
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(fs.Close)
+	return fs
+}
+
+// handle serves path with handler and returns its URL.
+func (fs *feedServer) handle(path string, handler http.HandlerFunc) string {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.handlers[path] = handler
+	return fs.URL + path
+}
+
+// serve publishes an RSS document at path and returns its URL.
+func (fs *feedServer) serve(path, doc string) string {
+	return fs.handle(path, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		io.WriteString(w, doc)
+	})
+}
+
+// count returns how many requests path has received.
+func (fs *feedServer) count(path string) int {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.requests[path]
+}
+
+func TestScrapeFeedCountsChanges(t *testing.T) {
+	ctx := context.Background()
+	s, user := newTestState(t)
+	srv := newFeedServer(t)
+	published := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	first := rssDocument("Blog",
+		testItem{"One", "https://blog.example/1", pubDate(published)},
+		testItem{"Two", "https://blog.example/2", pubDate(published.Add(time.Hour))},
+	)
+	second := rssDocument("Blog",
+		testItem{"One", "https://blog.example/1", pubDate(published)},
+		testItem{"Two, revised", "https://blog.example/2", pubDate(published.Add(time.Hour))},
+		testItem{"Three", "https://blog.example/3", pubDate(published.Add(2 * time.Hour))},
+	)
+	feed := addFeed(t, s, user, srv.serve("/feed.xml", first))
+
+	tests := []struct {
+		name	string
+		doc		string
+		want	app.ScrapeResult
+	}{
+		{"first fetch", first, app.ScrapeResult{New: 2}},
+		{"unchanged", first, app.ScrapeResult{Skipped: 2}},
+		{"edited and added", second, app.ScrapeResult{New: 1, Updated: 1, Skipped: 1}},
+	}
+	for _, tt := range tests {
+		srv.serve("/feed.xml", tt.doc)
+		got, err := app.ScrapeFeed(ctx, s, feed)
+		if err != nil {
+			t.Fatalf("%s: ScrapeFeed: %v", tt.name, err)
+		}
+		got.Warnings = nil
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("%s: ScrapeFeed = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+
+	posts := feedPosts(t, s, feed)
+	if len(posts) != 3 {
+		t.Fatalf("%d posts stored, want 3", len(posts))
+	}
+	if got := posts["https://blog.example/2"]; got.Title != "Two, revised" || !got.PublishedAt.Equal(published.Add(time.Hour)) {
+		t.Errorf("post 2 = %q published %v, want the revised title published %v", got.Title, got.PublishedAt, published.Add(time.Hour))
+	}
+
+	fetched, err := s.Db.FindFeedsByURL(ctx, feed.Url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fetched.LastFetchedAt.Valid {
+		t.Error("feed not marked as fetched")
+	}
+}
+
+func TestScrapeFeedHTTPErrorDefersFeed(t *testing.T) {
+	ctx := context.Background()
+	s, user := newTestState(t)
+	srv := newFeedServer(t)
+	url := srv.handle("/busy.xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	feed := addFeed(t, s, user, url)
+
+	_, err := app.ScrapeFeed(ctx, s, feed)
+	var feedErr *app.FeedError
+	if !errors.As(err, &feedErr) || feedErr.URL != url {
+		t.Fatalf("ScrapeFeed error = %v, want a *FeedError for %s", err, url)
+	}
+	var statusErr *app.HTTPStatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("ScrapeFeed error = %v, want an HTTP 503 *HTTPStatusError", err)
+	}
+
+	deferred, err := s.Db.FindFeedsByURL(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if until := time.Until(deferred.NextFetchAt.Time); !deferred.NextFetchAt.Valid || until < 59*time.Minute || until > time.Hour {
+		t.Errorf("next fetch in %v, want the hour asked for by Retry-After", until)
+	}
+	if _, err := app.ScrapeFeeds(ctx, s, time.Now()); !errors.Is(err, app.ErrNoFeedsDue) {
+		t.Errorf("ScrapeFeeds after a deferred fetch = %v, want ErrNoFeedsDue", err)
+	}
+}
+
+func TestScrapeFeedsVisitsEachDueFeedOnce(t *testing.T) {
+	ctx := context.Background()
+	s, user := newTestState(t)
+	srv := newFeedServer(t)
+	doc := rssDocument("Blog", testItem{"Post", "https://blog.example/post", pubDate(time.Now())})
+	addFeed(t, s, user, srv.serve("/a.xml", doc))
+	addFeed(t, s, user, srv.serve("/b.xml", strings.ReplaceAll(doc, "/post", "/other")))
+
+	// Neither feed has an interval, so both are due again right after a fetch
+	passStart := time.Now()
+	for i := 0; i < 2; i++ {
+		if _, err := app.ScrapeFeeds(ctx, s, passStart); err != nil {
+			t.Fatalf("ScrapeFeeds #%d: %v", i+1, err)
+		}
+	}
+	if _, err := app.ScrapeFeeds(ctx, s, passStart); !errors.Is(err, app.ErrNoFeedsDue) {
+		t.Fatalf("third ScrapeFeeds in the pass = %v, want ErrNoFeedsDue", err)
+	}
+	if srv.count("/a.xml") != 1 || srv.count("/b.xml") != 1 {
+		t.Errorf("fetched a.xml %d and b.xml %d times, want once each", srv.count("/a.xml"), srv.count("/b.xml"))
+	}
+
+	// A later pass picks them up again
+	if _, err := app.ScrapeFeeds(ctx, s, time.Now()); err != nil {
+		t.Errorf("ScrapeFeeds in the next pass: %v", err)
+	}
+}
+
+func TestScrapeFeedStopsWhenCancelled(t *testing.T) {
+	s, user := newTestState(t)
+	srv := newFeedServer(t)
+	feed := addFeed(t, s, user, srv.serve("/feed.xml", rssDocument("Blog",
+		testItem{"Post", "https://blog.example/post", pubDate(time.Now())},
+	)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := app.ScrapeFeed(ctx, s, feed)
+	if err == nil {
+		t.Fatal("ScrapeFeed with a cancelled context succeeded")
+	}
+	if posts := feedPosts(t, s, feed); len(posts) != 0 {
+		t.Errorf("%d posts stored after cancelling, want none", len(posts))
+	}
+
+	fetched, err := s.Db.FindFeedsByURL(context.Background(), feed.Url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fetched.LastFetchedAt != (sql.NullTime{}) {
+		t.Error("cancelled scrape marked the feed as fetched")
+	}
+}
+
+func TestScrapeFeedStoresFullText(t *testing.T) {
+	ctx := context.Background()
+	s, user := newTestState(t)
+	srv := newFeedServer(t)
+	article := "The whole article is only on the page itself, with enough words, and commas, to be found."
+	page := srv.handle("/post", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, `<html><body><div class="sidebar"><p>Subscribe to our newsletter, it's very good, really.</p></div><article><p>`+article+`</p></article></body></html>`)
+	})
+	missing := srv.URL + "/gone"
+	feed := addFeed(t, s, user, srv.serve("/feed.xml", rssDocument("Blog",
+		testItem{"Post", page, pubDate(time.Now())},
+		testItem{"Gone", missing, pubDate(time.Now())},
+	)))
+	if err := s.Db.SetFeedFullText(ctx, database.SetFeedFullTextParams{ID: feed.ID, FetchFullText: true}); err != nil {
+		t.Fatal(err)
+	}
+	feed.FetchFullText = true
+
+	got, err := app.ScrapeFeed(ctx, s, feed)
+	if err != nil {
+		t.Fatalf("ScrapeFeed: %v", err)
+	}
+	if got.New != 2 || len(got.Warnings) != 1 || !strings.Contains(got.Warnings[0], "Could not fetch article "+missing) {
+		t.Errorf("ScrapeFeed = %+v, want 2 new posts and a warning about %s", got, missing)
+	}
+
+	posts := feedPosts(t, s, feed)
+	if content := posts[page].Content; !content.Valid || content.String != article {
+		t.Errorf("content of %s = %+v, want the article text", page, content)
+	}
+	if content := posts[missing].Content; content.Valid {
+		t.Errorf("content of %s = %q, want none", missing, content.String)
+	}
+}
+
+func TestScrapeFeedSchedulesByInterval(t *testing.T) {
+	now := time.Now()
+	every := func(gap time.Duration, n int) []testItem {
+		var items []testItem
+		for i := 0; i < n; i++ {
+			link := fmt.Sprintf("https://blog.example/%d", i)
+			items = append(items, testItem{"Post", link, pubDate(now.Add(-time.Duration(i+1) * gap))})
+		}
+		return items
+	}
+
+	tests := []struct {
+		name		string
+		interval	sql.NullInt32
+		adaptive	bool
+		items		[]testItem
+		want		time.Duration	// 0 if the feed is due on the next pass
+	}{
+		{"no interval", sql.NullInt32{}, false, every(time.Hour, 3), 0},
+		{"fixed interval", sql.NullInt32{Int32: 6 * 3600, Valid: true}, false, every(time.Hour, 3), 6 * time.Hour},
+		{"adaptive halves the median gap", sql.NullInt32{}, true, every(4*time.Hour, 5), 2 * time.Hour},
+		{"adaptive without enough posts", sql.NullInt32{}, true, every(time.Hour, 1), time.Hour},
+		{"adaptive lower bound", sql.NullInt32{}, true, every(time.Minute, 5), 15 * time.Minute},
+		{"adaptive upper bound", sql.NullInt32{}, true, every(10*24*time.Hour, 3), 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		ctx := context.Background()
+		s, user := newTestState(t)
+		srv := newFeedServer(t)
+		feed := addFeed(t, s, user, srv.serve("/feed.xml", rssDocument("Blog", tt.items...)))
+		err := s.Db.SetFeedFetchInterval(ctx, database.SetFeedFetchIntervalParams{
+			ID: feed.ID,
+			FetchInterval: tt.interval,
+			AdaptiveInterval: tt.adaptive,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if feed, err = s.Db.FindFeedsByURL(ctx, feed.Url); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := app.ScrapeFeed(ctx, s, feed); err != nil {
+			t.Fatalf("%s: ScrapeFeed: %v", tt.name, err)
+		}
+		fetched, err := s.Db.FindFeedsByURL(ctx, feed.Url)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if tt.want == 0 {
+			if fetched.NextFetchAt.Valid {
+				t.Errorf("%s: next fetch at %v, want the next pass", tt.name, fetched.NextFetchAt.Time)
+			}
+			continue
+		}
+		if got := time.Duration(fetched.FetchInterval.Int32) * time.Second; got != tt.want {
+			t.Errorf("%s: interval = %v, want %v", tt.name, got, tt.want)
+		}
+		if until := time.Until(fetched.NextFetchAt.Time); until < tt.want-time.Minute || until > tt.want {
+			t.Errorf("%s: next fetch in %v, want %v", tt.name, until, tt.want)
+		}
+	}
+}
+
+func TestSetFetchInterval(t *testing.T) {
+	ctx := context.Background()
+	hours := func(n int32) sql.NullInt32 { return sql.NullInt32{Int32: n * 3600, Valid: true} }
+
+	tests := []struct {
+		name		string
+		fetched		bool			// whether the feed has been fetched
+		retryAfter	time.Duration	// a pending Retry-After, if any
+		interval	sql.NullInt32
+		want		time.Duration	// from now; 0 if the feed is due on the next pass
+	}{
+		{"never fetched", false, 0, hours(6), 0},
+		{"no interval", true, 0, sql.NullInt32{}, 0},
+		{"interval from the last fetch", true, 0, hours(6), 6 * time.Hour},
+		{"shorter interval takes effect", true, 0, hours(1), time.Hour},
+		{"Retry-After kept", false, 3 * time.Hour, hours(1), 3 * time.Hour},
+		{"Retry-After kept after a fetch", true, 3 * time.Hour, hours(1), 3 * time.Hour},
+		{"interval beyond Retry-After", true, 3 * time.Hour, hours(6), 6 * time.Hour},
+	}
+	for _, tt := range tests {
+		s, user := newTestState(t)
+		feed := addFeed(t, s, user, "https://blog.example/rss")
+		if tt.fetched {
+			err := s.Db.MarkFeedFetched(ctx, database.MarkFeedFetchedParams{
+				ID: feed.ID,
+				NextFetchAt: sql.NullTime{Time: time.Now().UTC().Add(24 * time.Hour), Valid: true},
+				FetchInterval: hours(24),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if tt.retryAfter > 0 {
+			retry := sql.NullTime{Time: time.Now().UTC().Add(tt.retryAfter), Valid: true}
+			if err := s.Db.DeferFeedFetch(ctx, database.DeferFeedFetchParams{ID: feed.ID, NextFetchAt: retry, RetryAfter: retry}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		feed, err := s.Db.FindFeedsByURL(ctx, feed.Url)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := app.SetFetchInterval(ctx, s, feed, tt.interval, false); err != nil {
+			t.Fatalf("%s: SetFetchInterval: %v", tt.name, err)
+		}
+		got, err := s.Db.FindFeedsByURL(ctx, feed.Url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.FetchInterval != tt.interval {
+			t.Errorf("%s: interval = %+v, want %+v", tt.name, got.FetchInterval, tt.interval)
+		}
+		if tt.want == 0 {
+			if got.NextFetchAt.Valid {
+				t.Errorf("%s: next fetch at %v, want the next pass", tt.name, got.NextFetchAt.Time)
+			}
+			continue
+		}
+		if until := time.Until(got.NextFetchAt.Time); !got.NextFetchAt.Valid || until < tt.want-time.Minute || until > tt.want {
+			t.Errorf("%s: next fetch in %v, want %v", tt.name, until, tt.want)
+		}
+	}
+}
+
+func TestScrapeFeedHonorsPollingHints(t *testing.T) {
+	ctx := context.Background()
+	doc := strings.Replace(rssDocument("Blog", testItem{"Post", "https://blog.example/1", pubDate(time.Now())}),
+		"<title>Blog</title>", "<title>Blog</title><ttl>120</ttl>", 1)
+
+	tests := []struct {
+		name			string
+		cacheControl	string
+		want			time.Duration
+	}{
+		{"ttl", "", 2 * time.Hour},
+		{"max-age beyond ttl", "max-age=10800", 3 * time.Hour},
+		{"max-age within ttl", "max-age=60", 2 * time.Hour},
+	}
+	for _, tt := range tests {
+		s, user := newTestState(t)
+		srv := newFeedServer(t)
+		cacheControl := tt.cacheControl
+		url := srv.handle("/feed.xml", func(w http.ResponseWriter, r *http.Request) {
+			if cacheControl != "" {
+				w.Header().Set("Cache-Control", cacheControl)
+			}
+			io.WriteString(w, doc)
+		})
+		feed := addFeed(t, s, user, url)
+
+		if _, err := app.ScrapeFeed(ctx, s, feed); err != nil {
+			t.Fatalf("%s: ScrapeFeed: %v", tt.name, err)
+		}
+		fetched, err := s.Db.FindFeedsByURL(ctx, url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fetched.TtlMinutes.Int32 != 120 {
+			t.Errorf("%s: stored ttl = %+v, want 120 minutes", tt.name, fetched.TtlMinutes)
+		}
+		if until := time.Until(fetched.NextFetchAt.Time); until < tt.want-time.Minute || until > tt.want {
+			t.Errorf("%s: next fetch in %v, want %v", tt.name, until, tt.want)
+		}
+	}
+}
+
+func TestScrapeFeedTranscodesToUTF8(t *testing.T) {
+	ctx := context.Background()
+	s, user := newTestState(t)
+	srv := newFeedServer(t)
+	latin1, err := charmap.ISO8859_1.NewEncoder().String(strings.Replace(
+		rssDocument("Blog", testItem{"Crème brûlée", "https://blog.example/1", pubDate(time.Now())}),
+		`encoding="UTF-8"`, `encoding="ISO-8859-1"`, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := srv.handle("/feed.xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml; charset=iso-8859-1")
+		io.WriteString(w, latin1)
+	})
+	feed := addFeed(t, s, user, url)
+
+	if _, err := app.ScrapeFeed(ctx, s, feed); err != nil {
+		t.Fatalf("ScrapeFeed: %v", err)
+	}
+	if got := feedPosts(t, s, feed)["https://blog.example/1"].Title; got != "Crème brûlée" {
+		t.Errorf("title = %q, want it transcoded to UTF-8", got)
+	}
+}
+
+// follow makes user follow feed.
+func follow(t *testing.T, s *app.State, user database.User, feed database.Feed) {
+	t.Helper()
+	_, err := s.Db.CreateFeedFollow(context.Background(), database.CreateFeedFollowParams{
+		ID: uuid.New(),
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+		UserID: user.ID,
+		FeedID: feed.ID,
+	})
+	if err != nil {
+		t.Fatalf("Error following feed: %v", err)
+	}
+}
+
+func TestScrapeFeedFollowsPermanentRedirects(t *testing.T) {
+	ctx := context.Background()
+	doc := rssDocument("Blog", testItem{"Post", "https://blog.example/1", pubDate(time.Now())})
+
+	tests := []struct {
+		name	string
+		status	int
+		merge	bool
+	}{
+		{"moved", http.StatusMovedPermanently, false},
+		{"moved onto a known feed", http.StatusPermanentRedirect, true},
+		{"temporary", http.StatusFound, false},
+	}
+	for _, tt := range tests {
+		s, user := newTestState(t)
+		srv := newFeedServer(t)
+		newURL := srv.serve("/new.xml", doc)
+		status := tt.status
+		oldURL := srv.handle("/old.xml", func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, "/new.xml", status)
+		})
+		old := addFeed(t, s, user, oldURL)
+		follow(t, s, user, old)
+		var existing database.Feed
+		if tt.merge {
+			existing = addFeed(t, s, user, newURL)
+			follow(t, s, user, existing)
+		}
+
+		result, err := app.ScrapeFeed(ctx, s, old)
+		if err != nil {
+			t.Fatalf("%s: ScrapeFeed: %v", tt.name, err)
+		}
+
+		follows, err := s.Db.GetFeedFollowsForUser(ctx, user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, oldErr := s.Db.FindFeedsByURL(ctx, oldURL)
+		moved, newErr := s.Db.FindFeedsByURL(ctx, newURL)
+		switch {
+		case tt.status == http.StatusFound:
+			if oldErr != nil || newErr == nil {
+				t.Errorf("%s: feed moved on a temporary redirect", tt.name)
+			}
+			continue
+		case tt.merge:
+			if !strings.Contains(strings.Join(result.Warnings, "\n"), "was merged into the existing feed") {
+				t.Errorf("%s: warnings %q don't mention the merge", tt.name, result.Warnings)
+			}
+			if moved.ID != existing.ID {
+				t.Errorf("%s: %s belongs to a different feed after the merge", tt.name, newURL)
+			}
+		default:
+			if moved.ID != old.ID {
+				t.Errorf("%s: feed not moved to %s: %v", tt.name, newURL, newErr)
+			}
+		}
+
+		if !errors.Is(oldErr, sql.ErrNoRows) {
+			t.Errorf("%s: feed still found at its old URL: %v", tt.name, oldErr)
+		}
+		if len(follows) != 1 || follows[0].Url != newURL {
+			t.Errorf("%s: follows = %+v, want one follow of %s", tt.name, follows, newURL)
+		}
+		if posts := feedPosts(t, s, moved); len(posts) != 1 {
+			t.Errorf("%s: %d posts in the moved feed, want 1", tt.name, len(posts))
+		}
+		history, err := s.Db.GetFeedURLHistory(ctx, moved.ID)
+		if err != nil || len(history) != 1 || history[0].OldUrl != oldURL || history[0].Merged != tt.merge {
+			t.Errorf("%s: URL history = %+v, %v; want the move from %s", tt.name, history, err, oldURL)
+		}
+	}
+}
@@ -0,0 +1,371 @@
+// Package app contains shared application services and state management.
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/nhdewitt/blog-aggregator/internal/database"
+)
+
+// snapshotVersion is the format version written to new snapshots.
+const snapshotVersion = 1
+
+// Snapshot holds database rows exactly as stored, so they can be put back with
+// their original IDs. It is written before reset deletes anything.
+type Snapshot struct {
+	Version			int							`json:"version"`
+	CreatedAt		time.Time					`json:"created_at"`
+	Reason			string						`json:"reason,omitempty"`
+	Users			[]database.User				`json:"users,omitempty"`
+	Folders			[]database.Folder			`json:"folders,omitempty"`
+	Feeds			[]database.Feed				`json:"feeds,omitempty"`
+	FeedURLHistory	[]database.FeedUrlHistory	`json:"feed_url_history,omitempty"`
+	FeedFollows		[]database.FeedFollow		`json:"feed_follows,omitempty"`
+	Posts			[]database.Post				`json:"posts,omitempty"`
+	FilterRules		[]database.FilterRule		`json:"filter_rules,omitempty"`
+	PostReads		[]database.PostRead			`json:"post_reads,omitempty"`
+	SavedPosts		[]database.SavedPost		`json:"saved_posts,omitempty"`
+}
+
+// ResetScope selects what reset deletes. The zero value deletes everything.
+type ResetScope struct {
+	User		*database.User	// delete only this user and their personal data
+	PostsOnly	bool			// delete posts, keeping users, feeds and follows
+	Before		time.Time		// with PostsOnly, only posts published before this time
+}
+
+// ErrResetCancelled is returned from a reset's confirmation step to abort it.
+var ErrResetCancelled = errors.New("reset cancelled")
+
+// Reset deletes the data selected by scope in a single transaction.
+//
+// The rows that are about to be deleted are collected into a Snapshot and passed
+// to confirm, which can show a summary, ask for confirmation and save the snapshot.
+// If confirm returns an error (such as ErrResetCancelled) nothing is deleted.
+//
+// When resetting a single user, the feeds they added are handed to admin so
+// other users keep them.
+//
+// Returns the snapshot of deleted rows, or an error if anything fails.
+func Reset(ctx context.Context, s *State, scope ResetScope, admin database.User, confirm func(*Snapshot) error) (*Snapshot, error) {
+	var snap *Snapshot
+	err := s.InTx(ctx, func(q Store) error {
+		var err error
+		snap, err = collectReset(ctx, q, scope)
+		if err != nil {
+			return err
+		}
+		if err := confirm(snap); err != nil {
+			return err
+		}
+
+		switch {
+		case scope.PostsOnly && scope.Before.IsZero():
+			_, err = q.DeletePosts(ctx)
+		case scope.PostsOnly:
+			_, err = q.DeletePostsPublishedBefore(ctx, scope.Before)
+		case scope.User != nil:
+			err = q.ReassignFeeds(ctx, database.ReassignFeedsParams{FromUserID: scope.User.ID, ToUserID: admin.ID})
+			if err == nil {
+				err = q.DeleteUser(ctx, scope.User.ID)
+			}
+		default:
+			err = q.DeleteAllUsers(ctx)
+		}
+		if err != nil {
+			return fmt.Errorf("Error deleting data: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return snap, nil
+}
+
+// collectReset reads every row the reset described by scope will delete.
+func collectReset(ctx context.Context, q Store, scope ResetScope) (*Snapshot, error) {
+	snap := &Snapshot{Version: snapshotVersion, CreatedAt: time.Now().UTC()}
+	var err error
+
+	switch {
+	case scope.PostsOnly && scope.Before.IsZero():
+		if snap.Posts, err = q.ListPosts(ctx); err != nil {
+			return nil, fmt.Errorf("Error reading posts: %w", err)
+		}
+		if snap.PostReads, err = q.ListPostReads(ctx); err != nil {
+			return nil, fmt.Errorf("Error reading read markers: %w", err)
+		}
+		if snap.SavedPosts, err = q.ListSavedPosts(ctx); err != nil {
+			return nil, fmt.Errorf("Error reading saved posts: %w", err)
+		}
+
+	case scope.PostsOnly:
+		if snap.Posts, err = q.ListPostsPublishedBefore(ctx, scope.Before); err != nil {
+			return nil, fmt.Errorf("Error reading posts: %w", err)
+		}
+		if snap.PostReads, err = q.ListPostReadsPublishedBefore(ctx, scope.Before); err != nil {
+			return nil, fmt.Errorf("Error reading read markers: %w", err)
+		}
+		if snap.SavedPosts, err = q.ListSavedPostsPublishedBefore(ctx, scope.Before); err != nil {
+			return nil, fmt.Errorf("Error reading saved posts: %w", err)
+		}
+
+	case scope.User != nil:
+		id := scope.User.ID
+		snap.Users = []database.User{*scope.User}
+		if snap.Folders, err = q.GetFoldersForUser(ctx, id); err != nil {
+			return nil, fmt.Errorf("Error reading folders: %w", err)
+		}
+		if snap.FeedFollows, err = q.ListFeedFollowsForUser(ctx, id); err != nil {
+			return nil, fmt.Errorf("Error reading follows: %w", err)
+		}
+		if snap.FilterRules, err = q.ListFilterRulesForUser(ctx, id); err != nil {
+			return nil, fmt.Errorf("Error reading filter rules: %w", err)
+		}
+		if snap.PostReads, err = q.ListPostReadsForUser(ctx, id); err != nil {
+			return nil, fmt.Errorf("Error reading read markers: %w", err)
+		}
+		if snap.SavedPosts, err = q.ListSavedPostsForUser(ctx, id); err != nil {
+			return nil, fmt.Errorf("Error reading saved posts: %w", err)
+		}
+
+	default:
+		if err := collectAll(ctx, q, snap); err != nil {
+			return nil, err
+		}
+	}
+	return snap, nil
+}
+
+// collectAll reads every table covered by snapshots into snap.
+func collectAll(ctx context.Context, q Store, snap *Snapshot) error {
+	var err error
+	if snap.Users, err = q.GetUsers(ctx); err != nil {
+		return fmt.Errorf("Error reading users: %w", err)
+	}
+	if snap.Folders, err = q.ListFolders(ctx); err != nil {
+		return fmt.Errorf("Error reading folders: %w", err)
+	}
+	if snap.Feeds, err = q.ListFeeds(ctx); err != nil {
+		return fmt.Errorf("Error reading feeds: %w", err)
+	}
+	if snap.FeedURLHistory, err = q.ListFeedURLHistory(ctx); err != nil {
+		return fmt.Errorf("Error reading feed URL history: %w", err)
+	}
+	if snap.FeedFollows, err = q.ListFeedFollows(ctx); err != nil {
+		return fmt.Errorf("Error reading follows: %w", err)
+	}
+	if snap.Posts, err = q.ListPosts(ctx); err != nil {
+		return fmt.Errorf("Error reading posts: %w", err)
+	}
+	if snap.FilterRules, err = q.ListFilterRules(ctx); err != nil {
+		return fmt.Errorf("Error reading filter rules: %w", err)
+	}
+	if snap.PostReads, err = q.ListPostReads(ctx); err != nil {
+		return fmt.Errorf("Error reading read markers: %w", err)
+	}
+	if snap.SavedPosts, err = q.ListSavedPosts(ctx); err != nil {
+		return fmt.Errorf("Error reading saved posts: %w", err)
+	}
+	return nil
+}
+
+// RestoreCount reports how many rows of one table a restore inserted, and how
+// many it skipped because they already existed or what they belong to is missing.
+type RestoreCount struct {
+	Table		string
+	Restored	int
+	Skipped		int
+}
+
+// RestoreSnapshot inserts the rows of snap with their original IDs in a single
+// transaction. Rows that already exist are left alone, so restoring the same
+// snapshot twice is harmless.
+//
+// Returns per-table counts, or an error if the database rejects a row.
+func RestoreSnapshot(ctx context.Context, s *State, snap *Snapshot) ([]RestoreCount, error) {
+	var r *restorer
+	err := s.InTx(ctx, func(q Store) error {
+		r = newRestorer(q)
+		// Parents go first so the rows referring to them find them
+		rows := [][]any{
+			toAny(snap.Users), toAny(snap.Folders), toAny(snap.Feeds), toAny(snap.FeedURLHistory),
+			toAny(snap.FeedFollows), toAny(snap.Posts), toAny(snap.FilterRules), toAny(snap.PostReads), toAny(snap.SavedPosts),
+		}
+		for _, table := range rows {
+			for _, row := range table {
+				if err := r.restore(ctx, row); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.counts, nil
+}
+
+// restorer inserts rows of any snapshotted table with their original IDs and
+// counts the results per table.
+type restorer struct {
+	q		Store
+	counts	[]RestoreCount
+}
+
+func newRestorer(q Store) *restorer {
+	return &restorer{q: q}
+}
+
+// restore inserts a single row, which must be one of the database model types
+// held in a Snapshot. Existing rows and rows whose parents are missing are skipped.
+func (r *restorer) restore(ctx context.Context, row any) error {
+	var table string
+	var n int64
+	var err error
+
+	q := r.q
+	switch v := row.(type) {
+	case database.User:
+		table = "users"
+		n, err = q.RestoreUser(ctx, database.RestoreUserParams{
+			ID: v.ID, CreatedAt: v.CreatedAt, UpdatedAt: v.UpdatedAt,
+			Name: v.Name, PasswordHash: v.PasswordHash, Role: v.Role,
+		})
+	case database.Folder:
+		table = "folders"
+		n, err = q.RestoreFolder(ctx, database.RestoreFolderParams{
+			ID: v.ID, CreatedAt: v.CreatedAt, UpdatedAt: v.UpdatedAt,
+			Name: v.Name, UserID: v.UserID,
+		})
+	case database.Feed:
+		table = "feeds"
+		n, err = q.RestoreFeed(ctx, database.RestoreFeedParams{
+			ID: v.ID, CreatedAt: v.CreatedAt, UpdatedAt: v.UpdatedAt,
+			LastFetchedAt: v.LastFetchedAt, Name: v.Name, Url: v.Url, UserID: v.UserID,
+			FetchFullText: v.FetchFullText, FetchInterval: v.FetchInterval,
+			AdaptiveInterval: v.AdaptiveInterval, NextFetchAt: v.NextFetchAt,
+			TtlMinutes: v.TtlMinutes, SkipHours: v.SkipHours, SkipDays: v.SkipDays,
+			CacheMaxAge: v.CacheMaxAge, RetryAfter: v.RetryAfter, ParseWarnings: v.ParseWarnings,
+			RetentionMaxAge: v.RetentionMaxAge, RetentionMaxPosts: v.RetentionMaxPosts,
+		})
+	case database.FeedUrlHistory:
+		table = "feed_url_history"
+		n, err = q.RestoreFeedURLChange(ctx, database.RestoreFeedURLChangeParams{
+			ID: v.ID, ChangedAt: v.ChangedAt, FeedID: v.FeedID,
+			OldUrl: v.OldUrl, NewUrl: v.NewUrl, Merged: v.Merged,
+		})
+	case database.FeedFollow:
+		table = "feed_follows"
+		n, err = q.RestoreFeedFollow(ctx, database.RestoreFeedFollowParams{
+			ID: v.ID, CreatedAt: v.CreatedAt, UpdatedAt: v.UpdatedAt,
+			UserID: v.UserID, FeedID: v.FeedID, FolderID: v.FolderID, DisplayName: v.DisplayName,
+		})
+	case database.Post:
+		table = "posts"
+		n, err = q.RestorePost(ctx, database.RestorePostParams{
+			ID: v.ID, CreatedAt: v.CreatedAt, UpdatedAt: v.UpdatedAt,
+			Title: v.Title, Url: v.Url, Description: v.Description, PublishedAt: v.PublishedAt,
+			FeedID: v.FeedID, Content: v.Content, Author: v.Author,
+		})
+	case database.FilterRule:
+		table = "filter_rules"
+		n, err = q.RestoreFilterRule(ctx, database.RestoreFilterRuleParams{
+			ID: v.ID, CreatedAt: v.CreatedAt, UpdatedAt: v.UpdatedAt,
+			UserID: v.UserID, FeedID: v.FeedID, Pattern: v.Pattern,
+			IsRegex: v.IsRegex, Field: v.Field, Action: v.Action,
+		})
+	case database.PostRead:
+		table = "post_reads"
+		n, err = q.RestorePostRead(ctx, database.RestorePostReadParams{
+			UserID: v.UserID, PostID: v.PostID, ReadAt: v.ReadAt,
+		})
+	case database.SavedPost:
+		table = "saved_posts"
+		n, err = q.RestoreSavedPost(ctx, database.RestoreSavedPostParams{
+			UserID: v.UserID, PostID: v.PostID, SavedAt: v.SavedAt,
+		})
+	default:
+		return fmt.Errorf("Cannot restore rows of type %T", row)
+	}
+	if err != nil {
+		return fmt.Errorf("Error restoring %s: %w", table, err)
+	}
+
+	r.count(table, n > 0)
+	return nil
+}
+
+// count records one restored or skipped row of table.
+func (r *restorer) count(table string, restored bool) {
+	i := len(r.counts) - 1
+	if i < 0 || r.counts[i].Table != table {
+		r.counts = append(r.counts, RestoreCount{Table: table})
+		i++
+	}
+	if restored {
+		r.counts[i].Restored++
+	} else {
+		r.counts[i].Skipped++
+	}
+}
+
+// toAny converts a slice of rows to []any for restorer.restore.
+func toAny[T any](rows []T) []any {
+	out := make([]any, len(rows))
+	for i, row := range rows {
+		out[i] = row
+	}
+	return out
+}
+
+// WriteSnapshot saves snap as JSON at path, creating its directory if needed.
+// The file is only readable by the current user since it holds password hashes.
+func WriteSnapshot(path string, snap *Snapshot) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("Error creating snapshot directory: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return fmt.Errorf("Error creating snapshot: %w", err)
+	}
+
+	encoder := json.NewEncoder(f)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(snap); err != nil {
+		f.Close()
+		return fmt.Errorf("Error writing snapshot: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("Error writing snapshot: %w", err)
+	}
+	return nil
+}
+
+// ReadSnapshot loads a snapshot written by WriteSnapshot.
+// Returns an error if the file can't be decoded or comes from a newer gator.
+func ReadSnapshot(path string) (*Snapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("Error opening snapshot: %w", err)
+	}
+	defer f.Close()
+
+	var snap Snapshot
+	if err := json.NewDecoder(f).Decode(&snap); err != nil {
+		return nil, fmt.Errorf("Error decoding snapshot: %w", err)
+	}
+	if snap.Version < 1 || snap.Version > snapshotVersion {
+		return nil, fmt.Errorf("Unsupported snapshot version %d", snap.Version)
+	}
+	return &snap, nil
+}
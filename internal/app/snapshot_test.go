@@ -0,0 +1,216 @@
+package app_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nhdewitt/blog-aggregator/internal/app"
+	"github.com/nhdewitt/blog-aggregator/internal/database"
+)
+
+// tableCounts is how many rows of each kind a snapshot or database holds.
+type tableCounts struct {
+	Users, Feeds, Follows, Posts, Reads, Saved int
+}
+
+func snapshotCounts(snap *app.Snapshot) tableCounts {
+	return tableCounts{len(snap.Users), len(snap.Feeds), len(snap.FeedFollows), len(snap.Posts), len(snap.PostReads), len(snap.SavedPosts)}
+}
+
+func databaseCounts(t *testing.T, s *app.State) tableCounts {
+	t.Helper()
+	ctx := context.Background()
+	users, err1 := s.Db.GetUsers(ctx)
+	feeds, err2 := s.Db.ListFeeds(ctx)
+	follows, err3 := s.Db.ListFeedFollows(ctx)
+	posts, err4 := s.Db.ListPosts(ctx)
+	reads, err5 := s.Db.ListPostReads(ctx)
+	saved, err6 := s.Db.ListSavedPosts(ctx)
+	if err := errors.Join(err1, err2, err3, err4, err5, err6); err != nil {
+		t.Fatalf("Error counting rows: %v", err)
+	}
+	return tableCounts{len(users), len(feeds), len(follows), len(posts), len(reads), len(saved)}
+}
+
+// resetFixture fills a State with two users who each added a feed, follow
+// both, and have read and saved posts of different ages.
+func resetFixture(t *testing.T) (*app.State, database.User, database.User) {
+	t.Helper()
+	ctx := context.Background()
+	s, alice := newTestState(t)
+	bob, err := s.Db.CreateUser(ctx, database.CreateUserParams{
+		ID: uuid.New(),
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+		Name: "bob",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	feedA := addFeed(t, s, alice, "https://a.example/rss")
+	feedB := addFeed(t, s, bob, "https://b.example/rss")
+	follow(t, s, alice, feedA)
+	follow(t, s, bob, feedA)
+	follow(t, s, bob, feedB)
+
+	post := func(feed database.Feed, url string, age time.Duration) uuid.UUID {
+		row, err := s.Db.UpsertPost(ctx, database.UpsertPostParams{
+			ID: uuid.New(),
+			Title: url,
+			Url: url,
+			PublishedAt: sql.NullTime{Time: time.Now().UTC().Add(-age), Valid: true},
+			FeedID: feed.ID,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return row.ID
+	}
+	old := post(feedA, "https://a.example/old", 60*24*time.Hour)
+	fresh := post(feedA, "https://a.example/fresh", time.Hour)
+	post(feedB, "https://b.example/fresh", time.Hour)
+
+	if err := s.Db.SavePost(ctx, database.SavePostParams{UserID: bob.ID, PostID: old}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Db.MarkPostRead(ctx, database.MarkPostReadParams{UserID: bob.ID, PostID: fresh}); err != nil {
+		t.Fatal(err)
+	}
+	return s, alice, bob
+}
+
+func TestReset(t *testing.T) {
+	ctx := context.Background()
+	all := tableCounts{Users: 2, Feeds: 2, Follows: 3, Posts: 3, Reads: 1, Saved: 1}
+
+	tests := []struct {
+		name		string
+		scope		func(bob database.User) app.ResetScope
+		snapshot	tableCounts
+		left		tableCounts
+	}{
+		{
+			name: "everything",
+			scope: func(database.User) app.ResetScope { return app.ResetScope{} },
+			snapshot: all,
+		},
+		{
+			name: "posts only",
+			scope: func(database.User) app.ResetScope { return app.ResetScope{PostsOnly: true} },
+			snapshot: tableCounts{Posts: 3, Reads: 1, Saved: 1},
+			left: tableCounts{Users: 2, Feeds: 2, Follows: 3},
+		},
+		{
+			name: "posts older than 30 days",
+			scope: func(database.User) app.ResetScope {
+				return app.ResetScope{PostsOnly: true, Before: time.Now().UTC().Add(-30 * 24 * time.Hour)}
+			},
+			snapshot: tableCounts{Posts: 1, Saved: 1},
+			left: tableCounts{Users: 2, Feeds: 2, Follows: 3, Posts: 2, Reads: 1},
+		},
+		{
+			name: "one user",
+			scope: func(bob database.User) app.ResetScope { return app.ResetScope{User: &bob} },
+			snapshot: tableCounts{Users: 1, Follows: 2, Reads: 1, Saved: 1},
+			left: tableCounts{Users: 1, Feeds: 2, Follows: 1, Posts: 3},
+		},
+	}
+	for _, tt := range tests {
+		s, alice, bob := resetFixture(t)
+		snap, err := app.Reset(ctx, s, tt.scope(bob), alice, func(*app.Snapshot) error { return nil })
+		if err != nil {
+			t.Fatalf("%s: Reset: %v", tt.name, err)
+		}
+		if got := snapshotCounts(snap); got != tt.snapshot {
+			t.Errorf("%s: snapshot holds %+v, want %+v", tt.name, got, tt.snapshot)
+		}
+		if got := databaseCounts(t, s); got != tt.left {
+			t.Errorf("%s: database holds %+v after reset, want %+v", tt.name, got, tt.left)
+		}
+
+		// Restoring the snapshot puts back exactly what was deleted
+		if _, err := app.RestoreSnapshot(ctx, s, snap); err != nil {
+			t.Fatalf("%s: RestoreSnapshot: %v", tt.name, err)
+		}
+		if got := databaseCounts(t, s); got != all {
+			t.Errorf("%s: database holds %+v after restore, want %+v", tt.name, got, all)
+		}
+	}
+}
+
+func TestResetHandsFeedsToAdmin(t *testing.T) {
+	ctx := context.Background()
+	s, alice, bob := resetFixture(t)
+	if _, err := app.Reset(ctx, s, app.ResetScope{User: &bob}, alice, func(*app.Snapshot) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	feed, err := s.Db.FindFeedsByURL(ctx, "https://b.example/rss")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feed.UserID != alice.ID {
+		t.Errorf("feed added by the deleted user belongs to %s, want the admin", feed.UserID)
+	}
+}
+
+func TestResetCancelled(t *testing.T) {
+	s, alice, _ := resetFixture(t)
+	before := databaseCounts(t, s)
+
+	var seen tableCounts
+	_, err := app.Reset(context.Background(), s, app.ResetScope{}, alice, func(snap *app.Snapshot) error {
+		seen = snapshotCounts(snap)
+		return app.ErrResetCancelled
+	})
+	if !errors.Is(err, app.ErrResetCancelled) {
+		t.Fatalf("Reset = %v, want ErrResetCancelled", err)
+	}
+	if seen != before {
+		t.Errorf("confirmation was shown %+v, want %+v", seen, before)
+	}
+	if got := databaseCounts(t, s); got != before {
+		t.Errorf("database holds %+v after a cancelled reset, want %+v", got, before)
+	}
+}
+
+func TestWriteSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshots", "reset.json")
+	snap := &app.Snapshot{Version: 1, Reason: "reset --posts-only", Posts: []database.Post{{ID: uuid.New(), Url: "https://a.example/1"}}}
+
+	if err := app.WriteSnapshot(path, snap); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("snapshot permissions = %o, want 600", perm)
+	}
+	if err := app.WriteSnapshot(path, snap); err == nil {
+		t.Error("WriteSnapshot overwrote an existing snapshot")
+	}
+
+	got, err := app.ReadSnapshot(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Reason != snap.Reason || len(got.Posts) != 1 || got.Posts[0].ID != snap.Posts[0].ID {
+		t.Errorf("ReadSnapshot = %+v, want %+v", got, snap)
+	}
+
+	newer := filepath.Join(t.TempDir(), "newer.json")
+	if err := os.WriteFile(newer, []byte(`{"version": 99}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := app.ReadSnapshot(newer); err == nil {
+		t.Error("ReadSnapshot accepted a snapshot from a newer version")
+	}
+}
@@ -2,12 +2,34 @@
 package app
 
 import (
+	"context"
+
 	"github.com/nhdewitt/blog-aggregator/internal/config"
 	"github.com/nhdewitt/blog-aggregator/internal/database"
 )
 
+// Store is the storage gator runs against. Postgres and SQLite implementations
+// live in the store package; both run the queries generated by sqlc.
+type Store interface {
+	database.Querier
+
+	// InTx runs fn with a Store bound to a single transaction, committing if fn
+	// succeeds and rolling back if it returns an error. Calling InTx on a Store
+	// that is already in a transaction runs fn in that transaction.
+	InTx(ctx context.Context, fn func(q Store) error) error
+
+	// Close releases the database. It does nothing on a Store bound to a transaction.
+	Close() error
+}
 
 type State struct {
-	Cfg	*config.Config
-	Db	*database.Queries
-}
\ No newline at end of file
+	Cfg		*config.Config
+	Db		Store
+	Fetcher	*Fetcher
+}
+
+// InTx runs fn with queries bound to a single transaction, committing if fn
+// succeeds and rolling back if it returns an error.
+func (s *State) InTx(ctx context.Context, fn func(q Store) error) error {
+	return s.Db.InTx(ctx, fn)
+}
@@ -3,26 +3,35 @@ package commands
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
 	"strconv"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/nhdewitt/blog-aggregator/internal/app"
 	"github.com/nhdewitt/blog-aggregator/internal/database"
 )
 
 // handlerBrowse displays the latest posts from feeds the current user follows.
 // Posts are shown in reverse chronological order with title, publication date,
-// description (if available), and URL.
+// feed name, author, description and full article text (if available), and URL.
+// The user's filter rules are applied: hidden posts are skipped, highlighted posts
+// are flagged, and auto-mark-read posts are recorded as read. With --saved,
+// only the posts the user saved are shown.
 //
-// Usage: gator browse <limit>
+// Usage: gator browse <limit> [--folder <name>] [--saved]
 // Default for limit is 2
-func handlerBrowse(s *app.State, cmd Command, user database.User) error {
-	c := context.Background()
+func handlerBrowse(ctx context.Context, s *app.State, cmd Command, user database.User) error {
+	args, flags, err := parseFlags(cmd.Args, flagSpec{"folder": true, "saved": false})
+	if err != nil {
+		return fmt.Errorf("usage: %s [limit] [--folder <name>] [--saved]", cmd.Name)
+	}
 
 	var limit int32 = 2
-	if len(cmd.Args) > 0 {
-		l, err := strconv.ParseInt(cmd.Args[0], 10, 32)
+	if len(args) > 0 {
+		l, err := strconv.ParseInt(args[0], 10, 32)
 		if err != nil {
 			return fmt.Errorf("Please enter a number for the limit: %w", err)
 		}
@@ -30,60 +39,278 @@ func handlerBrowse(s *app.State, cmd Command, user database.User) error {
 	}
 	id := user.ID
 
-	posts, err := s.Db.GetPostsForUser(c, database.GetPostsForUserParams{
-		UserID : id,
-		Limit: limit,
-	})
+	folderID, err := lookupFolder(ctx, s, user, flags["folder"])
 	if err != nil {
-		return fmt.Errorf("Error getting posts for user: %w", err)
+		return err
 	}
 
-	for _, post := range posts {
-		fmt.Printf("Title: %s (published on %s at %s)\n\n", post.Title, post.PublishedAt.Format("Jan 2, 2006"), post.PublishedAt.Format("3:04 PM"))
-		if post.Description.Valid {
-			fmt.Printf("Description: %s\n", post.Description.String)
+	rules, err := s.Db.GetFilterRulesForUser(ctx, id)
+	if err != nil {
+		return fmt.Errorf("Error getting filter rules: %w", err)
+	}
+	filter, err := app.NewPostFilter(rules)
+	if err != nil {
+		return err
+	}
+
+	// Hidden posts don't count toward the limit, so keep paging until it is filled
+	var shown int32
+	for offset := int32(0); shown < limit; offset += limit {
+		posts, err := s.Db.GetPostsForUser(ctx, database.GetPostsForUserParams{
+			UserID : id,
+			Limit: limit,
+			FolderID: folderID,
+			SavedOnly: flags["saved"] == "true",
+			Offset: offset,
+		})
+		if err != nil {
+			return fmt.Errorf("Error getting posts for user: %w", err)
+		}
+
+		for _, post := range posts {
+			result := filter.Apply(app.FilteredPost{
+				FeedID: post.FeedID,
+				Title: post.Title,
+				Description: post.Description.String,
+				Content: post.Content.String,
+				Author: post.Author.String,
+			})
+			if result.Hidden {
+				continue
+			}
+			if shown == limit {
+				break
+			}
+			shown++
+
+			read := post.ReadAt.Valid
+			if result.MarkRead && !read {
+				err = s.Db.MarkPostRead(ctx, database.MarkPostReadParams{
+					UserID: id,
+					PostID: post.ID,
+				})
+				if err != nil {
+					return fmt.Errorf("Error marking post read: %w", err)
+				}
+				read = true
+			}
+			printPost(post, result.Highlighted, read)
+		}
+
+		if int32(len(posts)) < limit {
+			break
 		}
-		fmt.Printf("URL: %s\n", post.Url)
-		fmt.Println()
 	}
 
 	return nil
 }
 
-// handlerAggregator starts a continuous process that fetches posts from all feeds.
-// It runs indefinitely, fetching new posts at the specified interval.
+// printPost displays a single post for browse.
+// Highlighted posts are flagged with [!], read posts with [read] and saved posts with [saved].
+func printPost(post database.GetPostsForUserRow, highlighted, read bool) {
+	title := post.Title
+	if post.SavedAt.Valid {
+		title = "[saved] " + title
+	}
+	if read {
+		title = "[read] " + title
+	}
+	if highlighted {
+		title = "[!] " + title
+	}
+
+	fmt.Printf("Title: %s (published on %s at %s)\n\n", title, post.PublishedAt.Format("Jan 2, 2006"), post.PublishedAt.Format("3:04 PM"))
+	fmt.Printf("Feed: %s\n", post.FeedName)
+	if post.Author.Valid {
+		fmt.Printf("Author: %s\n", post.Author.String)
+	}
+	if post.Description.Valid {
+		fmt.Printf("Description: %s\n", post.Description.String)
+	}
+	if post.Content.Valid {
+		fmt.Printf("Content:\n%s\n\n", post.Content.String)
+	}
+	fmt.Printf("URL: %s\n", post.Url)
+	fmt.Println()
+}
+
+// shutdownGrace is how long an in-flight scrape may keep running after
+// the aggregator is asked to stop.
+const shutdownGrace = 10 * time.Second
+
+// handlerAggregator fetches posts from all feeds that are due.
+// By default it runs until interrupted, fetching immediately on startup and then
+// at the specified interval. With --once it refreshes every due feed a single time,
+// prints a summary and exits, returning an error if any feed failed.
+//
+// Posts outside the retention policy are pruned on startup and then every
+// retention prune_interval (hourly by default), and after a --once run.
 //
-// Usage: gator agg <duration>
+// On SIGINT or SIGTERM the ticker is stopped and any feed being processed is given
+// up to shutdownGrace to finish before its database work is cancelled.
+//
+// Usage: gator agg <duration> | gator agg --once
 // Example: gator agg "1m" (every minute), gator agg "1h" (every hour)
-func handlerAggregator(s *app.State, cmd Command) error {
-	if len(cmd.Args) != 1 {
-		return fmt.Errorf("usage: %s <time_between_reqs>", cmd.Name)
+func handlerAggregator(ctx context.Context, s *app.State, cmd Command) error {
+	args, flags, err := parseFlags(cmd.Args, flagSpec{"once": false})
+	if err != nil {
+		return fmt.Errorf("usage: %s <time_between_reqs> | %s --once", cmd.Name, cmd.Name)
+	}
+
+	if flags["once"] == "true" {
+		if len(args) != 0 {
+			return fmt.Errorf("usage: %s --once", cmd.Name)
+		}
+		return aggregateOnce(ctx, s)
 	}
-	timeBetweenReqs := cmd.Args[0]
+
+	if len(args) != 1 {
+		return fmt.Errorf("usage: %s <time_between_reqs> | %s --once", cmd.Name, cmd.Name)
+	}
+	timeBetweenReqs := args[0]
 
 	var duration time.Duration
-	duration, err := time.ParseDuration(timeBetweenReqs)
+	duration, err = time.ParseDuration(timeBetweenReqs)
 	if err != nil {
 		return fmt.Errorf("Please enter duration in the form (1s|1m|1h)")
 	}
 
+	// Check the retention settings up front rather than on the first prune
+	if _, err := app.RetentionFromConfig(s.Cfg.Retention); err != nil {
+		return err
+	}
+	everyPrune, err := pruneInterval(s)
+	if err != nil {
+		return err
+	}
+
 	fmt.Printf("Collecting feeds every %s\n", timeBetweenReqs)
 	ticker := time.NewTicker(duration)
+	defer ticker.Stop()
+	var nextPrune time.Time
 	for {
-		<-ticker.C
-		app.ScrapeFeeds(s)
-	}
-}
+		scrapeCtx, cancel := graceContext(ctx, shutdownGrace)
+		_, err := app.ScrapeFeeds(scrapeCtx, s, time.Now())
+		cancel()
+		if err != nil && !errors.Is(err, app.ErrNoFeedsDue) {
+			log.Println(err)
+		}
 
+		if ctx.Err() == nil && !time.Now().Before(nextPrune) {
+			report, err := prunePosts(ctx, s)
+			if err != nil {
+				log.Println(err)
+			} else if report.Total > 0 {
+				log.Printf("Pruned %d posts from %d feeds", report.Total, len(report.Feeds))
+			}
+			nextPrune = time.Now().Add(everyPrune)
+		}
 
+		select {
+		case <-ctx.Done():
+			fmt.Println("Aggregator stopped")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
 
+// aggregateOnce fetches every feed that is currently due, one after another,
+// reports how many were refreshed and which ones failed, then prunes old posts.
+func aggregateOnce(ctx context.Context, s *app.State) error {
+	var refreshed int
+	var failures []error
 
+	// Feeds without an interval are due again as soon as they are fetched,
+	// so only fetch feeds not yet refreshed in this pass
+	passStart := time.Now()
+	tried := make(map[uuid.UUID]bool)
+	for ctx.Err() == nil {
+		scrapeCtx, cancel := graceContext(ctx, shutdownGrace)
+		feed, err := app.ScrapeFeeds(scrapeCtx, s, passStart)
+		cancel()
 
+		if errors.Is(err, app.ErrNoFeedsDue) {
+			break
+		}
+		// A feed whose fetch could not be recorded stays due and would be
+		// picked again forever, so give up on the pass when one comes back
+		if tried[feed.ID] {
+			log.Printf("Feed %s is still due after being refreshed, stopping", feed.Url)
+			break
+		}
+		tried[feed.ID] = true
 
+		var feedErr *app.FeedError
+		if errors.As(err, &feedErr) {
+			log.Println(err)
+			failures = append(failures, err)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		refreshed++
+	}
 
+	fmt.Printf("Refreshed %d feeds, %d failed\n", refreshed, len(failures))
+	if ctx.Err() != nil {
+		return fmt.Errorf("Aggregation interrupted: %w", ctx.Err())
+	}
 
+	report, err := prunePosts(ctx, s)
+	if err != nil {
+		return err
+	}
+	if report.Total > 0 {
+		fmt.Printf("Pruned %d posts from %d feeds\n", report.Total, len(report.Feeds))
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%d feeds failed to refresh", len(failures))
+	}
+	return nil
+}
 
+// handlerRefresh runs the full ingest pipeline for a single feed right away,
+// regardless of when it is next due, and reports what changed.
+//
+// Usage: gator refresh <url>
+func handlerRefresh(ctx context.Context, s *app.State, cmd Command) error {
+	if len(cmd.Args) != 1 {
+		return fmt.Errorf("usage: %s <url>", cmd.Name)
+	}
 
+	feed, err := s.Db.FindFeedsByURL(ctx, cmd.Args[0])
+	if err != nil {
+		return fmt.Errorf("Error finding feed: %w", err)
+	}
 
+	result, err := app.ScrapeFeed(ctx, s, feed)
+	for _, warning := range result.Warnings {
+		fmt.Printf("Warning: %s\n", warning)
+	}
+	if err != nil {
+		return err
+	}
 
+	fmt.Printf("Refreshed %s: %d new, %d updated, %d skipped", feed.Name, result.New, result.Updated, result.Skipped)
+	if result.Failed > 0 {
+		fmt.Printf(", %d failed", result.Failed)
+	}
+	fmt.Println()
+	return nil
+}
 
+// graceContext returns a context that outlives ctx by up to grace.
+// It is not cancelled when ctx is, so in-flight work can complete, but
+// is cancelled once grace has passed after ctx is done.
+func graceContext(ctx context.Context, grace time.Duration) (context.Context, context.CancelFunc) {
+	graceCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(ctx, func() {
+		time.AfterFunc(grace, cancel)
+	})
+	return graceCtx, func() {
+		stop()
+		cancel()
+	}
+}
\ No newline at end of file
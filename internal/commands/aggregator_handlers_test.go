@@ -0,0 +1,201 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nhdewitt/blog-aggregator/internal/app"
+	"github.com/nhdewitt/blog-aggregator/internal/database"
+)
+
+func TestAggOnceAndBrowse(t *testing.T) {
+	s := newTestState(t)
+	srv := newFeedServer(t)
+	now := time.Now()
+	url := srv.serve("/feed.xml", rssDocument("Blog",
+		testItem{"Newest post", "https://blog.example/3", now.Add(-time.Hour)},
+		testItem{"Middle post", "https://blog.example/2", now.Add(-2 * time.Hour)},
+		testItem{"Oldest post", "https://blog.example/1", now.Add(-3 * time.Hour)},
+	))
+
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Blog", url)
+
+	contains(t, mustRun(t, s, "agg", "--once"), "Refreshed 1 feeds, 0 failed")
+
+	out := mustRun(t, s, "browse")
+	contains(t, out, "Title: Newest post", "Title: Middle post", "Feed: Blog", "URL: https://blog.example/3")
+	if got := countTitles(out); got != 2 {
+		t.Errorf("browse showed %d posts, want the default of 2", got)
+	}
+	if got := countTitles(mustRun(t, s, "browse", "10")); got != 3 {
+		t.Errorf("browse 10 showed %d posts, want 3", got)
+	}
+	mustFail(t, s, "Please enter a number", "browse", "many")
+}
+
+func TestAggOnceReportsFailures(t *testing.T) {
+	s := newTestState(t)
+	srv := newFeedServer(t)
+
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Missing", srv.URL+"/missing.xml")
+
+	out, err := run(t, s, "agg", "--once")
+	if err == nil {
+		t.Fatalf("agg --once succeeded with a failing feed\n%s", out)
+	}
+	contains(t, out, "Refreshed 0 feeds, 1 failed")
+}
+
+func TestRefresh(t *testing.T) {
+	s := newTestState(t)
+	srv := newFeedServer(t)
+	now := time.Now()
+	url := srv.serve("/feed.xml", rssDocument("Blog",
+		testItem{"First", "https://blog.example/1", now.Add(-time.Hour)},
+	))
+
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Blog", url)
+
+	contains(t, mustRun(t, s, "refresh", url), "Refreshed Blog: 1 new, 0 updated, 0 skipped")
+	contains(t, mustRun(t, s, "refresh", url), "Refreshed Blog: 0 new, 0 updated, 1 skipped")
+	mustFail(t, s, "Error finding feed", "refresh", srv.URL+"/other.xml")
+}
+
+// countTitles counts the posts in browse output.
+func countTitles(out string) int {
+	return strings.Count(out, "Title: ")
+}
+
+func TestAggStopsWhenCancelled(t *testing.T) {
+	s := newTestState(t)
+	srv := newFeedServer(t)
+	url := srv.serve("/feed.xml", rssDocument("Blog",
+		testItem{"Post", "https://blog.example/1", time.Now().Add(-time.Hour)},
+	))
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Blog", url)
+
+	// The pass already under way finishes within the grace period, then agg returns
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	out, err := runContext(ctx, t, s, "agg", "1h")
+	if err != nil {
+		t.Fatalf("agg after cancelling: %v", err)
+	}
+	contains(t, out, "Collecting feeds every 1h", "Aggregator stopped")
+	contains(t, mustRun(t, s, "browse"), "Title: Post")
+
+	out, err = runContext(ctx, t, s, "agg", "--once")
+	if err == nil || !strings.Contains(err.Error(), "Aggregation interrupted") {
+		t.Errorf("agg --once after cancelling = %v, want it interrupted\n%s", err, out)
+	}
+}
+
+func TestGraceContext(t *testing.T) {
+	const grace = 50 * time.Millisecond
+
+	tests := []struct {
+		name			string
+		cancelParent	bool
+		cancelGrace		bool
+		wait			time.Duration
+		wantDone		bool
+	}{
+		{"parent running", false, false, 2 * grace, false},
+		{"within grace", true, false, 0, false},
+		{"after grace", true, false, 4 * grace, true},
+		{"cancelled directly", false, true, 0, true},
+	}
+	for _, tt := range tests {
+		parent, cancelParent := context.WithCancel(context.Background())
+		ctx, cancel := graceContext(parent, grace)
+		if tt.cancelParent {
+			cancelParent()
+		}
+		if tt.cancelGrace {
+			cancel()
+		}
+		time.Sleep(tt.wait)
+
+		if done := ctx.Err() != nil; done != tt.wantDone {
+			t.Errorf("%s: done = %v, want %v", tt.name, done, tt.wantDone)
+		}
+		cancel()
+		cancelParent()
+	}
+}
+
+// unrecordedStore fails to record that a feed was fetched.
+type unrecordedStore struct {
+	app.Store
+}
+
+func (unrecordedStore) MarkFeedFetched(ctx context.Context, arg database.MarkFeedFetchedParams) error {
+	return errors.New("disk full")
+}
+
+func TestAggOnceStopsWhenFeedStaysDue(t *testing.T) {
+	s := newTestState(t)
+	srv := newFeedServer(t)
+	url := srv.serve("/feed.xml", rssDocument("Blog",
+		testItem{"Post", "https://blog.example/1", time.Now().Add(-time.Hour)},
+	))
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Blog", url)
+	s.Db = unrecordedStore{s.Db}
+
+	// Without a limit on the pass this would fetch the feed until the timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	out, err := runContext(ctx, t, s, "agg", "--once")
+	if err == nil || ctx.Err() != nil {
+		t.Fatalf("agg --once = %v, want it to give up on the feed before the timeout\n%s", err, out)
+	}
+	contains(t, out, "Refreshed 0 feeds, 1 failed")
+	if n := srv.count("/feed.xml"); n != 2 {
+		t.Errorf("feed fetched %d times in one pass, want it given up on when it came back", n)
+	}
+}
+
+func TestRefreshReportsChanges(t *testing.T) {
+	s := newTestState(t)
+	srv := newFeedServer(t)
+	published := time.Now().Add(-time.Hour)
+	mustRun(t, s, "register", "alice")
+	url := srv.serve("/feed.xml", rssDocument("Blog"))
+	mustRun(t, s, "addfeed", "Blog", url)
+
+	tests := []struct {
+		items	[]testItem
+		want	string
+	}{
+		{[]testItem{{"First", "https://blog.example/1", published}}, "1 new, 0 updated, 0 skipped"},
+		{[]testItem{{"First, edited", "https://blog.example/1", published}}, "0 new, 1 updated, 0 skipped"},
+		{[]testItem{
+			{"First, edited", "https://blog.example/1", published},
+			{"Second", "https://blog.example/2", published.Add(time.Minute)},
+		}, "1 new, 0 updated, 1 skipped"},
+	}
+	for _, tt := range tests {
+		srv.serve("/feed.xml", rssDocument("Blog", tt.items...))
+		contains(t, mustRun(t, s, "refresh", url), "Refreshed Blog: "+tt.want+"\n")
+	}
+
+	// Refresh ignores the schedule, so a feed that isn't due is fetched anyway
+	mustRun(t, s, "feed", "set-interval", url, "24h")
+	contains(t, mustRun(t, s, "refresh", url), "0 new, 0 updated, 2 skipped")
+	if n := srv.count("/feed.xml"); n != 4 {
+		t.Errorf("feed fetched %d times, want once per refresh", n)
+	}
+
+	srv.serve("/feed.xml", strings.Replace(rssDocument("Blog", testItem{"Undated", "https://blog.example/3", published}),
+		published.Format(time.RFC1123Z), "someday", 1))
+	contains(t, mustRun(t, s, "refresh", url), "Warning: Could not parse pubDate", "1 new")
+	mustFail(t, s, "usage", "refresh")
+}
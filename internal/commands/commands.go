@@ -17,7 +17,7 @@ type cmdDef struct {
 	name			string
 	args			string
 	desc			string
-	handler			interface{}		// func(*State, Command) error or func(*State, Command, database.User) error
+	handler			interface{}		// func(context.Context, *State, Command) error or func(context.Context, *State, Command, database.User) error
 	requiresLogin	bool
 }
 
@@ -29,37 +29,69 @@ type Command struct {
 
 // commandsList defines all available CLI commands.
 var commandsList = []cmdDef{
-	{"login",		"<username>",		"change current user",					handlerLogin,				false},
-	{"register",	"<username>",		"create a new user",					handlerRegister,			false},
-	{"reset",		"",					"wipe all user data",					handlerReset,				false},
+	{"login",		"<username>",		"log in with your password",			handlerLogin,				false},
+	{"logout",		"",					"end your session",						handlerLogout,				false},
+	{"register",	"<username>",		"create a new user and log in",			handlerRegister,			false},
+	{"reset",		"[--posts-only] [--older-than <age>] [--user <name>] [--yes]",	"delete data, saving a snapshot (admin)",	middlewareAdmin(handlerReset),	true},
+	{"backup",		"<file>",			"save everything to a backup archive (admin)",	middlewareAdmin(handlerBackup),	true},
+	{"restore",		"<file>",			"restore a backup or reset snapshot (admin)",	handlerRestore,			false},
+	{"user",		"<promote|demote|delete|set-password> <username>",	"manage users (admin)",	handlerUser,	false},
 	{"users",		"",					"list all users",						handlerGetUsers,			false},
-	{"agg",			"<duration>",		"continuously aggregate posts",			handlerAggregator,			false},
+	{"agg",			"<duration>|--once",	"aggregate posts continuously or once",	handlerAggregator,		false},
+	{"refresh",		"<url>",			"fetch a single feed now",				handlerRefresh,				false},
 	{"addfeed",		"<name> <url>",		"add a new feed",						handlerAddFeed,				true},
 	{"feeds",		"",					"list all feeds",						handlerPrintAllFeeds,		false},
-	{"follow",		"<url>",			"follow an existing feed",				handlerFollow,				true},
+	{"feed",		"<set-fulltext|set-interval|set-max-age|set-max-posts> <url> <value>",	"change feed settings",	handlerFeed,	true},
+	{"follow",		"<url> [--folder <name>]",	"follow an existing feed",		handlerFollow,				true},
 	{"following",	"",					"show feeds you're following",			handlerShowFollowedFeeds,	true},
 	{"unfollow",	"<url>",			"stop following a feed",				handlerUnfollowFeed,		true},
-	{"browse",		"[limit|2]",		"browse your latest <limit> posts",		handlerBrowse,				true},
+	{"rmfeed",		"<url> [--dry-run]",	"remove a feed you added (or any, as admin)",	handlerRemoveFeed,	true},
+	{"transferfeed",	"<url> <username>",	"give a feed you added to another user",	handlerTransferFeed,	true},
+	{"folder",		"<create|list|rm> [name]",	"manage your feed folders",		handlerFolder,				true},
+	{"rename",		"<url> <name>",		"set your own name for a followed feed",	handlerRenameFeed,		true},
+	{"move",		"<url> <folder>",	"move a followed feed into a folder",	handlerMoveFeed,			true},
+	{"rule",		"<add|list|rm> [arguments...]",	"manage your post filter rules",	handlerRule,	true},
+	{"browse",		"[limit|2] [--folder <name>] [--saved]",	"browse your latest <limit> posts",	handlerBrowse,	true},
+	{"save",		"<post-url>",		"save a post so it is never pruned",	handlerSavePost,			true},
+	{"unsave",		"<post-url>",		"remove a post from your saved posts",	handlerUnsavePost,			true},
+	{"prune",		"",					"delete posts outside the retention policy",	handlerPrune,		false},
+	{"migrate",		"<up|down [--yes]|status>",	"create or upgrade the database schema",	handlerMigrate,			false},
+	{"config",		"<show|set|init> [arguments...]",	"show or change settings",	handlerConfig,			false},
+	{"profile",		"<use|list|add> [arguments...]",	"switch between databases",	handlerProfile,			false},
+}
+
+// noDatabase lists the commands that run without the database: migrate opens
+// it itself, and config and profile must work when db_url is wrong.
+var noDatabase = map[string]bool{
+	"migrate":	true,
+	"config":	true,
+	"profile":	true,
+}
+
+// NeedsDatabase reports whether the command uses s.Db, which is opened for it.
+func NeedsDatabase(cmd Command) bool {
+	return !noDatabase[cmd.Name]
 }
 
 // commandRegistry holds registered command handlers.
 type commandRegistry struct {
-	handlers		map[string]func(*app.State, Command) error
+	handlers		map[string]func(context.Context, *app.State, Command) error
 }
 
 // newCommandRegistry creates a new command registry.
 func newCommandRegistry() *commandRegistry {
 	return &commandRegistry{
-		handlers: make(map[string]func(*app.State, Command) error),
+		handlers: make(map[string]func(context.Context, *app.State, Command) error),
 	}
 }
 
 // register adds a command handler to the registry.
-func (r *commandRegistry) register(name string, handler func(*app.State, Command) error) {
+func (r *commandRegistry) register(name string, handler func(context.Context, *app.State, Command) error) {
 	r.handlers[name] = handler
 }
 
 // Execute runs the specified command with the given state.
+// The context is passed to the handler and cancelled when gator is asked to stop.
 func Execute(ctx context.Context, state *app.State, cmd Command) error {
 	registry := buildCommandRegistry()
 
@@ -68,7 +100,7 @@ func Execute(ctx context.Context, state *app.State, cmd Command) error {
 		return fmt.Errorf("unknown command: %q", cmd.Name)
 	}
 
-	return handler(state, cmd)
+	return handler(ctx, state, cmd)
 }
 
 // buildCommandRegistry creates and populates the command registry.
@@ -76,15 +108,15 @@ func buildCommandRegistry() *commandRegistry {
 	registry := newCommandRegistry()
 
 	for _, cd := range commandsList {
-		var handler func(*app.State, Command) error
+		var handler func(context.Context, *app.State, Command) error
 
 		if cd.requiresLogin {
 			// Wrap handler with authentication middleware
-			userHandler := cd.handler.(func(*app.State, Command, database.User) error)
+			userHandler := cd.handler.(func(context.Context, *app.State, Command, database.User) error)
 			handler = middlewareLoggedIn(userHandler)
 		} else {
 			// Use handler directly
-			handler = cd.handler.(func(*app.State, Command) error)
+			handler = cd.handler.(func(context.Context, *app.State, Command) error)
 		}
 
 		registry.register(cd.name, handler)
@@ -99,7 +131,7 @@ func PrintHelp() {
 	fmt.Println("    gator - RSS feed aggregator CLI")
 	fmt.Println()
 	fmt.Println("SYNOPSIS")
-	fmt.Println("    gator <command> [arguments...]")
+	fmt.Println("    gator [--config <path>] [--profile <name>] [--db-url <url>] [--set <key>=<value>] <command> [arguments...]")
 	fmt.Println()
 	fmt.Println("DESCRIPTION")
 	fmt.Println("    A command-line tool for managing RSS feeds and aggregating posts.")
@@ -0,0 +1,211 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nhdewitt/blog-aggregator/internal/app"
+	"github.com/nhdewitt/blog-aggregator/internal/config"
+	"github.com/nhdewitt/blog-aggregator/internal/store"
+)
+
+// testPassword is the password test users register and log in with.
+const testPassword = "correct-horse"
+
+// newTestState returns a State with an empty in-memory store, and a config
+// file and home directory of its own so tests never touch the real ones.
+func newTestState(t *testing.T) *app.State {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("GATOR_PROFILE", "")
+	t.Setenv(passwordEnv, testPassword)
+
+	cfg, err := config.Load(config.Options{Path: filepath.Join(dir, "config.json")})
+	if err != nil {
+		t.Fatalf("Error loading config: %v", err)
+	}
+	fetcher, err := app.NewFetcher(cfg.Fetch)
+	if err != nil {
+		t.Fatalf("Error creating fetcher: %v", err)
+	}
+	return &app.State{Cfg: &cfg, Db: store.NewMemory(), Fetcher: fetcher}
+}
+
+// run executes a gator command line against s and returns what it printed.
+func run(t *testing.T, s *app.State, args ...string) (string, error) {
+	t.Helper()
+	return runContext(context.Background(), t, s, args...)
+}
+
+// runContext is run with a context, for commands that are stopped by cancelling it.
+func runContext(ctx context.Context, t *testing.T, s *app.State, args ...string) (string, error) {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Error creating pipe: %v", err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	printed := make(chan string)
+	go func() {
+		var buf bytes.Buffer
+		io.Copy(&buf, r)
+		r.Close()
+		printed <- buf.String()
+	}()
+
+	err = Execute(ctx, s, Command{Name: args[0], Args: args[1:]})
+
+	os.Stdout = stdout
+	w.Close()
+	return <-printed, err
+}
+
+// mustRun is run for commands that are expected to succeed.
+func mustRun(t *testing.T, s *app.State, args ...string) string {
+	t.Helper()
+	out, err := run(t, s, args...)
+	if err != nil {
+		t.Fatalf("gator %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return out
+}
+
+// mustFail is run for commands that are expected to fail with an error
+// containing want.
+func mustFail(t *testing.T, s *app.State, want string, args ...string) {
+	t.Helper()
+	out, err := run(t, s, args...)
+	if err == nil {
+		t.Fatalf("gator %s succeeded, want error containing %q\n%s", strings.Join(args, " "), want, out)
+	}
+	if !strings.Contains(err.Error(), want) {
+		t.Fatalf("gator %s: error %q, want it to contain %q", strings.Join(args, " "), err, want)
+	}
+}
+
+// contains fails the test unless out contains every string in want.
+func contains(t *testing.T, out string, want ...string) {
+	t.Helper()
+	for _, w := range want {
+		if !strings.Contains(out, w) {
+			t.Errorf("output doesn't contain %q:\n%s", w, out)
+		}
+	}
+}
+
+// testItem is one <item> of a feed served by a feedServer.
+type testItem struct {
+	Title		string
+	Link		string
+	Published	time.Time
+}
+
+// rssDocument builds an RSS 2.0 feed with the given items.
+func rssDocument(title string, items ...testItem) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	b.WriteString(`<rss version="2.0"><channel>`)
+	fmt.Fprintf(&b, "<title>%s</title>", title)
+	for _, item := range items {
+		fmt.Fprintf(&b, "<item><title>%s</title><link>%s</link><description>About %s</description><pubDate>%s</pubDate></item>",
+			item.Title, item.Link, item.Title, item.Published.Format(time.RFC1123Z))
+	}
+	b.WriteString("</channel></rss>")
+	return b.String()
+}
+
+// feedServer serves feed documents by path over HTTP.
+type feedServer struct {
+	*httptest.Server
+	mu			sync.Mutex
+	docs		map[string]string
+	requests	map[string]int
+}
+
+// newFeedServer starts a feedServer that is shut down when the test ends.
+func newFeedServer(t *testing.T) *feedServer {
+	t.Helper()
+	fs := &feedServer{docs: make(map[string]string), requests: make(map[string]int)}
+	fs.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fs.mu.Lock()
+		doc, ok := fs.docs[r.URL.Path]
+		fs.requests[r.URL.Path]++
+		fs.mu.Unlock()
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/rss+xml")
+		io.WriteString(w, doc)
+	}))
+	t.Cleanup(fs.Close)
+	return fs
+}
+
+// serve publishes doc at path and returns its URL.
+func (fs *feedServer) serve(path, doc string) string {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.docs[path] = doc
+	return fs.URL + path
+}
+
+// count returns how many requests path has received.
+func (fs *feedServer) count(path string) int {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.requests[path]
+}
+
+func TestExecuteUnknownCommand(t *testing.T) {
+	s := newTestState(t)
+	mustFail(t, s, `unknown command: "frobnicate"`, "frobnicate")
+}
+
+func TestCommandsRequireLogin(t *testing.T) {
+	s := newTestState(t)
+	for _, cd := range commandsList {
+		if !cd.requiresLogin {
+			continue
+		}
+		t.Run(cd.name, func(t *testing.T) {
+			_, err := run(t, s, cd.name)
+			if !errors.Is(err, app.ErrNotLoggedIn) {
+				t.Errorf("gator %s without logging in: error %v, want %v", cd.name, err, app.ErrNotLoggedIn)
+			}
+		})
+	}
+}
+
+func TestNeedsDatabase(t *testing.T) {
+	tests := []struct {
+		name	string
+		want	bool
+	}{
+		{"browse", true},
+		{"agg", true},
+		{"login", true},
+		{"migrate", false},
+		{"config", false},
+		{"profile", false},
+	}
+	for _, tt := range tests {
+		if got := NeedsDatabase(Command{Name: tt.name}); got != tt.want {
+			t.Errorf("NeedsDatabase(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+// Package commands implements the CLI command system for the gator RSS aggregator.
+package commands
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nhdewitt/blog-aggregator/internal/app"
+	"github.com/nhdewitt/blog-aggregator/internal/config"
+	"github.com/nhdewitt/blog-aggregator/internal/store"
+	"golang.org/x/term"
+)
+
+// handlerConfig shows and changes the settings in the config file.
+// It runs without opening the database, so a wrong db_url can be fixed with it.
+//
+// Usage: gator config <show|set|init> [arguments...]
+func handlerConfig(ctx context.Context, s *app.State, cmd Command) error {
+	if len(cmd.Args) < 1 {
+		return fmt.Errorf("usage: %s <show|set|init> [arguments...]", cmd.Name)
+	}
+	sub := Command{
+		Name: cmd.Name + " " + cmd.Args[0],
+		Args: cmd.Args[1:],
+	}
+
+	switch cmd.Args[0] {
+	case "show":
+		return handlerConfigShow(ctx, s, sub)
+	case "set":
+		return handlerConfigSet(ctx, s, sub)
+	case "init":
+		return handlerConfigInit(ctx, s, sub)
+	default:
+		return fmt.Errorf("unknown %s subcommand: %q", cmd.Name, cmd.Args[0])
+	}
+}
+
+// handlerConfigShow displays every setting and where its value came from.
+//
+// Usage: gator config show
+func handlerConfigShow(ctx context.Context, s *app.State, cmd Command) error {
+	if len(cmd.Args) != 0 {
+		return fmt.Errorf("usage: %s", cmd.Name)
+	}
+
+	if s.Cfg.Exists() {
+		fmt.Printf("Config file: %s\n", s.Cfg.Path())
+	} else {
+		fmt.Printf("Config file: %s (not created yet)\n", s.Cfg.Path())
+	}
+	fmt.Printf("Profile: %s\n", s.Cfg.Profile())
+	if s.Cfg.CurrentUser != "" {
+		fmt.Printf("Logged in as: %s\n", s.Cfg.CurrentUser)
+	}
+	fmt.Println()
+
+	for _, v := range s.Cfg.Values() {
+		value := v.Value
+		if value == "" {
+			value = "(not set)"
+		}
+		fmt.Printf(" * %-29s %s (%s)\n", v.Key+":", value, v.Source)
+	}
+	fmt.Println()
+	fmt.Println("Environment variables override the file, e.g. GATOR_FETCH_TIMEOUT for fetch.timeout,")
+	fmt.Println("and --db-url or --set <key>=<value> before the command override both.")
+	return nil
+}
+
+// handlerConfigSet saves a setting in the config file. An empty value
+// removes the setting so its default applies again.
+//
+// Usage: gator config set <key> <value>
+func handlerConfigSet(ctx context.Context, s *app.State, cmd Command) error {
+	if len(cmd.Args) != 2 {
+		return fmt.Errorf("usage: %s <key> <value>\nsettings: %s", cmd.Name, strings.Join(config.Keys(), ", "))
+	}
+	key, value := cmd.Args[0], cmd.Args[1]
+
+	if err := s.Cfg.Save(key, value); err != nil {
+		return err
+	}
+
+	fmt.Printf("Saved %s in %s\n", key, s.Cfg.Path())
+	if s.Cfg.Overridden(key) {
+		fmt.Printf("Note: %s is currently overridden by %s\n", key, s.Cfg.Source(key))
+	}
+	return nil
+}
+
+// handlerConfigInit creates the config file, asking for the database to use
+// when run on a terminal.
+//
+// Usage: gator config init [--force]
+func handlerConfigInit(ctx context.Context, s *app.State, cmd Command) error {
+	args, flags, err := parseFlags(cmd.Args, flagSpec{"force": false})
+	if err != nil || len(args) != 0 {
+		return fmt.Errorf("usage: %s [--force]", cmd.Name)
+	}
+	if s.Cfg.Exists() && flags["force"] == "" {
+		return fmt.Errorf("%s already exists; use --force to set it up again", s.Cfg.Path())
+	}
+
+	return runSetup(ctx, s.Cfg)
+}
+
+// NeedsSetup reports whether to run the first-run setup before cmd: there is
+// no config file, the database hasn't been chosen with an environment
+// variable or flag, and someone is at the terminal to answer.
+func NeedsSetup(cfg *config.Config, cmd Command) bool {
+	return !cfg.Exists() &&
+		cfg.Source("db_url") == "default" &&
+		cmd.Name != "config" &&
+		term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// Setup walks a new user through creating the config file and the database
+// schema, then lets the command they ran continue.
+func Setup(ctx context.Context, cfg *config.Config) error {
+	fmt.Fprintln(os.Stderr, "Welcome to gator! There is no config file yet, so let's create one.")
+	return runSetup(ctx, cfg)
+}
+
+// runSetup asks for the database and contact URL, saves them and offers to
+// create the schema. Without a terminal the current values are saved as they are.
+func runSetup(ctx context.Context, cfg *config.Config) error {
+	interactive := term.IsTerminal(int(os.Stdin.Fd()))
+	in := bufio.NewReader(os.Stdin)
+
+	dbURL := cfg.DBUrl
+	contactURL := cfg.Fetch.ContactURL
+	if interactive {
+		fmt.Fprintln(os.Stderr, "Use a SQLite file for yourself, or a postgres:// URL to share a database server.")
+		for {
+			answer, err := prompt(in, "Database URL", dbURL)
+			if err != nil {
+				return err
+			}
+			if scheme, _, _ := strings.Cut(answer, ":"); isDatabaseScheme(scheme) {
+				dbURL = answer
+				break
+			}
+			fmt.Fprintln(os.Stderr, "The URL must start with sqlite:// or postgres://")
+		}
+
+		fmt.Fprintln(os.Stderr, "A URL or email address in gator's User-Agent lets publishers contact you about your polling.")
+		answer, err := prompt(in, "Contact URL (optional)", contactURL)
+		if err != nil {
+			return err
+		}
+		contactURL = answer
+	}
+
+	if err := cfg.Save("db_url", dbURL); err != nil {
+		return err
+	}
+	if err := cfg.Save("fetch.contact_url", contactURL); err != nil {
+		return err
+	}
+	fmt.Printf("Config saved to %s\n", cfg.Path())
+
+	if !interactive {
+		return nil
+	}
+	answer, err := prompt(in, "Create the database schema now? [Y/n]", "y")
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(strings.ToLower(answer), "y") {
+		fmt.Println("Run 'gator migrate up' to create the database schema")
+		return nil
+	}
+
+	dbURL, err = cfg.DatabaseURL()
+	if err != nil {
+		return err
+	}
+	m, err := store.OpenMigrator(ctx, dbURL)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+	applied, err := m.Up(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Applied %d migrations\n", len(applied))
+	return nil
+}
+
+// prompt asks a question on the terminal and returns the answer, or def if
+// the answer is empty.
+func prompt(in *bufio.Reader, question, def string) (string, error) {
+	if def != "" && !strings.HasSuffix(question, "]") {
+		question += " [" + def + "]"
+	}
+	fmt.Fprint(os.Stderr, question+": ")
+
+	line, err := in.ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("Error reading answer: %w", err)
+	}
+	if answer := strings.TrimSpace(line); answer != "" {
+		return answer, nil
+	}
+	return def, nil
+}
+
+// isDatabaseScheme reports whether store.Open accepts db_url with this scheme.
+func isDatabaseScheme(scheme string) bool {
+	switch strings.ToLower(scheme) {
+	case "postgres", "postgresql", "sqlite", "sqlite3", "file":
+		return true
+	}
+	return false
+}
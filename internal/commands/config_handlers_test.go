@@ -0,0 +1,21 @@
+package commands
+
+import (
+	"testing"
+)
+
+func TestConfigShowAndSet(t *testing.T) {
+	s := newTestState(t)
+
+	contains(t, mustRun(t, s, "config", "show"), "(not created yet)", "Profile: default", "fetch.timeout:", "(not set) (default)")
+
+	contains(t, mustRun(t, s, "config", "set", "fetch.timeout", "45s"), "Saved fetch.timeout in "+s.Cfg.Path())
+	if s.Cfg.Fetch.Timeout != "45s" {
+		t.Errorf("fetch.timeout = %q after config set, want 45s", s.Cfg.Fetch.Timeout)
+	}
+	contains(t, mustRun(t, s, "config", "show"), "Config file: "+s.Cfg.Path()+"\n", "45s ("+s.Cfg.Path()+")")
+
+	mustFail(t, s, `Unknown setting "fetch.colour"`, "config", "set", "fetch.colour", "red")
+	mustFail(t, s, "must be a whole number", "config", "set", "fetch.max_redirects", "lots")
+	mustFail(t, s, "already exists; use --force", "config", "init")
+}
@@ -3,7 +3,11 @@ package commands
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"math"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -15,14 +19,12 @@ import (
 // The feed URL must be valid and accessible.
 //
 // Usage: gator addfeed <feed_name> <feed_url>
-func handlerAddFeed(s *app.State, cmd Command, user database.User) error {
+func handlerAddFeed(ctx context.Context, s *app.State, cmd Command, user database.User) error {
 	if len(cmd.Args) != 2 {
 		return fmt.Errorf("usage: %s <feed name> <feed url>", cmd.Name)
 	}
 
-	c := context.Background()
-
-	newFeed, err := s.Db.AddFeed(c, database.AddFeedParams{
+	newFeed, err := s.Db.AddFeed(ctx, database.AddFeedParams{
 		ID: uuid.New(),
 		CreatedAt: time.Now().UTC(),
 		UpdatedAt: time.Now().UTC(),
@@ -34,7 +36,7 @@ func handlerAddFeed(s *app.State, cmd Command, user database.User) error {
 		return fmt.Errorf("Error adding feed: %w", err)
 	}
 
-	_, err = s.Db.CreateFeedFollow(c, database.CreateFeedFollowParams{
+	_, err = s.Db.CreateFeedFollow(ctx, database.CreateFeedFollowParams{
 		ID: uuid.New(),
 		CreatedAt: time.Now().UTC(),
 		UpdatedAt: time.Now().UTC(),
@@ -50,12 +52,12 @@ func handlerAddFeed(s *app.State, cmd Command, user database.User) error {
 }
 
 // handlerPrintAllFeeds displays all feeds in the system with their creators.
-// Shows feed name, URL, and thje user who added it.
+// Shows feed name, URL, thje user who added it, its refresh interval and retention
+// limits, and any problems found the last time the feed was parsed.
 //
 // Usage: gator feeds
-func handlerPrintAllFeeds(s *app.State, cmd Command) error {
-	c := context.Background()
-	feeds, err := s.Db.PrintAllFeeds(c)
+func handlerPrintAllFeeds(ctx context.Context, s *app.State, cmd Command) error {
+	feeds, err := s.Db.PrintAllFeeds(ctx)
 	if err != nil {
 		return fmt.Errorf("Error retrieving all feeds: %w", err)
 	}
@@ -64,33 +66,47 @@ func handlerPrintAllFeeds(s *app.State, cmd Command) error {
 		fmt.Printf(" * Feed:\t%s\n", feed.FeedName)
 		fmt.Printf(" * URL:\t\t%s\n", feed.FeedUrl)
 		fmt.Printf(" * Added by:\t%s\n", feed.UserName)
+		fmt.Printf(" * Interval:\t%s\n", formatInterval(feed.FetchInterval, feed.AdaptiveInterval))
+		fmt.Printf(" * Retention:\t%s\n", formatRetention(feed.RetentionMaxAge, feed.RetentionMaxPosts))
+		if feed.ParseWarnings.Valid {
+			for _, warning := range strings.Split(feed.ParseWarnings.String, "\n") {
+				fmt.Printf(" * Warning:\t%s\n", warning)
+			}
+		}
 		fmt.Println()
 	}
 	return nil
 }
 
 // handlerFollow allows the current user to follow an existing feed by URL.
-// The feed must already exist in the syustem.
+// The feed must already exist in the syustem. With --folder, the feed is
+// placed in one of the user's folders.
 //
-// Usage: gator follow <url>
-func handlerFollow(s *app.State, cmd Command, user database.User) error {
-	if len(cmd.Args) != 1 {
-		return fmt.Errorf("usage: %s <url>", cmd.Name)
+// Usage: gator follow <url> [--folder <name>]
+func handlerFollow(ctx context.Context, s *app.State, cmd Command, user database.User) error {
+	args, flags, err := parseFlags(cmd.Args, flagSpec{"folder": true})
+	if err != nil || len(args) != 1 {
+		return fmt.Errorf("usage: %s <url> [--folder <name>]", cmd.Name)
 	}
-	url := cmd.Args[0]
+	url := args[0]
 
-	c := context.Background()
-	followedFeed, err := s.Db.FindFeedsByURL(c, url)
+	followedFeed, err := s.Db.FindFeedsByURL(ctx, url)
 	if err != nil {
 		return fmt.Errorf("Error finding feed: %w", err)
 	}
 
-	createdFollow, err := s.Db.CreateFeedFollow(c, database.CreateFeedFollowParams{
+	folderID, err := lookupFolder(ctx, s, user, flags["folder"])
+	if err != nil {
+		return err
+	}
+
+	createdFollow, err := s.Db.CreateFeedFollow(ctx, database.CreateFeedFollowParams{
 		ID:	uuid.New(),
 		CreatedAt: time.Now().UTC(),
 		UpdatedAt: time.Now().UTC(),
 		UserID: user.ID,
 		FeedID: followedFeed.ID,
+		FolderID: folderID,
 	})
 	if err != nil {
 		return fmt.Errorf("Error following feed: %w", err)
@@ -101,17 +117,16 @@ func handlerFollow(s *app.State, cmd Command, user database.User) error {
 }
 
 // handlerShowFollowedFeeds displays all feeds that the current user is following.
-// Shows the name of each feed.
+// Shows the name of each feed, grouped by folder with unfiled feeds first.
 //
 // Usage: gator following
-func handlerShowFollowedFeeds(s *app.State, cmd Command, user database.User) error {
+func handlerShowFollowedFeeds(ctx context.Context, s *app.State, cmd Command, user database.User) error {
 	if len(cmd.Args) != 0 {
 		return fmt.Errorf("usage: %s", cmd.Name)
 	}
-	c := context.Background()
 	
 	id := user.ID
-	feeds, err := s.Db.GetFeedFollowsForUser(c, id)
+	feeds, err := s.Db.GetFeedFollowsForUser(ctx, id)
 	if err != nil {
 		return fmt.Errorf("Error getting user's feeds: %w", err)
 	}
@@ -119,36 +134,296 @@ func handlerShowFollowedFeeds(s *app.State, cmd Command, user database.User) err
 	if len(feeds) == 0 {
 		fmt.Println("You are not subscribed to any feeds")
 	} else {
-		fmt.Printf("Subscribed feeds for %s:\n", s.Cfg.CurrentUser)
+		fmt.Printf("Subscribed feeds for %s:\n", user.Name)
 	}
-	for _, feed := range feeds {
-		fmt.Println(feed.Name)
+	currentFolder := ""
+	for i, feed := range feeds {
+		if i == 0 || feed.FolderName.String != currentFolder {
+			currentFolder = feed.FolderName.String
+			if feed.FolderName.Valid {
+				fmt.Printf("\n%s/\n", currentFolder)
+			}
+		}
+		if feed.FolderName.Valid {
+			fmt.Printf("    %s\n", feed.Name)
+		} else {
+			fmt.Println(feed.Name)
+		}
 	}
 
 	return nil
 }
 
 // handlerUnfollowFeed removes the current user's subscription to a feed.
-// The user will no longer receive posts from this feed.
+// The user will no longer receive posts from this feed. A feed nobody
+// follows anymore is removed from the catalog, unless someone saved one of
+// its posts.
 //
 // Usage: gator unfollow <url>
-func handlerUnfollowFeed(s *app.State, cmd Command, user database.User) error {
+func handlerUnfollowFeed(ctx context.Context, s *app.State, cmd Command, user database.User) error {
 	if len(cmd.Args) != 1 {
 		return fmt.Errorf("usage: %s <url>", cmd.Name)
 	}
-	c := context.Background()
-
 	id := user.ID
 	url := cmd.Args[0]
 
-	err := s.Db.UnfollowFeed(c, database.UnfollowFeedParams{
+	err := s.Db.UnfollowFeed(ctx, database.UnfollowFeedParams{
 		ID: id,
 		Url: url,
 	})
 	if err != nil {
 		return fmt.Errorf("Error unfollowing feed: %w", err)
 	}
-
 	fmt.Println("You have unfollowed the feed")
+
+	// Drop the feed from the catalog if that was its last follower and
+	// none of its posts are saved
+	removed, err := s.Db.DeleteFeedIfOrphaned(ctx, url)
+	if err != nil {
+		return fmt.Errorf("Error removing unfollowed feed: %w", err)
+	}
+	if removed > 0 {
+		fmt.Println("Nobody follows it anymore, so it was removed from the catalog")
+	}
+	return nil
+}
+
+// handlerRemoveFeed deletes a feed from the catalog together with its follows,
+// posts and feed-specific filter rules. Only the user who added the feed or an
+// admin may remove it. With --dry-run, only the summary of what would be
+// deleted is shown.
+//
+// Usage: gator rmfeed <url> [--dry-run]
+func handlerRemoveFeed(ctx context.Context, s *app.State, cmd Command, user database.User) error {
+	args, flags, err := parseFlags(cmd.Args, flagSpec{"dry-run": false})
+	if err != nil || len(args) != 1 {
+		return fmt.Errorf("usage: %s <url> [--dry-run]", cmd.Name)
+	}
+
+	feed, err := findManagedFeed(ctx, s, args[0], user)
+	if err != nil {
+		return err
+	}
+
+	usage, err := s.Db.GetFeedUsage(ctx, feed.ID)
+	if err != nil {
+		return fmt.Errorf("Error counting feed data: %w", err)
+	}
+	fmt.Printf("Feed %s (%s)\n", feed.Name, feed.Url)
+	fmt.Printf(" * Followers:\t%d\n", usage.Followers)
+	fmt.Printf(" * Posts:\t%d\n", usage.Posts)
+	fmt.Printf(" * Rules:\t%d\n", usage.Rules)
+
+	if flags["dry-run"] == "true" {
+		fmt.Println("Dry run, nothing was deleted")
+		return nil
+	}
+
+	if err := s.Db.DeleteFeed(ctx, feed.ID); err != nil {
+		return fmt.Errorf("Error deleting feed: %w", err)
+	}
+	fmt.Println("Feed removed")
+	return nil
+}
+
+// handlerTransferFeed hands ownership of a feed to another user.
+// Only the feed's current owner or an admin may transfer it.
+//
+// Usage: gator transferfeed <url> <username>
+func handlerTransferFeed(ctx context.Context, s *app.State, cmd Command, user database.User) error {
+	if len(cmd.Args) != 2 {
+		return fmt.Errorf("usage: %s <url> <username>", cmd.Name)
+	}
+
+	feed, err := findManagedFeed(ctx, s, cmd.Args[0], user)
+	if err != nil {
+		return err
+	}
+
+	newOwner, err := s.Db.GetUser(ctx, cmd.Args[1])
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("User %s not found", cmd.Args[1])
+	}
+	if err != nil {
+		return fmt.Errorf("Error finding user: %w", err)
+	}
+
+	err = s.Db.SetFeedOwner(ctx, database.SetFeedOwnerParams{ID: feed.ID, UserID: newOwner.ID})
+	if err != nil {
+		return fmt.Errorf("Error transferring feed: %w", err)
+	}
+
+	fmt.Printf("%s now belongs to %s\n", feed.Name, newOwner.Name)
+	return nil
+}
+
+// findManagedFeed looks up a feed by URL and checks that user may manage it,
+// i.e. that they added it or are an admin.
+func findManagedFeed(ctx context.Context, s *app.State, url string, user database.User) (database.Feed, error) {
+	feed, err := s.Db.FindFeedsByURL(ctx, url)
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.Feed{}, fmt.Errorf("Feed %s not found", url)
+	}
+	if err != nil {
+		return database.Feed{}, fmt.Errorf("Error finding feed: %w", err)
+	}
+	if feed.UserID != user.ID && !app.IsAdmin(user) {
+		return database.Feed{}, fmt.Errorf("Only the user who added %s or an admin can manage it", url)
+	}
+	return feed, nil
+}
+
+// handlerFeed dispatches the feed management subcommands.
+//
+// Usage: gator feed <subcommand> [arguments...]
+func handlerFeed(ctx context.Context, s *app.State, cmd Command, user database.User) error {
+	if len(cmd.Args) < 1 {
+		return fmt.Errorf("usage: %s <set-fulltext|set-interval|set-max-age|set-max-posts> [arguments...]", cmd.Name)
+	}
+	sub := Command{
+		Name: cmd.Name + " " + cmd.Args[0],
+		Args: cmd.Args[1:],
+	}
+
+	switch cmd.Args[0] {
+	case "set-fulltext":
+		return handlerFeedSetFullText(ctx, s, sub, user)
+	case "set-interval":
+		return handlerFeedSetInterval(ctx, s, sub, user)
+	case "set-max-age":
+		return handlerFeedSetMaxAge(ctx, s, sub, user)
+	case "set-max-posts":
+		return handlerFeedSetMaxPosts(ctx, s, sub, user)
+	default:
+		return fmt.Errorf("unknown %s subcommand: %q", cmd.Name, cmd.Args[0])
+	}
+}
+
+// handlerFeedSetFullText turns full article fetching on or off for a feed.
+// When enabled, the aggregator downloads each new post's page and stores
+// the extracted article text alongside the post.
+//
+// Usage: gator feed set-fulltext <url> <on|off>
+func handlerFeedSetFullText(ctx context.Context, s *app.State, cmd Command, user database.User) error {
+	if len(cmd.Args) != 2 {
+		return fmt.Errorf("usage: %s <url> <on|off>", cmd.Name)
+	}
+
+	var enabled bool
+	switch cmd.Args[1] {
+	case "on":
+		enabled = true
+	case "off":
+		enabled = false
+	default:
+		return fmt.Errorf("usage: %s <url> <on|off>", cmd.Name)
+	}
+
+	feed, err := findManagedFeed(ctx, s, cmd.Args[0], user)
+	if err != nil {
+		return err
+	}
+
+	err = s.Db.SetFeedFullText(ctx, database.SetFeedFullTextParams{
+		ID: feed.ID,
+		FetchFullText: enabled,
+	})
+	if err != nil {
+		return fmt.Errorf("Error updating feed: %w", err)
+	}
+
+	fmt.Printf("Full text fetching for %s is now %s\n", feed.Name, cmd.Args[1])
+	return nil
+}
+
+// handlerFeedSetInterval sets how often the aggregator fetches a feed.
+// "adaptive" learns the interval from how often the feed publishes, and
+// "default" fetches the feed on every aggregator pass.
+//
+// Usage: gator feed set-interval <url> <duration|adaptive|default>
+func handlerFeedSetInterval(ctx context.Context, s *app.State, cmd Command, user database.User) error {
+	if len(cmd.Args) != 2 {
+		return fmt.Errorf("usage: %s <url> <duration|adaptive|default>", cmd.Name)
+	}
+
+	var interval sql.NullInt32
+	var adaptive bool
+	switch cmd.Args[1] {
+	case "adaptive":
+		adaptive = true
+	case "default":
+	default:
+		d, err := time.ParseDuration(cmd.Args[1])
+		if err != nil || d < time.Second {
+			return fmt.Errorf("Please enter duration in the form (30m|6h|24h), or adaptive or default")
+		}
+		// The interval is stored in seconds as a 32-bit integer
+		if d/time.Second > math.MaxInt32 {
+			return fmt.Errorf("Interval can be at most %s", time.Duration(math.MaxInt32)*time.Second)
+		}
+		interval = sql.NullInt32{Int32: int32(d / time.Second), Valid: true}
+	}
+
+	feed, err := findManagedFeed(ctx, s, cmd.Args[0], user)
+	if err != nil {
+		return err
+	}
+
+	err = app.SetFetchInterval(ctx, s, feed, interval, adaptive)
+	if err != nil {
+		return fmt.Errorf("Error updating feed: %w", err)
+	}
+
+	fmt.Printf("Refresh interval for %s is now %s\n", feed.Name, cmd.Args[1])
+	return nil
+}
+
+// handlerRenameFeed sets the name the current user sees for a followed feed.
+// The override only affects the current user; an empty name restores the feed's own name.
+//
+// Usage: gator rename <url> <name>
+func handlerRenameFeed(ctx context.Context, s *app.State, cmd Command, user database.User) error {
+	if len(cmd.Args) != 2 {
+		return fmt.Errorf("usage: %s <url> <name>", cmd.Name)
+	}
+	url := cmd.Args[0]
+	name := cmd.Args[1]
+
+	renamed, err := s.Db.SetFeedFollowDisplayName(ctx, database.SetFeedFollowDisplayNameParams{
+		UserID: user.ID,
+		Url: url,
+		DisplayName: sql.NullString{String: name, Valid: name != ""},
+	})
+	if err != nil {
+		return fmt.Errorf("Error renaming feed: %w", err)
+	}
+	if renamed == 0 {
+		return fmt.Errorf("You are not following %s", url)
+	}
+
+	if name == "" {
+		fmt.Printf("Restored the original name for %s\n", url)
+	} else {
+		fmt.Printf("Renamed %s to %s\n", url, name)
+	}
 	return nil
+}
+
+// formatInterval describes a feed's refresh interval for display.
+func formatInterval(interval sql.NullInt32, adaptive bool) string {
+	var d string
+	if interval.Valid {
+		d = (time.Duration(interval.Int32) * time.Second).String()
+	}
+
+	switch {
+	case adaptive && d != "":
+		return "adaptive (currently " + d + ")"
+	case adaptive:
+		return "adaptive"
+	case d != "":
+		return d
+	default:
+		return "every pass"
+	}
 }
\ No newline at end of file
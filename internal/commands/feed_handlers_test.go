@@ -0,0 +1,205 @@
+package commands
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAddFeedFollowUnfollow(t *testing.T) {
+	s := newTestState(t)
+	mustRun(t, s, "register", "alice")
+
+	out := mustRun(t, s, "addfeed", "Go Blog", "https://go.dev/blog/feed.atom")
+	contains(t, out, "New Feed Added", "Go Blog")
+	contains(t, mustRun(t, s, "following"), "Subscribed feeds for alice", "Go Blog")
+
+	out = mustRun(t, s, "feeds")
+	contains(t, out, "Feed:\tGo Blog", "URL:\t\thttps://go.dev/blog/feed.atom", "Added by:\talice", "Interval:\tevery pass")
+
+	mustRun(t, s, "register", "bob")
+	contains(t, mustRun(t, s, "follow", "https://go.dev/blog/feed.atom"), "User bob is now following feed Go Blog")
+	mustFail(t, s, "Error following feed", "follow", "https://go.dev/blog/feed.atom")
+	mustFail(t, s, "Error finding feed", "follow", "https://example.com/missing.xml")
+
+	contains(t, mustRun(t, s, "unfollow", "https://go.dev/blog/feed.atom"), "You have unfollowed the feed")
+	contains(t, mustRun(t, s, "following"), "You are not subscribed to any feeds")
+}
+
+func TestRemoveAndTransferFeed(t *testing.T) {
+	s := newTestState(t)
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "register", "bob")
+	mustRun(t, s, "addfeed", "Bob's Blog", "https://bob.example/feed")
+
+	contains(t, mustRun(t, s, "transferfeed", "https://bob.example/feed", "alice"), "Bob's Blog now belongs to alice")
+	mustFail(t, s, "Only the user who added", "rmfeed", "https://bob.example/feed")
+
+	mustRun(t, s, "login", "alice")
+	out := mustRun(t, s, "rmfeed", "--dry-run", "https://bob.example/feed")
+	contains(t, out, "Followers:\t1", "Dry run, nothing was deleted")
+	contains(t, mustRun(t, s, "rmfeed", "https://bob.example/feed"), "Feed removed")
+	mustFail(t, s, "not found", "rmfeed", "https://bob.example/feed")
+}
+
+func TestUnfollowKeepsSavedPosts(t *testing.T) {
+	s := newTestState(t)
+	srv := newFeedServer(t)
+	url := srv.serve("/feed.xml", rssDocument("Blog",
+		testItem{"Keeper", "https://blog.example/keeper", time.Now().Add(-time.Hour)},
+	))
+
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Blog", url)
+	mustRun(t, s, "refresh", url)
+	mustRun(t, s, "save", "https://blog.example/keeper")
+
+	// The last follower leaving doesn't take the saved post with it
+	out := mustRun(t, s, "unfollow", url)
+	if strings.Contains(out, "removed from the catalog") {
+		t.Errorf("unfollow removed a feed with a saved post:\n%s", out)
+	}
+	mustRun(t, s, "follow", url)
+	contains(t, mustRun(t, s, "browse", "--saved"), "[saved] Keeper")
+
+	// Once nothing is saved, unfollowing removes it as before
+	mustRun(t, s, "unsave", "https://blog.example/keeper")
+	contains(t, mustRun(t, s, "unfollow", url), "Nobody follows it anymore, so it was removed from the catalog")
+	mustFail(t, s, "Error finding feed", "follow", url)
+}
+
+func TestFeedSettings(t *testing.T) {
+	s := newTestState(t)
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Blog", "https://blog.example/rss")
+
+	tests := []struct {
+		args	[]string
+		want	string
+	}{
+		{[]string{"set-fulltext", "https://blog.example/rss", "on"}, "Full text fetching for Blog is now on"},
+		{[]string{"set-interval", "https://blog.example/rss", "6h"}, "Refresh interval for Blog is now 6h"},
+		{[]string{"set-interval", "https://blog.example/rss", "adaptive"}, "Refresh interval for Blog is now adaptive"},
+		{[]string{"set-max-age", "https://blog.example/rss", "30d"}, "Maximum post age for Blog is now 30d"},
+		{[]string{"set-max-posts", "https://blog.example/rss", "100"}, "Maximum number of posts for Blog is now 100"},
+	}
+	for _, tt := range tests {
+		contains(t, mustRun(t, s, append([]string{"feed"}, tt.args...)...), tt.want)
+	}
+
+	contains(t, mustRun(t, s, "feeds"), "Interval:\tadaptive", "Retention:\tmax age 720h0m0s, max 100 posts")
+	mustFail(t, s, "usage", "feed", "set-fulltext", "https://blog.example/rss", "maybe")
+	mustFail(t, s, "unknown feed subcommand", "feed", "set-colour", "https://blog.example/rss", "red")
+}
+
+func TestRenameFeed(t *testing.T) {
+	s := newTestState(t)
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Blog", "https://blog.example/rss")
+
+	contains(t, mustRun(t, s, "rename", "https://blog.example/rss", "My Blog"), "Renamed https://blog.example/rss to My Blog")
+	contains(t, mustRun(t, s, "following"), "My Blog")
+	contains(t, mustRun(t, s, "rename", "https://blog.example/rss", ""), "Restored the original name")
+	mustFail(t, s, "You are not following", "rename", "https://other.example/rss", "Other")
+}
+
+func TestSetFullTextNeedsOwner(t *testing.T) {
+	s := newTestState(t)
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "register", "bob")
+	mustRun(t, s, "addfeed", "Bob's Blog", "https://bob.example/feed")
+	mustRun(t, s, "register", "carol")
+
+	mustFail(t, s, "Only the user who added https://bob.example/feed or an admin can manage it", "feed", "set-fulltext", "https://bob.example/feed", "on")
+	mustFail(t, s, "Feed https://nobody.example/feed not found", "feed", "set-fulltext", "https://nobody.example/feed", "on")
+
+	// alice registered first and is the admin
+	mustRun(t, s, "login", "alice")
+	contains(t, mustRun(t, s, "feed", "set-fulltext", "https://bob.example/feed", "on"), "Full text fetching for Bob's Blog is now on")
+}
+
+func TestRenameIsPerUser(t *testing.T) {
+	s := newTestState(t)
+	srv := newFeedServer(t)
+	url := srv.serve("/feed.xml", rssDocument("Blog",
+		testItem{"Post", "https://blog.example/1", time.Now().Add(-time.Hour)},
+	))
+
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Blog", url)
+	mustRun(t, s, "refresh", url)
+	mustRun(t, s, "rename", url, "Alice's Pick")
+	mustRun(t, s, "register", "bob")
+	mustRun(t, s, "follow", url)
+
+	tests := []struct {
+		user	string
+		want	string
+	}{
+		{"alice", "Feed: Alice's Pick"},
+		{"bob", "Feed: Blog"},
+	}
+	for _, tt := range tests {
+		mustRun(t, s, "login", tt.user)
+		contains(t, mustRun(t, s, "browse"), tt.want)
+	}
+
+	// The catalog keeps the feed's own name
+	contains(t, mustRun(t, s, "feeds"), "Feed:\tBlog")
+}
+
+func TestSetIntervalNeedsOwner(t *testing.T) {
+	s := newTestState(t)
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "register", "bob")
+	mustRun(t, s, "addfeed", "Bob's Blog", "https://bob.example/feed")
+	mustRun(t, s, "register", "carol")
+
+	mustFail(t, s, "Only the user who added https://bob.example/feed or an admin can manage it", "feed", "set-interval", "https://bob.example/feed", "1h")
+	mustFail(t, s, "Feed https://nobody.example/feed not found", "feed", "set-interval", "https://nobody.example/feed", "1h")
+	mustFail(t, s, "Please enter duration", "feed", "set-interval", "https://bob.example/feed", "soon")
+
+	mustRun(t, s, "login", "bob")
+	contains(t, mustRun(t, s, "feed", "set-interval", "https://bob.example/feed", "30m"), "Refresh interval for Bob's Blog is now 30m")
+	contains(t, mustRun(t, s, "feeds"), "Interval:\t30m0s")
+}
+
+func TestSetIntervalRejects(t *testing.T) {
+	s := newTestState(t)
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Blog", "https://blog.example/rss")
+
+	tests := []struct {
+		interval	string
+		want		string
+	}{
+		{"soon", "Please enter duration"},
+		{"500ms", "Please enter duration"},
+		{"-1h", "Please enter duration"},
+		{"600000h", "Interval can be at most 596523h14m7s"},
+	}
+	for _, tt := range tests {
+		mustFail(t, s, tt.want, "feed", "set-interval", "https://blog.example/rss", tt.interval)
+	}
+	// The longest interval that fits is still accepted
+	contains(t, mustRun(t, s, "feed", "set-interval", "https://blog.example/rss", "596523h"), "Refresh interval for Blog is now 596523h")
+}
+
+func TestFormatInterval(t *testing.T) {
+	tests := []struct {
+		interval	sql.NullInt32
+		adaptive	bool
+		want		string
+	}{
+		{sql.NullInt32{}, false, "every pass"},
+		{sql.NullInt32{Int32: 5400, Valid: true}, false, "1h30m0s"},
+		{sql.NullInt32{}, true, "adaptive"},
+		{sql.NullInt32{Int32: 900, Valid: true}, true, "adaptive (currently 15m0s)"},
+	}
+	for _, tt := range tests {
+		if got := formatInterval(tt.interval, tt.adaptive); got != tt.want {
+			t.Errorf("formatInterval(%v, %v) = %q, want %q", tt.interval, tt.adaptive, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+// Package commands implements the CLI command system for the gator RSS aggregator.
+package commands
+
+import (
+	"fmt"
+	"strings"
+)
+
+// flagSpec lists the flags a command accepts.
+// The value reports whether the flag takes an argument (--name value)
+// or is a boolean switch (--name).
+type flagSpec map[string]bool
+
+// parseFlags separates --flag options from positional arguments.
+// Flags may appear anywhere in args and may be written as --name value or --name=value.
+// Boolean flags are stored with the value "true".
+//
+// Returns the positional arguments in order, the flags that were set,
+// or an error if an unknown flag is given or a value is missing.
+func parseFlags(args []string, spec flagSpec) ([]string, map[string]string, error) {
+	var positional []string
+	flags := make(map[string]string)
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "--") || arg == "--" {
+			positional = append(positional, arg)
+			continue
+		}
+
+		name, value, hasValue := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+		takesValue, known := spec[name]
+		if !known {
+			return nil, nil, fmt.Errorf("unknown flag: --%s", name)
+		}
+
+		switch {
+		case !takesValue && hasValue:
+			return nil, nil, fmt.Errorf("flag --%s does not take a value", name)
+		case !takesValue:
+			value = "true"
+		case !hasValue:
+			if i+1 >= len(args) {
+				return nil, nil, fmt.Errorf("flag --%s requires a value", name)
+			}
+			i++
+			value = args[i]
+		}
+		flags[name] = value
+	}
+
+	return positional, flags, nil
+}
+
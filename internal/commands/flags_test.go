@@ -0,0 +1,42 @@
+package commands
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestParseFlags(t *testing.T) {
+	spec := flagSpec{"folder": true, "saved": false}
+
+	tests := []struct {
+		args		[]string
+		positional	[]string
+		flags		map[string]string
+		wantErr		string
+	}{
+		{[]string{"10"}, []string{"10"}, map[string]string{}, ""},
+		{[]string{"--folder", "tech", "10"}, []string{"10"}, map[string]string{"folder": "tech"}, ""},
+		{[]string{"10", "--folder=tech", "--saved"}, []string{"10"}, map[string]string{"folder": "tech", "saved": "true"}, ""},
+		{[]string{"--folder="}, nil, map[string]string{"folder": ""}, ""},
+		{[]string{"--"}, []string{"--"}, map[string]string{}, ""},
+		{[]string{"--colour", "red"}, nil, nil, "unknown flag: --colour"},
+		{[]string{"--saved=yes"}, nil, nil, "flag --saved does not take a value"},
+		{[]string{"10", "--folder"}, nil, nil, "flag --folder requires a value"},
+	}
+	for _, tt := range tests {
+		positional, flags, err := parseFlags(tt.args, spec)
+		if tt.wantErr != "" {
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("parseFlags(%q) error = %v, want %q", tt.args, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseFlags(%q): %v", tt.args, err)
+			continue
+		}
+		if fmt.Sprint(positional) != fmt.Sprint(tt.positional) || fmt.Sprint(flags) != fmt.Sprint(tt.flags) {
+			t.Errorf("parseFlags(%q) = %q, %v; want %q, %v", tt.args, positional, flags, tt.positional, tt.flags)
+		}
+	}
+}
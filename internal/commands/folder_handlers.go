@@ -0,0 +1,159 @@
+// Package commands implements the CLI command system for the gator RSS aggregator.
+package commands
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nhdewitt/blog-aggregator/internal/app"
+	"github.com/nhdewitt/blog-aggregator/internal/database"
+)
+
+// handlerFolder dispatches the folder management subcommands.
+//
+// Usage: gator folder <create|list|rm> [name]
+func handlerFolder(ctx context.Context, s *app.State, cmd Command, user database.User) error {
+	if len(cmd.Args) < 1 {
+		return fmt.Errorf("usage: %s <create|list|rm> [name]", cmd.Name)
+	}
+	sub := Command{
+		Name: cmd.Name + " " + cmd.Args[0],
+		Args: cmd.Args[1:],
+	}
+
+	switch cmd.Args[0] {
+	case "create":
+		return handlerFolderCreate(ctx, s, sub, user)
+	case "list":
+		return handlerFolderList(ctx, s, sub, user)
+	case "rm":
+		return handlerFolderRemove(ctx, s, sub, user)
+	default:
+		return fmt.Errorf("unknown %s subcommand: %q", cmd.Name, cmd.Args[0])
+	}
+}
+
+// handlerFolderCreate creates a new folder for organizing the current user's feeds.
+// Folder names must be unique per user.
+//
+// Usage: gator folder create <name>
+func handlerFolderCreate(ctx context.Context, s *app.State, cmd Command, user database.User) error {
+	if len(cmd.Args) != 1 {
+		return fmt.Errorf("usage: %s <name>", cmd.Name)
+	}
+
+	folder, err := s.Db.CreateFolder(ctx, database.CreateFolderParams{
+		ID: uuid.New(),
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+		Name: cmd.Args[0],
+		UserID: user.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("Error creating folder: %w", err)
+	}
+
+	fmt.Printf("Folder %s created\n", folder.Name)
+	return nil
+}
+
+// handlerFolderList displays the current user's folders.
+//
+// Usage: gator folder list
+func handlerFolderList(ctx context.Context, s *app.State, cmd Command, user database.User) error {
+	if len(cmd.Args) != 0 {
+		return fmt.Errorf("usage: %s", cmd.Name)
+	}
+
+	folders, err := s.Db.GetFoldersForUser(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("Error getting folders: %w", err)
+	}
+
+	if len(folders) == 0 {
+		fmt.Println("You have no folders")
+	}
+	for _, folder := range folders {
+		fmt.Printf(" * %s\n", folder.Name)
+	}
+	return nil
+}
+
+// handlerFolderRemove deletes one of the current user's folders.
+// Feeds in the folder are kept and become unfiled.
+//
+// Usage: gator folder rm <name>
+func handlerFolderRemove(ctx context.Context, s *app.State, cmd Command, user database.User) error {
+	if len(cmd.Args) != 1 {
+		return fmt.Errorf("usage: %s <name>", cmd.Name)
+	}
+
+	deleted, err := s.Db.DeleteFolder(ctx, database.DeleteFolderParams{
+		UserID: user.ID,
+		Name: cmd.Args[0],
+	})
+	if err != nil {
+		return fmt.Errorf("Error deleting folder: %w", err)
+	}
+	if deleted == 0 {
+		return fmt.Errorf("Folder %s not found", cmd.Args[0])
+	}
+
+	fmt.Printf("Folder %s removed\n", cmd.Args[0])
+	return nil
+}
+
+// handlerMoveFeed moves a followed feed into one of the current user's folders.
+//
+// Usage: gator move <url> <folder>
+func handlerMoveFeed(ctx context.Context, s *app.State, cmd Command, user database.User) error {
+	if len(cmd.Args) != 2 {
+		return fmt.Errorf("usage: %s <url> <folder>", cmd.Name)
+	}
+	url := cmd.Args[0]
+
+	folderID, err := lookupFolder(ctx, s, user, cmd.Args[1])
+	if err != nil {
+		return err
+	}
+
+	moved, err := s.Db.SetFeedFollowFolder(ctx, database.SetFeedFollowFolderParams{
+		UserID: user.ID,
+		Url: url,
+		FolderID: folderID,
+	})
+	if err != nil {
+		return fmt.Errorf("Error moving feed: %w", err)
+	}
+	if moved == 0 {
+		return fmt.Errorf("You are not following %s", url)
+	}
+
+	fmt.Printf("Moved %s to %s\n", url, cmd.Args[1])
+	return nil
+}
+
+// lookupFolder resolves a folder name to its ID for the given user.
+// An empty name resolves to no folder.
+func lookupFolder(ctx context.Context, s *app.State, user database.User, name string) (uuid.NullUUID, error) {
+	if name == "" {
+		return uuid.NullUUID{}, nil
+	}
+
+	folder, err := s.Db.GetFolderByName(ctx, database.GetFolderByNameParams{
+		UserID: user.ID,
+		Name: name,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return uuid.NullUUID{}, fmt.Errorf("Folder %s not found (create it with: gator folder create %s)", name, name)
+	}
+	if err != nil {
+		return uuid.NullUUID{}, fmt.Errorf("Error finding folder: %w", err)
+	}
+
+	return uuid.NullUUID{UUID: folder.ID, Valid: true}, nil
+}
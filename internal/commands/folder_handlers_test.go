@@ -0,0 +1,69 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFolders(t *testing.T) {
+	s := newTestState(t)
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Blog", "https://blog.example/rss")
+
+	contains(t, mustRun(t, s, "folder", "list"), "You have no folders")
+	contains(t, mustRun(t, s, "folder", "create", "tech"), "Folder tech created")
+	contains(t, mustRun(t, s, "folder", "list"), " * tech")
+
+	contains(t, mustRun(t, s, "move", "https://blog.example/rss", "tech"), "Moved https://blog.example/rss to tech")
+	contains(t, mustRun(t, s, "following"), "\ntech/\n    Blog\n")
+
+	contains(t, mustRun(t, s, "folder", "rm", "tech"), "Folder tech removed")
+	mustFail(t, s, "Folder tech not found", "folder", "rm", "tech")
+}
+
+func TestFoldersArePerUser(t *testing.T) {
+	s := newTestState(t)
+	srv := newFeedServer(t)
+	tech := srv.serve("/tech.xml", rssDocument("Tech",
+		testItem{"Compilers", "https://tech.example/1", time.Now().Add(-time.Hour)},
+	))
+	food := srv.serve("/food.xml", rssDocument("Food",
+		testItem{"Bread", "https://food.example/1", time.Now().Add(-time.Hour)},
+	))
+
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Food", food)
+	mustRun(t, s, "folder", "create", "tech")
+	mustRun(t, s, "register", "bob")
+	mustRun(t, s, "addfeed", "Tech", tech)
+	mustRun(t, s, "follow", food)
+	mustRun(t, s, "agg", "--once")
+
+	// Folders belong to their user, so bob has no tech folder yet
+	mustFail(t, s, "Folder tech not found (create it with: gator folder create tech)", "move", tech, "tech")
+	contains(t, mustRun(t, s, "folder", "create", "tech"), "Folder tech created")
+	mustFail(t, s, "Error creating folder", "folder", "create", "tech")
+	mustRun(t, s, "move", tech, "tech")
+	mustFail(t, s, "You are not following", "move", "https://other.example/rss", "tech")
+
+	out := mustRun(t, s, "browse", "10", "--folder", "tech")
+	contains(t, out, "Title: Compilers")
+	if countTitles(out) != 1 {
+		t.Errorf("browse --folder tech showed %d posts, want only the tech feed's:\n%s", countTitles(out), out)
+	}
+	if n := countTitles(mustRun(t, s, "browse", "10")); n != 2 {
+		t.Errorf("browse showed %d posts, want both feeds'", n)
+	}
+
+	// Removing the folder leaves the feed followed but unfiled
+	mustRun(t, s, "folder", "rm", "tech")
+	out = mustRun(t, s, "following")
+	contains(t, out, "Tech")
+	if strings.Contains(out, "tech/") {
+		t.Errorf("following still shows the removed folder:\n%s", out)
+	}
+
+	mustRun(t, s, "login", "alice")
+	contains(t, mustRun(t, s, "folder", "list"), " * tech")
+}
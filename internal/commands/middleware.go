@@ -9,13 +9,24 @@ import (
 )
 
 // middlewareLoggedIn wraps command handlers that require user authentication.
-// Automatically fetches the current user and passes it to the handler.
-func middlewareLoggedIn(handler func(s *app.State, cmd Command, user database.User) error) func(*app.State, Command) error {
-	return func(s *app.State, cmd Command) error {
-		user, err := s.Db.GetUser(context.Background(), s.Cfg.CurrentUser)
+// Validates the session token from the config file and passes its user to the handler.
+func middlewareLoggedIn(handler func(ctx context.Context, s *app.State, cmd Command, user database.User) error) func(context.Context, *app.State, Command) error {
+	return func(ctx context.Context, s *app.State, cmd Command) error {
+		user, err := app.SessionUser(ctx, s, s.Cfg.SessionToken)
 		if err != nil {
 			return err
 		}
-		return handler(s, cmd, user)
+		return handler(ctx, s, cmd, user)
+	}
+}
+
+// middlewareAdmin wraps command handlers that only admins may run.
+// Use it inside middlewareLoggedIn, which supplies the user.
+func middlewareAdmin(handler func(ctx context.Context, s *app.State, cmd Command, user database.User) error) func(context.Context, *app.State, Command, database.User) error {
+	return func(ctx context.Context, s *app.State, cmd Command, user database.User) error {
+		if !app.IsAdmin(user) {
+			return app.ErrAdminOnly
+		}
+		return handler(ctx, s, cmd, user)
 	}
 }
\ No newline at end of file
@@ -0,0 +1,138 @@
+// Package commands implements the CLI command system for the gator RSS aggregator.
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nhdewitt/blog-aggregator/internal/app"
+	"github.com/nhdewitt/blog-aggregator/internal/store"
+)
+
+// handlerMigrate manages the database schema with the migrations built into
+// gator. It opens the database itself, since the other commands refuse to run
+// until the schema is up to date, so s.Db is not set.
+//
+// Usage: gator migrate <up|down [--yes]|status>
+func handlerMigrate(ctx context.Context, s *app.State, cmd Command) error {
+	usage := fmt.Errorf("usage: %s <up|down [--yes]|status>", cmd.Name)
+	args, flags, err := parseFlags(cmd.Args, flagSpec{"yes": false})
+	if err != nil || len(args) != 1 {
+		return usage
+	}
+	if flags["yes"] == "true" && args[0] != "down" {
+		return usage
+	}
+
+	dbURL, err := s.Cfg.DatabaseURL()
+	if err != nil {
+		return err
+	}
+	m, err := store.OpenMigrator(ctx, dbURL)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	switch args[0] {
+	case "up":
+		return handlerMigrateUp(ctx, m)
+	case "down":
+		return handlerMigrateDown(ctx, m, flags["yes"] == "true")
+	case "status":
+		return handlerMigrateStatus(ctx, m)
+	default:
+		return fmt.Errorf("unknown %s subcommand: %q", cmd.Name, args[0])
+	}
+}
+
+// handlerMigrateUp applies every pending migration.
+//
+// Usage: gator migrate up
+func handlerMigrateUp(ctx context.Context, m *store.Migrator) error {
+	applied, err := m.Up(ctx)
+	for _, name := range applied {
+		fmt.Printf(" * applied %s\n", name)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(applied) == 0 {
+		fmt.Println("The database schema is already up to date")
+	} else {
+		fmt.Printf("Applied %d migrations\n", len(applied))
+	}
+	return nil
+}
+
+// handlerMigrateDown rolls back the most recently applied migration. Rolling
+// back can drop tables along with their data, so it asks for confirmation
+// unless yes is set.
+//
+// Usage: gator migrate down [--yes]
+func handlerMigrateDown(ctx context.Context, m *store.Migrator, yes bool) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	latest := ""
+	for _, status := range statuses {
+		if status.Applied {
+			latest = status.Name
+		}
+	}
+	if latest == "" {
+		fmt.Println("No migrations to roll back")
+		return nil
+	}
+
+	if !yes {
+		fmt.Printf("This will roll back %s, which can drop tables and the data in them\n", latest)
+		confirmed, err := confirm("roll back a migration")
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			fmt.Println("Rollback cancelled, nothing was changed")
+			return nil
+		}
+	}
+
+	name, err := m.Down(ctx)
+	if err != nil {
+		return err
+	}
+
+	if name == "" {
+		fmt.Println("No migrations to roll back")
+	} else {
+		fmt.Printf("Rolled back %s\n", name)
+	}
+	return nil
+}
+
+// handlerMigrateStatus lists the migrations and whether each has been applied.
+//
+// Usage: gator migrate status
+func handlerMigrateStatus(ctx context.Context, m *store.Migrator) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	pending := 0
+	for _, status := range statuses {
+		switch {
+		case !status.Applied:
+			pending++
+			fmt.Printf(" * %-28s pending\n", status.Name)
+		case status.AppliedAt.IsZero():
+			fmt.Printf(" * %-28s applied\n", status.Name)
+		default:
+			fmt.Printf(" * %-28s applied %s\n", status.Name, status.AppliedAt.Local().Format("Jan 2, 2006 at 3:04 PM"))
+		}
+	}
+	fmt.Printf("%d of %d migrations applied\n", len(statuses)-pending, len(statuses))
+	return nil
+}
@@ -0,0 +1,26 @@
+package commands
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrate(t *testing.T) {
+	s := newTestState(t)
+	s.Cfg.DBUrl = "sqlite://" + filepath.Join(t.TempDir(), "gator.db")
+
+	contains(t, mustRun(t, s, "migrate", "status"), "pending", "0 of ")
+	contains(t, mustRun(t, s, "migrate", "up"), " * applied 0115_initial", " * applied 0116_undated_posts", "Applied 2 migrations")
+	contains(t, mustRun(t, s, "migrate", "up"), "already up to date")
+	contains(t, mustRun(t, s, "migrate", "status"), "2 of 2 migrations applied")
+
+	// Rolling back drops data, so without a terminal it needs --yes
+	mustFail(t, s, "refusing to roll back a migration without confirmation; pass --yes", "migrate", "down")
+	contains(t, mustRun(t, s, "migrate", "status"), "2 of 2 migrations applied")
+	contains(t, mustRun(t, s, "migrate", "down", "--yes"), "Rolled back 0116_undated_posts")
+	contains(t, mustRun(t, s, "migrate", "down", "--yes"), "Rolled back 0115_initial")
+	contains(t, mustRun(t, s, "migrate", "down"), "No migrations to roll back")
+
+	mustFail(t, s, `unknown migrate subcommand: "sideways"`, "migrate", "sideways")
+	mustFail(t, s, "usage", "migrate", "up", "--yes")
+}
@@ -0,0 +1,60 @@
+// Package commands implements the CLI command system for the gator RSS aggregator.
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// passwordEnv names the environment variable that supplies a password to
+// login and register without prompting, for scripts.
+const passwordEnv = "GATOR_PASSWORD"
+
+// readPassword gets a password from GATOR_PASSWORD, or prompts for it on the
+// terminal without echoing. When stdin isn't a terminal, one line is read from it.
+func readPassword(prompt string) (string, error) {
+	if password, ok := os.LookupEnv(passwordEnv); ok {
+		return password, nil
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return "", fmt.Errorf("Error reading password: %w", err)
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	password, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("Error reading password: %w", err)
+	}
+	return string(password), nil
+}
+
+// readNewPassword gets a password to set. Interactive users type it twice.
+func readNewPassword() (string, error) {
+	password, err := readPassword("New password: ")
+	if err != nil {
+		return "", err
+	}
+	if _, ok := os.LookupEnv(passwordEnv); ok || !term.IsTerminal(int(os.Stdin.Fd())) {
+		return password, nil
+	}
+
+	confirm, err := readPassword("Confirm password: ")
+	if err != nil {
+		return "", err
+	}
+	if confirm != password {
+		return "", fmt.Errorf("Passwords do not match")
+	}
+	return password, nil
+}
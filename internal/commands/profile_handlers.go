@@ -0,0 +1,101 @@
+// Package commands implements the CLI command system for the gator RSS aggregator.
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/nhdewitt/blog-aggregator/internal/app"
+)
+
+// handlerProfile manages the named profiles in the config file, each with
+// its own database, login and fetch settings.
+//
+// Usage: gator profile <use|list|add> [arguments...]
+func handlerProfile(ctx context.Context, s *app.State, cmd Command) error {
+	if len(cmd.Args) < 1 {
+		return fmt.Errorf("usage: %s <use|list|add> [arguments...]", cmd.Name)
+	}
+	sub := Command{
+		Name: cmd.Name + " " + cmd.Args[0],
+		Args: cmd.Args[1:],
+	}
+
+	switch cmd.Args[0] {
+	case "use":
+		return handlerProfileUse(ctx, s, sub)
+	case "list":
+		return handlerProfileList(ctx, s, sub)
+	case "add":
+		return handlerProfileAdd(ctx, s, sub)
+	default:
+		return fmt.Errorf("unknown %s subcommand: %q", cmd.Name, cmd.Args[0])
+	}
+}
+
+// handlerProfileUse switches to another profile for the commands that follow.
+// --profile and $GATOR_PROFILE still choose a profile for a single command.
+//
+// Usage: gator profile use <name>
+func handlerProfileUse(ctx context.Context, s *app.State, cmd Command) error {
+	if len(cmd.Args) != 1 {
+		return fmt.Errorf("usage: %s <name>", cmd.Name)
+	}
+
+	if err := s.Cfg.UseProfile(cmd.Args[0]); err != nil {
+		return err
+	}
+	fmt.Printf("Now using profile %s\n", cmd.Args[0])
+	return nil
+}
+
+// handlerProfileList displays the profiles, marking the one in use.
+//
+// Usage: gator profile list
+func handlerProfileList(ctx context.Context, s *app.State, cmd Command) error {
+	if len(cmd.Args) != 0 {
+		return fmt.Errorf("usage: %s", cmd.Name)
+	}
+
+	profiles, err := s.Cfg.Profiles()
+	if err != nil {
+		return err
+	}
+
+	for _, p := range profiles {
+		marker := " "
+		if p.Active {
+			marker = "*"
+		}
+		user := p.CurrentUser
+		if user == "" {
+			user = "not logged in"
+		}
+		fmt.Printf(" %s %s\n", marker, p.Name)
+		fmt.Printf("     Database:\t%s\n", p.DBUrl)
+		fmt.Printf("     User:\t%s\n", user)
+	}
+	return nil
+}
+
+// handlerProfileAdd creates a profile for another database. Its fetch
+// settings start out empty; set them with `gator --profile <name> config set`.
+//
+// Usage: gator profile add <name> <db_url>
+func handlerProfileAdd(ctx context.Context, s *app.State, cmd Command) error {
+	if len(cmd.Args) != 2 {
+		return fmt.Errorf("usage: %s <name> <db_url>", cmd.Name)
+	}
+	name, dbURL := cmd.Args[0], cmd.Args[1]
+	if scheme, _, _ := strings.Cut(dbURL, ":"); !isDatabaseScheme(scheme) {
+		return fmt.Errorf("The database URL must start with sqlite:// or postgres://")
+	}
+
+	if err := s.Cfg.AddProfile(name, dbURL); err != nil {
+		return err
+	}
+	fmt.Printf("Profile %s added\n", name)
+	fmt.Printf("Switch to it with 'gator profile use %s', or try it with 'gator --profile %s <command>'\n", name, name)
+	return nil
+}
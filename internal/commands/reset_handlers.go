@@ -0,0 +1,229 @@
+// Package commands implements the CLI command system for the gator RSS aggregator.
+package commands
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nhdewitt/blog-aggregator/internal/app"
+	"github.com/nhdewitt/blog-aggregator/internal/database"
+	"golang.org/x/term"
+)
+
+// snapshotDir is where reset saves snapshots, relative to the home directory.
+const snapshotDir = ".gator/snapshots"
+
+// handlerReset deletes data from the database after saving a JSON snapshot of
+// it that `gator restore` can put back. Without flags every user is deleted,
+// and with them all feeds, follows and posts.
+//
+// --posts-only deletes only posts, and --older-than limits that to posts
+// published more than the given age ago (e.g. 720h or 30d). --user deletes one
+// user and their folders, follows, rules and read markers; feeds they added
+// are handed to the admin running the reset.
+//
+// The command shows what will be deleted and asks for confirmation, or
+// proceeds without asking when --yes is given. It is restricted to admins.
+//
+// Usage: gator reset [--posts-only] [--older-than <age>] [--user <name>] [--yes]
+func handlerReset(ctx context.Context, s *app.State, cmd Command, admin database.User) error {
+	usage := fmt.Errorf("usage: %s [--posts-only] [--older-than <age>] [--user <name>] [--yes]", cmd.Name)
+
+	args, flags, err := parseFlags(cmd.Args, flagSpec{"posts-only": false, "older-than": true, "user": true, "yes": false})
+	if err != nil || len(args) != 0 {
+		return usage
+	}
+
+	var scope app.ResetScope
+	reason := []string{"reset"}
+	if flags["posts-only"] == "true" {
+		scope.PostsOnly = true
+		reason = append(reason, "--posts-only")
+	}
+	if age, ok := flags["older-than"]; ok {
+		d, err := app.ParseAge(age)
+		if err != nil {
+			return fmt.Errorf("Invalid --older-than: %w", err)
+		}
+		// Only posts have an age worth resetting by
+		scope.PostsOnly = true
+		scope.Before = time.Now().UTC().Add(-d)
+		reason = append(reason, "--older-than", age)
+	}
+	if name, ok := flags["user"]; ok {
+		if scope.PostsOnly {
+			return fmt.Errorf("--user can't be combined with --posts-only or --older-than")
+		}
+		user, err := s.Db.GetUser(ctx, name)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("User %s not found", name)
+		}
+		if err != nil {
+			return fmt.Errorf("Error getting user: %w", err)
+		}
+		if user.ID == admin.ID {
+			return fmt.Errorf("You can't reset your own account")
+		}
+		scope.User = &user
+		reason = append(reason, "--user", name)
+	}
+
+	snapshotPath, err := newSnapshotPath()
+	if err != nil {
+		return err
+	}
+
+	_, err = app.Reset(ctx, s, scope, admin, func(snap *app.Snapshot) error {
+		snap.Reason = strings.Join(reason, " ")
+		printSnapshotSummary("This will delete:", snap)
+
+		if flags["yes"] != "true" {
+			confirmed, err := confirm("reset")
+			if err != nil {
+				return err
+			}
+			if !confirmed {
+				return app.ErrResetCancelled
+			}
+		}
+		return app.WriteSnapshot(snapshotPath, snap)
+	})
+	if errors.Is(err, app.ErrResetCancelled) {
+		fmt.Println("Reset cancelled, nothing was deleted")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Couldn't reset database: %w", err)
+	}
+
+	fmt.Println("Database reset")
+	fmt.Printf("Snapshot saved to %s\n", snapshotPath)
+	fmt.Printf("Undo with: gator restore %s\n", snapshotPath)
+	return nil
+}
+
+// handlerBackup writes every user, feed, follow and post plus each user's
+// folders, filter rules and read markers to a compressed archive that
+// `gator restore` can load into this or another database. Restricted to admins,
+// since the archive includes password hashes.
+//
+// Usage: gator backup <file>
+func handlerBackup(ctx context.Context, s *app.State, cmd Command, user database.User) error {
+	if len(cmd.Args) != 1 {
+		return fmt.Errorf("usage: %s <file>", cmd.Name)
+	}
+
+	counts, err := app.WriteBackup(ctx, s, cmd.Args[0])
+	if err != nil {
+		return fmt.Errorf("Couldn't write backup: %w", err)
+	}
+
+	for _, count := range counts {
+		fmt.Printf(" * %-13s %d\n", count.Table+":", count.Restored)
+	}
+	fmt.Printf("Backup saved to %s\n", cmd.Args[0])
+	return nil
+}
+
+// handlerRestore loads a backup archive or a reset snapshot, keeping the
+// original IDs. Rows that already exist are skipped, so it is safe to run twice.
+//
+// Restoring is restricted to admins, except on an empty database where nobody
+// could log in yet.
+//
+// Usage: gator restore <file>
+func handlerRestore(ctx context.Context, s *app.State, cmd Command) error {
+	if len(cmd.Args) != 1 {
+		return fmt.Errorf("usage: %s <file>", cmd.Name)
+	}
+
+	if err := requireAdminUnlessEmpty(ctx, s); err != nil {
+		return err
+	}
+
+	counts, err := app.RestoreFile(ctx, s, cmd.Args[0])
+	if err != nil {
+		return fmt.Errorf("Couldn't restore %s: %w", cmd.Args[0], err)
+	}
+
+	for _, count := range counts {
+		fmt.Printf(" * %-13s %d restored, %d skipped\n", count.Table+":", count.Restored, count.Skipped)
+	}
+	fmt.Println("Restore complete")
+	return nil
+}
+
+// requireAdminUnlessEmpty checks that the logged-in user is an admin, unless
+// there are no users at all.
+func requireAdminUnlessEmpty(ctx context.Context, s *app.State) error {
+	users, err := s.Db.GetUsers(ctx)
+	if err != nil {
+		return fmt.Errorf("Error getting users: %w", err)
+	}
+	if len(users) == 0 {
+		return nil
+	}
+
+	user, err := app.SessionUser(ctx, s, s.Cfg.SessionToken)
+	if err != nil {
+		return err
+	}
+	if !app.IsAdmin(user) {
+		return app.ErrAdminOnly
+	}
+	return nil
+}
+
+// confirm asks the user to type "yes" on the terminal before action goes ahead.
+// Returns false for any other answer, or an error when there is no terminal
+// to ask on.
+func confirm(action string) (bool, error) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return false, fmt.Errorf("refusing to %s without confirmation; pass --yes to run non-interactively", action)
+	}
+
+	fmt.Print("Type 'yes' to continue: ")
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false, nil
+	}
+	return strings.TrimSpace(answer) == "yes", nil
+}
+
+// printSnapshotSummary shows how many rows of each kind a snapshot holds.
+func printSnapshotSummary(heading string, snap *app.Snapshot) {
+	fmt.Println(heading)
+	fmt.Printf(" * Users:\t\t%d\n", len(snap.Users))
+	fmt.Printf(" * Folders:\t\t%d\n", len(snap.Folders))
+	fmt.Printf(" * Feeds:\t\t%d\n", len(snap.Feeds))
+	fmt.Printf(" * Follows:\t\t%d\n", len(snap.FeedFollows))
+	fmt.Printf(" * Posts:\t\t%d\n", len(snap.Posts))
+	fmt.Printf(" * Filter rules:\t%d\n", len(snap.FilterRules))
+	fmt.Printf(" * Read markers:\t%d\n", len(snap.PostReads))
+	fmt.Printf(" * Saved posts:\t%d\n", len(snap.SavedPosts))
+}
+
+// newSnapshotPath returns a fresh, timestamped file name in the snapshot directory.
+// A numbered suffix keeps resets within the same second from colliding.
+func newSnapshotPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("Error finding home directory: %w", err)
+	}
+	stamp := time.Now().UTC().Format("20060102-150405")
+	path := filepath.Join(home, snapshotDir, fmt.Sprintf("reset-%s.json", stamp))
+	for n := 2; ; n++ {
+		// Anything but an existing file is left for WriteSnapshot to report
+		if _, err := os.Stat(path); err != nil {
+			return path, nil
+		}
+		path = filepath.Join(home, snapshotDir, fmt.Sprintf("reset-%s-%d.json", stamp, n))
+	}
+}
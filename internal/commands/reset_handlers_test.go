@@ -0,0 +1,128 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nhdewitt/blog-aggregator/internal/app"
+	"github.com/nhdewitt/blog-aggregator/internal/database"
+)
+
+func TestResetAndRestoreSnapshot(t *testing.T) {
+	s := newTestState(t)
+	srv := newFeedServer(t)
+	url := srv.serve("/feed.xml", rssDocument("Blog",
+		testItem{"Post", "https://blog.example/1", time.Now().Add(-time.Hour)},
+	))
+
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Blog", url)
+	mustRun(t, s, "refresh", url)
+
+	out := mustRun(t, s, "reset", "--posts-only", "--yes")
+	contains(t, out, "Posts:\t\t1", "Database reset", "Undo with: gator restore ")
+	snapshot := strings.TrimSpace(out[strings.LastIndex(out, "gator restore ")+len("gator restore "):])
+	if countTitles(mustRun(t, s, "browse")) != 0 {
+		t.Fatal("posts left after reset --posts-only")
+	}
+
+	contains(t, mustRun(t, s, "restore", snapshot), "Restore complete")
+	contains(t, mustRun(t, s, "browse"), "Title: Post")
+}
+
+func TestResetScopes(t *testing.T) {
+	s := newTestState(t)
+	srv := newFeedServer(t)
+	now := time.Now()
+	url := srv.serve("/feed.xml", rssDocument("Blog",
+		testItem{"Fresh", "https://blog.example/fresh", now.Add(-time.Hour)},
+		testItem{"Stale", "https://blog.example/stale", now.Add(-60 * 24 * time.Hour)},
+	))
+
+	mustRun(t, s, "register", "admin")
+	mustRun(t, s, "addfeed", "Blog", url)
+	mustRun(t, s, "refresh", url)
+	mustRun(t, s, "register", "carol")
+	mustRun(t, s, "follow", url)
+	mustRun(t, s, "login", "admin")
+
+	tests := []struct {
+		args	[]string
+		want	[]string
+	}{
+		{[]string{"--older-than", "30d", "--yes"}, []string{"Users:\t\t0", "Posts:\t\t1", "Database reset"}},
+		{[]string{"--user", "carol", "--yes"}, []string{"Users:\t\t1", "Follows:\t\t1", "Posts:\t\t0", "Database reset"}},
+		{[]string{"--posts-only", "--yes"}, []string{"Users:\t\t0", "Feeds:\t\t0", "Posts:\t\t1", "Database reset"}},
+	}
+	for _, tt := range tests {
+		contains(t, mustRun(t, s, append([]string{"reset"}, tt.args...)...), tt.want...)
+	}
+	contains(t, mustRun(t, s, "users"), " * admin [admin] (current)\n")
+	contains(t, mustRun(t, s, "feeds"), "Feed:\tBlog")
+	if n := countTitles(mustRun(t, s, "browse", "10")); n != 0 {
+		t.Errorf("browse shows %d posts after resetting them all", n)
+	}
+}
+
+func TestResetRefuses(t *testing.T) {
+	s := newTestState(t)
+	mustRun(t, s, "register", "admin")
+
+	tests := []struct {
+		args	[]string
+		want	string
+	}{
+		{[]string{"--older-than", "soon", "--yes"}, "Invalid --older-than"},
+		{[]string{"--user", "admin", "--posts-only", "--yes"}, "--user can't be combined"},
+		{[]string{"--user", "admin", "--older-than", "30d", "--yes"}, "--user can't be combined"},
+		{[]string{"--user", "admin", "--yes"}, "You can't reset your own account"},
+		{[]string{"--user", "nobody", "--yes"}, "User nobody not found"},
+		{[]string{"everything"}, "usage"},
+		// Tests don't run on a terminal, so there is nobody to confirm
+		{nil, "pass --yes to run non-interactively"},
+	}
+	for _, tt := range tests {
+		mustFail(t, s, tt.want, append([]string{"reset"}, tt.args...)...)
+	}
+	contains(t, mustRun(t, s, "users"), " * admin [admin] (current)\n")
+
+	// A failing database isn't mistaken for a missing user
+	s.Db = unreachableUserStore{s.Db}
+	mustFail(t, s, "Error getting user: connection reset", "reset", "--user", "nobody", "--yes")
+}
+
+// unreachableUserStore fails to look up users by name.
+type unreachableUserStore struct {
+	app.Store
+}
+
+func (unreachableUserStore) GetUser(ctx context.Context, name string) (database.User, error) {
+	return database.User{}, errors.New("connection reset")
+}
+
+func TestBackupAndRestore(t *testing.T) {
+	s := newTestState(t)
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Blog", "https://blog.example/rss")
+
+	backup := filepath.Join(t.TempDir(), "gator.backup")
+	contains(t, mustRun(t, s, "backup", backup), "Backup saved to "+backup)
+	if _, err := os.Stat(backup); err != nil {
+		t.Fatalf("backup file: %v", err)
+	}
+
+	// Restore into an empty database, where nobody has to be logged in
+	fresh := newTestState(t)
+	out := mustRun(t, fresh, "restore", backup)
+	contains(t, out, "users:        1 restored, 0 skipped", "feeds:        1 restored, 0 skipped", "Restore complete")
+
+	// Now that it has users, only an admin may restore, and existing rows are skipped
+	mustFail(t, fresh, "not logged in", "restore", backup)
+	mustRun(t, fresh, "login", "alice")
+	contains(t, mustRun(t, fresh, "restore", backup), "users:        0 restored, 1 skipped")
+}
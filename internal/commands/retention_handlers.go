@@ -0,0 +1,234 @@
+// Package commands implements the CLI command system for the gator RSS aggregator.
+package commands
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/nhdewitt/blog-aggregator/internal/app"
+	"github.com/nhdewitt/blog-aggregator/internal/database"
+)
+
+// defaultPruneInterval is how often agg prunes posts when the config doesn't say.
+const defaultPruneInterval = time.Hour
+
+// handlerPrune deletes posts that fall outside the retention policy: the global
+// limits from the config, overridden per feed with `gator feed set-max-age` and
+// `gator feed set-max-posts`. Saved posts are never deleted.
+//
+// Usage: gator prune
+func handlerPrune(ctx context.Context, s *app.State, cmd Command) error {
+	if len(cmd.Args) != 0 {
+		return fmt.Errorf("usage: %s", cmd.Name)
+	}
+
+	report, err := prunePosts(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	for _, feed := range report.Feeds {
+		fmt.Printf(" * %s: %d too old, %d over the limit\n", feed.URL, feed.ByAge, feed.ByCount)
+	}
+	fmt.Printf("Removed %d posts\n", report.Total)
+	return nil
+}
+
+// prunePosts applies the configured retention policy to every feed.
+func prunePosts(ctx context.Context, s *app.State) (app.PruneReport, error) {
+	policy, err := app.RetentionFromConfig(s.Cfg.Retention)
+	if err != nil {
+		return app.PruneReport{}, err
+	}
+	report, err := app.Prune(ctx, s, policy)
+	if err != nil {
+		return report, fmt.Errorf("Error pruning posts: %w", err)
+	}
+	return report, nil
+}
+
+// pruneInterval returns how often agg should prune, from the config.
+func pruneInterval(s *app.State) (time.Duration, error) {
+	if s.Cfg.Retention.PruneInterval == "" {
+		return defaultPruneInterval, nil
+	}
+	d, err := app.ParseAge(s.Cfg.Retention.PruneInterval)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid retention prune_interval: %w", err)
+	}
+	return d, nil
+}
+
+// handlerFeedSetMaxAge sets how long a feed's posts are kept, overriding the
+// global max_age. "forever" disables the limit for the feed and "default"
+// returns to the global setting. Only the feed's creator or an admin may change it.
+//
+// Usage: gator feed set-max-age <url> <age|forever|default>
+func handlerFeedSetMaxAge(ctx context.Context, s *app.State, cmd Command, user database.User) error {
+	if len(cmd.Args) != 2 {
+		return fmt.Errorf("usage: %s <url> <age|forever|default>", cmd.Name)
+	}
+
+	var maxAge sql.NullInt32
+	switch cmd.Args[1] {
+	case "default":
+	case "forever":
+		maxAge = sql.NullInt32{Int32: 0, Valid: true}
+	default:
+		d, err := app.ParseAge(cmd.Args[1])
+		if err != nil || d < time.Second {
+			return fmt.Errorf("Please enter an age such as 30d or 720h, or forever or default")
+		}
+		// The age is stored in seconds as a 32-bit integer
+		if d/time.Second > math.MaxInt32 {
+			return fmt.Errorf("Maximum age can be at most %s", time.Duration(math.MaxInt32)*time.Second)
+		}
+		maxAge = sql.NullInt32{Int32: int32(d / time.Second), Valid: true}
+	}
+
+	feed, err := findManagedFeed(ctx, s, cmd.Args[0], user)
+	if err != nil {
+		return err
+	}
+
+	err = s.Db.SetFeedRetention(ctx, database.SetFeedRetentionParams{
+		ID: feed.ID,
+		RetentionMaxAge: maxAge,
+		RetentionMaxPosts: feed.RetentionMaxPosts,
+	})
+	if err != nil {
+		return fmt.Errorf("Error updating feed: %w", err)
+	}
+
+	fmt.Printf("Maximum post age for %s is now %s\n", feed.Name, cmd.Args[1])
+	return nil
+}
+
+// handlerFeedSetMaxPosts sets how many of a feed's newest posts are kept,
+// overriding the global max_posts_per_feed. "forever" disables the limit for
+// the feed and "default" returns to the global setting. Only the feed's
+// creator or an admin may change it.
+//
+// Usage: gator feed set-max-posts <url> <count|forever|default>
+func handlerFeedSetMaxPosts(ctx context.Context, s *app.State, cmd Command, user database.User) error {
+	if len(cmd.Args) != 2 {
+		return fmt.Errorf("usage: %s <url> <count|forever|default>", cmd.Name)
+	}
+
+	var maxPosts sql.NullInt32
+	switch cmd.Args[1] {
+	case "default":
+	case "forever":
+		maxPosts = sql.NullInt32{Int32: 0, Valid: true}
+	default:
+		n, err := strconv.ParseInt(cmd.Args[1], 10, 32)
+		if err != nil || n < 1 {
+			return fmt.Errorf("Please enter a positive number of posts, or forever or default")
+		}
+		maxPosts = sql.NullInt32{Int32: int32(n), Valid: true}
+	}
+
+	feed, err := findManagedFeed(ctx, s, cmd.Args[0], user)
+	if err != nil {
+		return err
+	}
+
+	err = s.Db.SetFeedRetention(ctx, database.SetFeedRetentionParams{
+		ID: feed.ID,
+		RetentionMaxAge: feed.RetentionMaxAge,
+		RetentionMaxPosts: maxPosts,
+	})
+	if err != nil {
+		return fmt.Errorf("Error updating feed: %w", err)
+	}
+
+	fmt.Printf("Maximum number of posts for %s is now %s\n", feed.Name, cmd.Args[1])
+	return nil
+}
+
+// handlerSavePost saves a post for the current user. Saved posts are never
+// removed by pruning and can be listed with `gator browse --saved`.
+//
+// Usage: gator save <post-url>
+func handlerSavePost(ctx context.Context, s *app.State, cmd Command, user database.User) error {
+	if len(cmd.Args) != 1 {
+		return fmt.Errorf("usage: %s <post-url>", cmd.Name)
+	}
+
+	post, err := findPost(ctx, s, cmd.Args[0])
+	if err != nil {
+		return err
+	}
+
+	err = s.Db.SavePost(ctx, database.SavePostParams{UserID: user.ID, PostID: post.ID})
+	if err != nil {
+		return fmt.Errorf("Error saving post: %w", err)
+	}
+
+	fmt.Printf("Saved %s\n", post.Title)
+	return nil
+}
+
+// handlerUnsavePost removes a post from the current user's saved posts.
+//
+// Usage: gator unsave <post-url>
+func handlerUnsavePost(ctx context.Context, s *app.State, cmd Command, user database.User) error {
+	if len(cmd.Args) != 1 {
+		return fmt.Errorf("usage: %s <post-url>", cmd.Name)
+	}
+
+	post, err := findPost(ctx, s, cmd.Args[0])
+	if err != nil {
+		return err
+	}
+
+	removed, err := s.Db.UnsavePost(ctx, database.UnsavePostParams{UserID: user.ID, PostID: post.ID})
+	if err != nil {
+		return fmt.Errorf("Error unsaving post: %w", err)
+	}
+	if removed == 0 {
+		return fmt.Errorf("%s is not saved", post.Title)
+	}
+
+	fmt.Printf("Unsaved %s\n", post.Title)
+	return nil
+}
+
+// findPost looks up a post by its URL.
+func findPost(ctx context.Context, s *app.State, url string) (database.Post, error) {
+	post, err := s.Db.GetPostByURL(ctx, url)
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.Post{}, fmt.Errorf("Post %s not found", url)
+	}
+	if err != nil {
+		return database.Post{}, fmt.Errorf("Error finding post: %w", err)
+	}
+	return post, nil
+}
+
+// formatRetention describes a feed's retention limits for display.
+func formatRetention(maxAge, maxPosts sql.NullInt32) string {
+	describe := func(limit sql.NullInt32, format func(int32) string) string {
+		switch {
+		case !limit.Valid:
+			return "default"
+		case limit.Int32 == 0:
+			return "forever"
+		default:
+			return format(limit.Int32)
+		}
+	}
+
+	age := describe(maxAge, func(seconds int32) string {
+		return (time.Duration(seconds) * time.Second).String()
+	})
+	count := describe(maxPosts, func(n int32) string {
+		return strconv.Itoa(int(n)) + " posts"
+	})
+	return fmt.Sprintf("max age %s, max %s", age, count)
+}
@@ -0,0 +1,63 @@
+package commands
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPruneKeepsSavedPosts(t *testing.T) {
+	s := newTestState(t)
+	srv := newFeedServer(t)
+	now := time.Now()
+	url := srv.serve("/feed.xml", rssDocument("Blog",
+		testItem{"Fresh", "https://blog.example/fresh", now.Add(-time.Hour)},
+		testItem{"Stale", "https://blog.example/stale", now.Add(-60 * 24 * time.Hour)},
+		testItem{"Ancient", "https://blog.example/ancient", now.Add(-90 * 24 * time.Hour)},
+	))
+
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Blog", url)
+	mustRun(t, s, "refresh", url)
+
+	contains(t, mustRun(t, s, "save", "https://blog.example/ancient"), "Saved Ancient")
+	contains(t, mustRun(t, s, "browse", "--saved"), "[saved] Ancient")
+
+	mustRun(t, s, "config", "set", "retention.max_age", "30d")
+	out := mustRun(t, s, "prune")
+	contains(t, out, url+": 1 too old, 0 over the limit", "Removed 1 posts")
+
+	out = mustRun(t, s, "browse", "10")
+	contains(t, out, "Fresh", "Ancient")
+	if countTitles(out) != 2 {
+		t.Errorf("browse after prune:\n%s\nwant Fresh and the saved Ancient only", out)
+	}
+
+	contains(t, mustRun(t, s, "unsave", "https://blog.example/ancient"), "Unsaved Ancient")
+	mustFail(t, s, "is not saved", "unsave", "https://blog.example/ancient")
+	mustFail(t, s, "Post https://blog.example/nope not found", "save", "https://blog.example/nope")
+	contains(t, mustRun(t, s, "prune"), "Removed 1 posts")
+}
+
+func TestSetMaxAgeRejects(t *testing.T) {
+	s := newTestState(t)
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Blog", "https://blog.example/rss")
+
+	tests := []struct {
+		age		string
+		want	string
+	}{
+		{"someday", "Please enter an age"},
+		{"500ms", "Please enter an age"},
+		{"-30d", "Please enter an age"},
+		{"200000d", "Please enter an age"},
+		{"24856d", "Maximum age can be at most 596523h14m7s"},
+		{"600000h", "Maximum age can be at most 596523h14m7s"},
+	}
+	for _, tt := range tests {
+		mustFail(t, s, tt.want, "feed", "set-max-age", "https://blog.example/rss", tt.age)
+	}
+	// The longest age that fits is still accepted
+	contains(t, mustRun(t, s, "feed", "set-max-age", "https://blog.example/rss", "24855d"), "Maximum post age for Blog is now 24855d")
+	contains(t, mustRun(t, s, "feeds"), "max age 596520h0m0s")
+}
@@ -0,0 +1,172 @@
+// Package commands implements the CLI command system for the gator RSS aggregator.
+package commands
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nhdewitt/blog-aggregator/internal/app"
+	"github.com/nhdewitt/blog-aggregator/internal/database"
+)
+
+// ruleActions maps the action names accepted on the command line to stored actions.
+var ruleActions = map[string]string{
+	"hide":			app.RuleActionHide,
+	"highlight":	app.RuleActionHighlight,
+	"mark-read":	app.RuleActionMarkRead,
+}
+
+// handlerRule dispatches the filter rule subcommands.
+//
+// Usage: gator rule <add|list|rm> [arguments...]
+func handlerRule(ctx context.Context, s *app.State, cmd Command, user database.User) error {
+	if len(cmd.Args) < 1 {
+		return fmt.Errorf("usage: %s <add|list|rm> [arguments...]", cmd.Name)
+	}
+	sub := Command{
+		Name: cmd.Name + " " + cmd.Args[0],
+		Args: cmd.Args[1:],
+	}
+
+	switch cmd.Args[0] {
+	case "add":
+		return handlerRuleAdd(ctx, s, sub, user)
+	case "list":
+		return handlerRuleList(ctx, s, sub, user)
+	case "rm":
+		return handlerRuleRemove(ctx, s, sub, user)
+	default:
+		return fmt.Errorf("unknown %s subcommand: %q", cmd.Name, cmd.Args[0])
+	}
+}
+
+// handlerRuleAdd creates a filter rule for the current user.
+// Keyword patterns match case-insensitively; --regex treats the pattern as a regular expression.
+// Rules apply to every followed feed unless --feed limits them to one.
+//
+// Usage: gator rule add <hide|highlight|mark-read> <pattern> [--regex] [--feed <url>] [--field <any|title|author>]
+func handlerRuleAdd(ctx context.Context, s *app.State, cmd Command, user database.User) error {
+	usage := fmt.Errorf("usage: %s <hide|highlight|mark-read> <pattern> [--regex] [--feed <url>] [--field <any|title|author>]", cmd.Name)
+
+	args, flags, err := parseFlags(cmd.Args, flagSpec{"regex": false, "feed": true, "field": true})
+	if err != nil || len(args) != 2 {
+		return usage
+	}
+
+	action, ok := ruleActions[args[0]]
+	if !ok {
+		return usage
+	}
+	pattern := args[1]
+
+	isRegex := flags["regex"] == "true"
+	if isRegex {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("Invalid regular expression: %w", err)
+		}
+	}
+
+	field := app.RuleFieldAny
+	if f, ok := flags["field"]; ok {
+		switch f {
+		case app.RuleFieldAny, app.RuleFieldTitle, app.RuleFieldAuthor:
+			field = f
+		default:
+			return usage
+		}
+	}
+
+	var feedID uuid.NullUUID
+	if url, ok := flags["feed"]; ok {
+		feed, err := s.Db.FindFeedsByURL(ctx, url)
+		if err != nil {
+			return fmt.Errorf("Error finding feed: %w", err)
+		}
+		feedID = uuid.NullUUID{UUID: feed.ID, Valid: true}
+	}
+
+	rule, err := s.Db.CreateFilterRule(ctx, database.CreateFilterRuleParams{
+		ID: uuid.New(),
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+		UserID: user.ID,
+		FeedID: feedID,
+		Pattern: pattern,
+		IsRegex: isRegex,
+		Field: field,
+		Action: action,
+	})
+	if err != nil {
+		return fmt.Errorf("Error creating rule: %w", err)
+	}
+
+	fmt.Printf("Rule %s added\n", rule.ID)
+	return nil
+}
+
+// handlerRuleList displays the current user's filter rules.
+//
+// Usage: gator rule list
+func handlerRuleList(ctx context.Context, s *app.State, cmd Command, user database.User) error {
+	if len(cmd.Args) != 0 {
+		return fmt.Errorf("usage: %s", cmd.Name)
+	}
+
+	rules, err := s.Db.GetFilterRulesForUser(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("Error getting rules: %w", err)
+	}
+
+	if len(rules) == 0 {
+		fmt.Println("You have no filter rules")
+	}
+	for _, rule := range rules {
+		kind := "keyword"
+		if rule.IsRegex {
+			kind = "regex"
+		}
+		scope := "all feeds"
+		if rule.FeedUrl.Valid {
+			scope = rule.FeedUrl.String
+		}
+
+		fmt.Printf(" * ID:\t\t%s\n", rule.ID)
+		fmt.Printf(" * Action:\t%s\n", strings.ReplaceAll(rule.Action, "_", "-"))
+		fmt.Printf(" * Pattern:\t%s (%s, %s)\n", rule.Pattern, kind, rule.Field)
+		fmt.Printf(" * Scope:\t%s\n", scope)
+		fmt.Println()
+	}
+	return nil
+}
+
+// handlerRuleRemove deletes one of the current user's filter rules by ID.
+//
+// Usage: gator rule rm <id>
+func handlerRuleRemove(ctx context.Context, s *app.State, cmd Command, user database.User) error {
+	if len(cmd.Args) != 1 {
+		return fmt.Errorf("usage: %s <id>", cmd.Name)
+	}
+
+	id, err := uuid.Parse(cmd.Args[0])
+	if err != nil {
+		return fmt.Errorf("Invalid rule ID: %w", err)
+	}
+
+	deleted, err := s.Db.DeleteFilterRule(ctx, database.DeleteFilterRuleParams{
+		ID: id,
+		UserID: user.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("Error deleting rule: %w", err)
+	}
+	if deleted == 0 {
+		return fmt.Errorf("Rule %s not found", id)
+	}
+
+	fmt.Println("Rule removed")
+	return nil
+}
@@ -0,0 +1,57 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRules(t *testing.T) {
+	s := newTestState(t)
+	mustRun(t, s, "register", "alice")
+
+	contains(t, mustRun(t, s, "rule", "list"), "You have no filter rules")
+	out := mustRun(t, s, "rule", "add", "hide", "sponsored")
+	contains(t, out, "Rule ", " added")
+	id := strings.Fields(out)[1]
+
+	contains(t, mustRun(t, s, "rule", "list"), "Action:\thide", "Pattern:\tsponsored (keyword, any)", "Scope:\tall feeds")
+	contains(t, mustRun(t, s, "rule", "rm", id), "Rule removed")
+	mustFail(t, s, "not found", "rule", "rm", id)
+}
+
+func TestRulesFilterBrowse(t *testing.T) {
+	s := newTestState(t)
+	srv := newFeedServer(t)
+	url := srv.serve("/feed.xml", rssDocument("Blog",
+		testItem{"Sponsored: buy things", "https://blog.example/1", time.Now().Add(-3 * time.Hour)},
+		testItem{"Go generics explained", "https://blog.example/2", time.Now().Add(-2 * time.Hour)},
+		testItem{"Weekly links", "https://blog.example/3", time.Now().Add(-time.Hour)},
+	))
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Blog", url)
+	mustRun(t, s, "refresh", url)
+
+	mustRun(t, s, "rule", "add", "hide", "sponsored")
+	mustRun(t, s, "rule", "add", "highlight", "^Go ", "--regex", "--field", "title")
+	mustRun(t, s, "rule", "add", "mark-read", "weekly", "--feed", url)
+
+	out := mustRun(t, s, "browse", "10")
+	contains(t, out, "Title: [!] Go generics explained", "Title: [read] Weekly links")
+	if strings.Contains(out, "Sponsored") {
+		t.Errorf("browse shows a hidden post:\n%s", out)
+	}
+
+	tests := []struct {
+		args	[]string
+		want	string
+	}{
+		{[]string{"rule", "add", "hide", "(unclosed", "--regex"}, "Invalid regular expression"},
+		{[]string{"rule", "add", "delete", "spam"}, "usage"},
+		{[]string{"rule", "add", "hide", "spam", "--field", "body"}, "usage"},
+		{[]string{"rule", "add", "hide", "spam", "--feed", "https://nobody.example/rss"}, "Error finding feed"},
+	}
+	for _, tt := range tests {
+		mustFail(t, s, tt.want, tt.args...)
+	}
+}
@@ -3,8 +3,9 @@ package commands
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
-	"os"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,56 +13,98 @@ import (
 	"github.com/nhdewitt/blog-aggregator/internal/database"
 )
 
-// handlerLogin authenticates a user by setting them as the current user.
-// The user must already exist in the database.
+// handlerLogin authenticates a user with their password and starts a session.
+// The session token is saved in the config file and checked by every command
+// that needs a logged-in user. Users created before passwords existed can't
+// log in until an admin sets their password with `gator user set-password`.
 //
 // Usage: gator login <username>
-func handlerLogin(s *app.State, cmd Command) error {
+func handlerLogin(ctx context.Context, s *app.State, cmd Command) error {
 	if len(cmd.Args) != 1 {
 		return fmt.Errorf("usage: %s <name>", cmd.Name)
 	}
 
 	username := cmd.Args[0]
 
-	_, err := s.Db.GetUser(context.Background(), username)
+	user, err := s.Db.GetUser(ctx, username)
+	if errors.Is(err, sql.ErrNoRows) {
+		return app.ErrBadCredentials
+	}
 	if err != nil {
-		fmt.Printf("User doesn't exist: %v\n", err)
-		os.Exit(1)
+		return fmt.Errorf("Error finding user: %w", err)
 	}
 
-	err = s.Cfg.SetUser(username)
+	// Whoever logged in first would otherwise get to choose the password
+	if !user.PasswordHash.Valid {
+		return fmt.Errorf("%s has no password yet; an admin can set one with 'gator user set-password %s'", username, username)
+	}
+	password, err := readPassword("Password: ")
 	if err != nil {
-		return fmt.Errorf("Error setting username: %v\n", err)
+		return err
+	}
+	if !app.CheckPassword(user, password) {
+		return app.ErrBadCredentials
 	}
 
-	fmt.Printf("Username set to %s\n", username)
-	
+	if err := startSession(ctx, s, user); err != nil {
+		return err
+	}
+
+	fmt.Printf("Logged in as %s\n", username)
 	return nil
 }
 
-// handlerRegister creates a new user account and sets them as the current user.
+// handlerLogout ends the current session and forgets it in the config file.
+//
+// Usage: gator logout
+func handlerLogout(ctx context.Context, s *app.State, cmd Command) error {
+	if len(cmd.Args) != 0 {
+		return fmt.Errorf("usage: %s", cmd.Name)
+	}
+
+	if err := app.EndSession(ctx, s, s.Cfg.SessionToken); err != nil {
+		return err
+	}
+	if err := s.Cfg.SetSession("", ""); err != nil {
+		return fmt.Errorf("Error clearing session: %w", err)
+	}
+
+	fmt.Println("Logged out")
+	return nil
+}
+
+// handlerRegister creates a new user account with a password and logs them in.
 // The username must be unique.
 //
 // Usage: gator register <username>
-func handlerRegister(s *app.State, cmd Command) error {
+func handlerRegister(ctx context.Context, s *app.State, cmd Command) error {
 	if len(cmd.Args) != 1 {
 		return fmt.Errorf("usage: %s <name>", cmd.Name)
 	}
 
+	password, err := readNewPassword()
+	if err != nil {
+		return err
+	}
+	hash, err := app.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
 	username := cmd.Args[0]
-	user, err := s.Db.CreateUser(context.Background(), database.CreateUserParams{
+	user, err := s.Db.CreateUser(ctx, database.CreateUserParams{
 		ID: uuid.New(),
 		CreatedAt: time.Now().UTC(),
 		UpdatedAt: time.Now().UTC(),
 		Name: username,
+		PasswordHash: sql.NullString{String: hash, Valid: true},
 	})
 	if err != nil {
 		return fmt.Errorf("Couldn't create user: %w", err)
 	}
 
-	err = s.Cfg.SetUser(user.Name)
-	if err != nil {
-		return fmt.Errorf("Couldn't set current user: %w", err)
+	if err := startSession(ctx, s, user); err != nil {
+		return err
 	}
 
 	fmt.Println("User created successfully")
@@ -69,43 +112,234 @@ func handlerRegister(s *app.State, cmd Command) error {
 	return nil
 }
 
-// handlerReset removes all users from the database.
-// This operation cannot be undone.
-//
-// Usage: gator reset
-func handlerReset(s *app.State, cmd Command) error {
-	err := s.Db.DeleteAllUsers(context.Background())
-	if err != nil {
-		return fmt.Errorf("Couldn't reset database: %w", err)
+// startSession logs user in, replacing any session saved in the config file.
+func startSession(ctx context.Context, s *app.State, user database.User) error {
+	// Don't leave the previous session usable
+	if err := app.EndSession(ctx, s, s.Cfg.SessionToken); err != nil {
+		return err
 	}
 
-	fmt.Println("Databse reset")
+	token, err := app.CreateSession(ctx, s, user)
+	if err != nil {
+		return err
+	}
+	if err := s.Cfg.SetSession(user.Name, token); err != nil {
+		return fmt.Errorf("Couldn't save session: %w", err)
+	}
 	return nil
 }
 
 // handlerGetUsers displays a list of all registered users.
-// The current user is noted with (current).
+// Admins are marked [admin] and the current user is noted with (current).
 //
 // Usage: gator users
-func handlerGetUsers(s *app.State, cmd Command) error {
+func handlerGetUsers(ctx context.Context, s *app.State, cmd Command) error {
 	currentUser := s.Cfg.CurrentUser
 
-	users, err := s.Db.GetUsers(context.Background())
+	users, err := s.Db.GetUsers(ctx)
 	if err != nil {
 		return fmt.Errorf("Couldn't get user list: %w", err)
 	}
 
 	for _, user := range users {
+		name := user.Name
+		if app.IsAdmin(user) {
+			name += " [admin]"
+		}
 		if user.Name == currentUser {
-			fmt.Printf(" * %s (current)\n", user.Name)
+			fmt.Printf(" * %s (current)\n", name)
 		} else {
-			fmt.Printf(" * %s\n", user.Name)
+			fmt.Printf(" * %s\n", name)
 		}
 	}
 
 	return nil
 }
 
+// handlerUser dispatches the user management subcommands, which only admins
+// may run. While no admin has a password, as on an installation upgraded from
+// before passwords existed, nobody could log in to set one, so anyone may set
+// an admin's password, just as anyone may restore into an empty database.
+//
+// Usage: gator user <promote|demote|delete|set-password> <username>
+func handlerUser(ctx context.Context, s *app.State, cmd Command) error {
+	if len(cmd.Args) != 2 {
+		return fmt.Errorf("usage: %s <promote|demote|delete|set-password> <username>", cmd.Name)
+	}
+
+	var setup bool
+	if cmd.Args[0] == "set-password" {
+		var err error
+		if setup, err = noAdminHasPassword(ctx, s); err != nil {
+			return err
+		}
+	}
+	var admin database.User
+	if !setup {
+		var err error
+		if admin, err = app.SessionUser(ctx, s, s.Cfg.SessionToken); err != nil {
+			return err
+		}
+		if !app.IsAdmin(admin) {
+			return app.ErrAdminOnly
+		}
+	}
+
+	target, err := s.Db.GetUser(ctx, cmd.Args[1])
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("User %s not found", cmd.Args[1])
+	}
+	if err != nil {
+		return fmt.Errorf("Error finding user: %w", err)
+	}
+
+	switch cmd.Args[0] {
+	case "promote":
+		return setUserRole(ctx, s, target, app.RoleAdmin)
+	case "demote":
+		return setUserRole(ctx, s, target, app.RoleUser)
+	case "delete":
+		return deleteUser(ctx, s, target, admin)
+	case "set-password":
+		if setup && !app.IsAdmin(target) {
+			return fmt.Errorf("No admin has a password yet; set an admin's password first, then log in as them to set %s's", target.Name)
+		}
+		return setUserPassword(ctx, s, target)
+	default:
+		return fmt.Errorf("unknown %s subcommand: %q", cmd.Name, cmd.Args[0])
+	}
+}
+
+// noAdminHasPassword reports whether every admin is still without a password.
+func noAdminHasPassword(ctx context.Context, s *app.State) (bool, error) {
+	users, err := s.Db.GetUsers(ctx)
+	if err != nil {
+		return false, fmt.Errorf("Error getting users: %w", err)
+	}
+	for _, user := range users {
+		if app.IsAdmin(user) && user.PasswordHash.Valid {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// setUserPassword prompts for a new password for target and stores it, for
+// users created before passwords existed and users who forgot theirs.
+func setUserPassword(ctx context.Context, s *app.State, target database.User) error {
+	password, err := readNewPassword()
+	if err != nil {
+		return err
+	}
+	hash, err := app.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	err = s.Db.SetUserPassword(ctx, database.SetUserPasswordParams{
+		ID: target.ID,
+		PasswordHash: sql.NullString{String: hash, Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("Error setting password: %w", err)
+	}
+
+	fmt.Printf("Password for %s set\n", target.Name)
+	return nil
+}
+
+// setUserRole changes a user's role, refusing to demote the last admin.
+func setUserRole(ctx context.Context, s *app.State, target database.User, role string) error {
+	if target.Role == role {
+		fmt.Printf("%s is already %s\n", target.Name, role)
+		return nil
+	}
+	if err := requireAnotherAdmin(ctx, s, target); err != nil {
+		return err
+	}
+
+	err := s.Db.SetUserRole(ctx, database.SetUserRoleParams{ID: target.ID, Role: role})
+	if err != nil {
+		return fmt.Errorf("Error changing role: %w", err)
+	}
+
+	fmt.Printf("%s is now %s\n", target.Name, role)
+	return nil
+}
+
+// deleteUser removes a user along with their follows, folders, rules and sessions.
+// Feeds they added stay in the catalog and are handed to the admin running the
+// command, or to the longest-standing other admin when admins delete
+// themselves, except feeds that nobody follows once the user is gone.
+func deleteUser(ctx context.Context, s *app.State, target, admin database.User) error {
+	if err := requireAnotherAdmin(ctx, s, target); err != nil {
+		return err
+	}
+
+	heir := admin
+	if target.ID == admin.ID {
+		var err error
+		if heir, err = anotherAdmin(ctx, s, target); err != nil {
+			return err
+		}
+	}
+
+	var orphaned []string
+	err := s.InTx(ctx, func(q app.Store) error {
+		// Deleting the user cascades to their feeds, so hand them over first
+		err := q.ReassignFeeds(ctx, database.ReassignFeedsParams{FromUserID: target.ID, ToUserID: heir.ID})
+		if err != nil {
+			return fmt.Errorf("Error reassigning feeds: %w", err)
+		}
+		if err := q.DeleteUser(ctx, target.ID); err != nil {
+			return fmt.Errorf("Error deleting user: %w", err)
+		}
+		orphaned, err = q.DeleteOrphanedFeeds(ctx)
+		if err != nil {
+			return fmt.Errorf("Error removing unfollowed feeds: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("User %s deleted\n", target.Name)
+	for _, url := range orphaned {
+		fmt.Printf("Removed %s, which nobody follows anymore\n", url)
+	}
+	return nil
+}
+
+// requireAnotherAdmin returns an error if target is the only admin left,
+// so the installation can't be locked out of admin commands.
+func requireAnotherAdmin(ctx context.Context, s *app.State, target database.User) error {
+	if !app.IsAdmin(target) {
+		return nil
+	}
+	admins, err := s.Db.CountAdmins(ctx)
+	if err != nil {
+		return fmt.Errorf("Error counting admins: %w", err)
+	}
+	if admins <= 1 {
+		return fmt.Errorf("%s is the only admin; promote another user first", target.Name)
+	}
+	return nil
+}
+
+// anotherAdmin returns the earliest admin other than user.
+func anotherAdmin(ctx context.Context, s *app.State, user database.User) (database.User, error) {
+	users, err := s.Db.GetUsers(ctx)
+	if err != nil {
+		return database.User{}, fmt.Errorf("Error getting users: %w", err)
+	}
+	for _, u := range users {
+		if app.IsAdmin(u) && u.ID != user.ID {
+			return u, nil
+		}
+	}
+	return database.User{}, fmt.Errorf("%s is the only admin; promote another user first", user.Name)
+}
+
 // printUser displays formatted user information.
 // Runs when a user is created.
 func printUser(u database.User) {
@@ -0,0 +1,216 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nhdewitt/blog-aggregator/internal/app"
+	"github.com/nhdewitt/blog-aggregator/internal/database"
+)
+
+func TestRegisterLoginLogout(t *testing.T) {
+	s := newTestState(t)
+
+	out := mustRun(t, s, "register", "alice")
+	contains(t, out, "User created successfully", "Name:\talice")
+	if s.Cfg.CurrentUser != "alice" || s.Cfg.SessionToken == "" {
+		t.Fatalf("after register: user %q, token %q, want alice logged in", s.Cfg.CurrentUser, s.Cfg.SessionToken)
+	}
+
+	mustRun(t, s, "register", "bob")
+	out = mustRun(t, s, "users")
+	contains(t, out, " * alice [admin]\n", " * bob (current)\n")
+
+	out = mustRun(t, s, "logout")
+	contains(t, out, "Logged out")
+	if s.Cfg.SessionToken != "" {
+		t.Errorf("token %q after logout, want none", s.Cfg.SessionToken)
+	}
+	if _, err := run(t, s, "following"); !errors.Is(err, app.ErrNotLoggedIn) {
+		t.Errorf("following after logout: error %v, want %v", err, app.ErrNotLoggedIn)
+	}
+
+	out = mustRun(t, s, "login", "alice")
+	contains(t, out, "Logged in as alice")
+	mustRun(t, s, "following")
+
+	mustFail(t, s, "Couldn't create user", "register", "alice")
+	mustFail(t, s, "usage", "login")
+}
+
+func TestUserManagement(t *testing.T) {
+	s := newTestState(t)
+	mustRun(t, s, "register", "admin")
+	mustRun(t, s, "register", "carol")
+	mustRun(t, s, "login", "admin")
+
+	contains(t, mustRun(t, s, "user", "promote", "carol"), "carol is now admin")
+	contains(t, mustRun(t, s, "user", "demote", "carol"), "carol is now user")
+	contains(t, mustRun(t, s, "user", "delete", "carol"), "User carol deleted")
+	mustFail(t, s, "User carol not found", "user", "promote", "carol")
+	mustFail(t, s, "unknown user subcommand", "user", "rename", "admin")
+}
+
+func TestLoginChecksPassword(t *testing.T) {
+	s := newTestState(t)
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "logout")
+
+	tests := []struct {
+		user		string
+		password	string
+		wantErr		error
+	}{
+		{"alice", "wrong-horse", app.ErrBadCredentials},
+		{"alice", "", app.ErrBadCredentials},
+		{"mallory", testPassword, app.ErrBadCredentials},
+		{"alice", testPassword, nil},
+	}
+	for _, tt := range tests {
+		t.Setenv(passwordEnv, tt.password)
+		if _, err := run(t, s, "login", tt.user); !errors.Is(err, tt.wantErr) {
+			t.Errorf("login %s with %q: error %v, want %v", tt.user, tt.password, err, tt.wantErr)
+		}
+	}
+
+	// A short password is refused at registration
+	t.Setenv(passwordEnv, "short")
+	if _, err := run(t, s, "register", "bob"); !errors.Is(err, app.ErrPasswordTooShort) {
+		t.Errorf("register with a short password: error %v, want %v", err, app.ErrPasswordTooShort)
+	}
+}
+
+func TestSessionDecidesTheUser(t *testing.T) {
+	s := newTestState(t)
+	mustRun(t, s, "register", "alice")
+
+	// Editing current_user doesn't log in as someone else
+	mustRun(t, s, "addfeed", "Blog", "https://blog.example/rss")
+	mustRun(t, s, "register", "bob")
+	mustRun(t, s, "login", "alice")
+	s.Cfg.CurrentUser = "bob"
+	contains(t, mustRun(t, s, "following"), "Subscribed feeds for alice")
+
+	// A session token that was logged out elsewhere stops working
+	token := s.Cfg.SessionToken
+	mustRun(t, s, "logout")
+	s.Cfg.SessionToken = token
+	if _, err := run(t, s, "following"); !errors.Is(err, app.ErrSessionExpired) {
+		t.Errorf("following with an ended session: error %v, want %v", err, app.ErrSessionExpired)
+	}
+}
+
+func TestAdminOnlyCommands(t *testing.T) {
+	s := newTestState(t)
+	mustRun(t, s, "register", "admin")
+	mustRun(t, s, "register", "carol")
+
+	backup := filepath.Join(t.TempDir(), "gator.backup")
+	tests := [][]string{
+		{"reset", "--yes"},
+		{"backup", backup},
+		{"restore", backup},
+		{"user", "promote", "carol"},
+		{"user", "delete", "admin"},
+		{"user", "set-password", "admin"},
+	}
+	for _, args := range tests {
+		if _, err := run(t, s, args...); !errors.Is(err, app.ErrAdminOnly) {
+			t.Errorf("%q as a regular user: error %v, want %v", args, err, app.ErrAdminOnly)
+		}
+	}
+	contains(t, mustRun(t, s, "users"), " * admin [admin]\n", " * carol (current)\n")
+}
+
+func TestLastAdminStays(t *testing.T) {
+	s := newTestState(t)
+	mustRun(t, s, "register", "admin")
+	mustRun(t, s, "addfeed", "Admin's Blog", "https://admin.example/rss")
+	mustRun(t, s, "register", "carol")
+	mustRun(t, s, "follow", "https://admin.example/rss")
+	mustRun(t, s, "login", "admin")
+
+	mustFail(t, s, "admin is the only admin; promote another user first", "user", "demote", "admin")
+	mustFail(t, s, "admin is the only admin", "user", "delete", "admin")
+
+	// With a second admin, the first may step down
+	mustRun(t, s, "user", "promote", "carol")
+	contains(t, mustRun(t, s, "user", "demote", "admin"), "admin is now user")
+	mustFail(t, s, "this command requires an admin user", "user", "promote", "admin")
+
+	// Deleting a user hands their followed feeds to the admin doing it
+	mustRun(t, s, "login", "carol")
+	contains(t, mustRun(t, s, "user", "delete", "admin"), "User admin deleted")
+	contains(t, mustRun(t, s, "feeds"), "Feed:\tAdmin's Blog\n * URL:\t\thttps://admin.example/rss\n * Added by:\tcarol")
+}
+
+func TestAdminDeletesOwnAccount(t *testing.T) {
+	s := newTestState(t)
+	srv := newFeedServer(t)
+	url := srv.serve("/feed.xml", rssDocument("Blog",
+		testItem{"Keeper", "https://admin.example/keeper", time.Now().Add(-time.Hour)},
+	))
+	mustRun(t, s, "register", "admin")
+	mustRun(t, s, "addfeed", "Admin's Blog", url)
+	mustRun(t, s, "refresh", url)
+	mustRun(t, s, "register", "carol")
+	mustRun(t, s, "register", "dave")
+	mustRun(t, s, "follow", url)
+	mustRun(t, s, "save", "https://admin.example/keeper")
+	mustRun(t, s, "login", "admin")
+	mustRun(t, s, "user", "promote", "carol")
+
+	contains(t, mustRun(t, s, "user", "delete", "admin"), "User admin deleted")
+
+	// The feed and what others kept of it survive, handed to the other admin
+	mustRun(t, s, "login", "dave")
+	contains(t, mustRun(t, s, "feeds"), "Feed:\tAdmin's Blog\n * URL:\t\t"+url+"\n * Added by:\tcarol")
+	contains(t, mustRun(t, s, "following"), "Admin's Blog")
+	contains(t, mustRun(t, s, "browse", "--saved"), "Keeper")
+}
+
+func TestUsersWithoutPasswords(t *testing.T) {
+	s := newTestState(t)
+	// Users from before passwords existed; the earliest is the admin
+	for _, name := range []string{"admin", "carol"} {
+		_, err := s.Db.CreateUser(context.Background(), database.CreateUserParams{
+			ID: uuid.New(),
+			CreatedAt: time.Now().UTC(),
+			UpdatedAt: time.Now().UTC(),
+			Name: name,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		args	[]string
+		want	string	// error, or output if the command succeeds
+		ok		bool
+	}{
+		// Nobody can claim an account by logging in first
+		{[]string{"login", "carol"}, "carol has no password yet; an admin can set one with 'gator user set-password carol'", false},
+		{[]string{"login", "admin"}, "admin has no password yet", false},
+		// Until an admin has a password, only an admin's can be set without logging in
+		{[]string{"user", "set-password", "carol"}, "No admin has a password yet; set an admin's password first", false},
+		{[]string{"user", "promote", "carol"}, app.ErrNotLoggedIn.Error(), false},
+		{[]string{"user", "set-password", "admin"}, "Password for admin set", true},
+		{[]string{"user", "set-password", "admin"}, app.ErrNotLoggedIn.Error(), false},
+		{[]string{"user", "set-password", "carol"}, app.ErrNotLoggedIn.Error(), false},
+		{[]string{"login", "admin"}, "Logged in as admin", true},
+		{[]string{"user", "set-password", "carol"}, "Password for carol set", true},
+		{[]string{"login", "carol"}, "Logged in as carol", true},
+	}
+	for _, tt := range tests {
+		if tt.ok {
+			contains(t, mustRun(t, s, tt.args...), tt.want)
+		} else {
+			mustFail(t, s, tt.want, tt.args...)
+		}
+	}
+}
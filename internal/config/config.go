@@ -1,86 +1,351 @@
+// Package config loads gator's settings from defaults, the config file,
+// GATOR_* environment variables and command-line flags, in that order.
 package config
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"io/fs"
 	"os"
+	"path/filepath"
 )
 
 const (
-	configFileName = "/.gatorconfig.json"
+	// legacyConfigFileName is where gator kept its config in the home
+	// directory before it used the XDG config directory. It is still read
+	// when it exists and the XDG file doesn't.
+	legacyConfigFileName = ".gatorconfig.json"
+
+	// configPathEnv names the environment variable that points at the config file.
+	configPathEnv = "GATOR_CONFIG"
 )
 
+// Config holds the settings in effect: those of the active profile, with
+// environment variables and flags applied.
 type Config struct {
-	DBUrl	string	`json:"db_url"`
-	CurrentUser	string	`json:"current_user_name"`
+	DBUrl			string
+	DBPasswordFile	string
+	CurrentUser		string
+	SessionToken	string
+	Fetch			FetchConfig
+	Retention		RetentionConfig
+
+	profile	string				// the active profile
+	path	string				// the config file, which may not exist yet
+	exists	bool				// whether path existed when the config was loaded
+	sources	map[string]string	// where each setting came from, by key
+}
+
+// FetchConfig controls how feeds and articles are downloaded.
+// Empty fields fall back to the fetcher's defaults.
+type FetchConfig struct {
+	Timeout			string	`json:"timeout,omitempty"`			// overall limit per request, e.g. "30s"
+	ConnectTimeout	string	`json:"connect_timeout,omitempty"`	// limit for establishing the connection
+	ReadTimeout		string	`json:"read_timeout,omitempty"`		// limit for waiting on response headers
+	MaxBodyBytes	int64	`json:"max_body_bytes,omitempty"`	// largest response body accepted
+	MaxRedirects	int		`json:"max_redirects,omitempty"`
+	UserAgent		string	`json:"user_agent,omitempty"`
+	ContactURL		string	`json:"contact_url,omitempty"`		// included in the User-Agent so publishers can reach you
+	Proxy			string	`json:"proxy,omitempty"`			// proxy URL; HTTP_PROXY/HTTPS_PROXY are used when empty
+}
+
+// RetentionConfig limits how many posts are kept. Feeds can override the
+// limits individually. Empty fields keep posts forever.
+type RetentionConfig struct {
+	MaxAge			string	`json:"max_age,omitempty"`			// delete posts published longer ago, e.g. "90d" or "720h"
+	MaxPostsPerFeed	int		`json:"max_posts_per_feed,omitempty"`	// keep only the newest posts of each feed
+	PruneInterval	string	`json:"prune_interval,omitempty"`	// how often agg prunes, "1h" when empty
+}
+
+// Options are the settings given on the command line, which take
+// precedence over the config file and the environment.
+type Options struct {
+	Path		string				// config file to use instead of the default one
+	Profile		string				// profile to use instead of the active one
+	Overrides	map[string]string	// setting values by key, from --db-url and --set
+}
+
+// Load builds the configuration from its layers: built-in defaults, the
+// active profile in the config file, GATOR_* environment variables, then
+// opts. A missing config file is not an error; the defaults are used and the
+// file is created when a setting is saved.
+func Load(opts Options) (Config, error) {
+	cfg := Config{DBUrl: defaultDBUrl(), sources: make(map[string]string)}
+
+	path, err := configFilePath(opts.Path)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.path = path
+
+	file, err := readFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return cfg, err
+	}
+	cfg.exists = err == nil
+
+	cfg.profile, err = file.activeProfile(opts.Profile)
+	if err != nil {
+		return cfg, err
+	}
+	saved := file.get(cfg.profile)
+	cfg.CurrentUser = saved.CurrentUser
+	cfg.SessionToken = saved.SessionToken
+	for _, s := range saved.settings() {
+		if s.isSet() {
+			cfg.set(s.Key, s.String(), path)
+		}
+	}
+
+	for _, s := range cfg.settings() {
+		if value, ok := os.LookupEnv(s.envVar()); ok {
+			if err := cfg.set(s.Key, value, "$"+s.envVar()); err != nil {
+				return cfg, err
+			}
+		}
+	}
+	for key, value := range opts.Overrides {
+		if err := cfg.set(key, value, "command line"); err != nil {
+			return cfg, err
+		}
+	}
+	return cfg, nil
+}
+
+// Path returns the config file, whether or not it exists.
+func (cfg *Config) Path() string {
+	return cfg.path
 }
 
+// Exists reports whether the config file existed when the config was loaded.
+func (cfg *Config) Exists() bool {
+	return cfg.exists
+}
+
+// SetUser stores the current user of the active profile.
 func (cfg *Config) SetUser(u string) error {
 	cfg.CurrentUser = u
-	err := write(*cfg)
+	err := cfg.update(func(file *Config) error {
+		file.CurrentUser = u
+		return nil
+	})
 	if err != nil {
 		return fmt.Errorf("Error writing to config file: %v", err)
 	}
 	return nil
 }
 
-func getConfigFilePath() (string, error) {
-	homeDir, err := os.UserHomeDir()
+// SetSession stores the logged-in user and their session token in the
+// active profile. Passing empty strings logs out.
+func (cfg *Config) SetSession(u, token string) error {
+	cfg.CurrentUser = u
+	cfg.SessionToken = token
+	err := cfg.update(func(file *Config) error {
+		file.CurrentUser = u
+		file.SessionToken = token
+		return nil
+	})
 	if err != nil {
-		log.Printf("Error finding home directory: %v", err)
-		return "", err
+		return fmt.Errorf("Error writing to config file: %v", err)
 	}
+	return nil
+}
 
-	return homeDir + configFileName, nil
+// Save stores a setting in the active profile, or for retention settings
+// in the part of the config file all profiles share. The loaded configuration picks
+// up the new value too, unless the environment or a flag overrides it.
+func (cfg *Config) Save(key, value string) error {
+	if err := cfg.update(func(file *Config) error {
+		return file.set(key, value, "")
+	}); err != nil {
+		return err
+	}
+	if !cfg.Overridden(key) {
+		return cfg.set(key, value, cfg.path)
+	}
+	return nil
+}
+
+// update applies change to the active profile's settings as stored in the
+// config file and writes it back. Only the file is changed, so values from
+// the environment and flags are never saved, and other profiles are kept as they are.
+func (cfg *Config) update(change func(saved *Config) error) error {
+	return cfg.updateFile(func(file *fileConfig) error {
+		saved := file.get(cfg.profile)
+		if err := change(&saved); err != nil {
+			return err
+		}
+		file.put(cfg.profile, saved)
+		return nil
+	})
 }
 
-func write(cfg Config) error {
-	configFile, err := getConfigFilePath()
+// updateFile reads the config file, applies change and writes it back,
+// holding the config lock so changes made by concurrent gator processes,
+// such as two logins, aren't lost.
+func (cfg *Config) updateFile(change func(file *fileConfig) error) error {
+	unlock, err := lockConfig(cfg.path)
 	if err != nil {
-		log.Printf("Error finding home directory: %v", err)
 		return err
 	}
+	defer unlock()
 
-	f, err := os.Create(configFile)
-	if err != nil {
-		log.Printf("Error opening config file %s: %v", configFile, err)
+	file, err := readFile(cfg.path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
 		return err
 	}
-	defer f.Close()
+	if err := change(&file); err != nil {
+		return err
+	}
+	if err := write(cfg.path, file); err != nil {
+		return err
+	}
+	cfg.exists = true
+	return nil
+}
+
+// configFilePath returns the config file to use: the one given on the
+// command line, then $GATOR_CONFIG, then ~/.gatorconfig.json if it exists
+// and the XDG one doesn't, and otherwise gator/config.json in the XDG
+// config directory.
+func configFilePath(path string) (string, error) {
+	if path == "" {
+		path = os.Getenv(configPathEnv)
+	}
+	if path != "" {
+		return expandHome(path)
+	}
 
-	jsonData, err := json.Marshal(cfg)
+	configDir, err := os.UserConfigDir()
 	if err != nil {
-		log.Printf("Error marshalling JSON: %v", err)
-		return err
+		return "", fmt.Errorf("Error finding config directory: %w", err)
+	}
+	xdgPath := filepath.Join(configDir, "gator", "config.json")
+	if _, err := os.Stat(xdgPath); err == nil {
+		return xdgPath, nil
 	}
 
-	_, err = f.Write(jsonData)
+	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		log.Printf("Error writing JSON: %v", err)
-		return err
+		return "", fmt.Errorf("Error finding home directory: %w", err)
+	}
+	legacyPath := filepath.Join(homeDir, legacyConfigFileName)
+	if _, err := os.Stat(legacyPath); err == nil {
+		return legacyPath, nil
 	}
+	return xdgPath, nil
+}
 
-	return nil
+// defaultDBUrl is a SQLite database in the XDG data directory.
+func defaultDBUrl() string {
+	dataDir := os.Getenv("XDG_DATA_HOME")
+	if dataDir == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dataDir = filepath.Join(homeDir, ".local", "share")
+	}
+	return "sqlite://" + filepath.ToSlash(filepath.Join(dataDir, "gator", "gator.db"))
 }
 
-func Read() (Config, error) {
-	configFile, err := getConfigFilePath()
+// expandHome replaces a leading ~/ in path with the home directory.
+func expandHome(path string) (string, error) {
+	if len(path) < 2 || path[:2] != "~/" {
+		return path, nil
+	}
+	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		return Config{}, err
+		return "", fmt.Errorf("Error finding home directory: %w", err)
 	}
+	return filepath.Join(homeDir, path[2:]), nil
+}
 
-	f, err := os.Open(configFile)
+// readFile reads the config file. A missing file reads as an empty one,
+// along with an error matching fs.ErrNotExist.
+func readFile(path string) (fileConfig, error) {
+	var config fileConfig
+	config.normalize()
+	f, err := os.Open(path)
 	if err != nil {
-		return Config{}, fmt.Errorf("Error opening config file %s: %v", configFile, err)
+		if errors.Is(err, fs.ErrNotExist) {
+			return config, err
+		}
+		return config, fmt.Errorf("Error opening config file %s: %v", path, err)
 	}
 	defer f.Close()
 
-	var config Config
 	jsonParser := json.NewDecoder(f)
 	if err := jsonParser.Decode(&config); err != nil {
-		return config, fmt.Errorf("Error decoding json: %v", err)
+		return config, fmt.Errorf("Error decoding config file %s: %v", path, err)
 	}
-	
+	config.normalize()
 	return config, nil
-}
\ No newline at end of file
+}
+
+// write replaces the config file atomically. The new contents go to a
+// temporary file beside it, readable only by the owner since the file may
+// hold a database password, which is synced and renamed over the old file.
+// A crash leaves either the old config or the new one, never a partial file.
+func write(path string, cfg fileConfig) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("Error creating config directory: %w", err)
+	}
+
+	jsonData, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Error marshalling JSON: %w", err)
+	}
+
+	// CreateTemp makes the file with 0600 permissions
+	f, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("Error creating temporary config file: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(append(jsonData, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("Error writing JSON: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("Error syncing config file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("Error writing config file: %w", err)
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("Error replacing config file %s: %w", path, err)
+	}
+
+	// Make the rename itself durable. Not every platform can sync a
+	// directory, and the config is already complete either way.
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+// lockConfig takes the lock that serializes changes to the config file
+// between gator processes and returns the function that releases it. The
+// lock is a separate file, because the config file itself is replaced on
+// every write.
+func lockConfig(path string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("Error creating config directory: %w", err)
+	}
+
+	f, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("Error opening config lock file: %w", err)
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("Error locking config file: %w", err)
+	}
+	return func() { f.Close() }, nil
+}
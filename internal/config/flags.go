@@ -0,0 +1,49 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseArgs separates the global flags at the start of gator's arguments
+// from the command and its arguments:
+//
+//	--config <path>       use another config file
+//	--profile <name>      use another profile
+//	--db-url <url>        override db_url
+//	--set <key>=<value>   override any setting, e.g. --set fetch.timeout=1m
+//
+// Flags may also be written as --name=value.
+func ParseArgs(args []string) (Options, []string, error) {
+	opts := Options{Overrides: make(map[string]string)}
+
+	for len(args) > 0 && strings.HasPrefix(args[0], "--") {
+		name, value, hasValue := strings.Cut(strings.TrimPrefix(args[0], "--"), "=")
+		if !hasValue {
+			if len(args) < 2 {
+				return opts, nil, fmt.Errorf("flag --%s requires a value", name)
+			}
+			value = args[1]
+			args = args[1:]
+		}
+		args = args[1:]
+
+		switch name {
+		case "config":
+			opts.Path = value
+		case "profile":
+			opts.Profile = value
+		case "db-url":
+			opts.Overrides["db_url"] = value
+		case "set":
+			key, v, ok := strings.Cut(value, "=")
+			if !ok {
+				return opts, nil, fmt.Errorf("flag --set expects <key>=<value>, not %q", value)
+			}
+			opts.Overrides[key] = v
+		default:
+			return opts, nil, fmt.Errorf("unknown flag: --%s", name)
+		}
+	}
+	return opts, args, nil
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		args		[]string
+		opts		Options
+		rest		[]string
+		wantErr		string
+	}{
+		{
+			args: []string{"browse", "10"},
+			opts: Options{Overrides: map[string]string{}},
+			rest: []string{"browse", "10"},
+		},
+		{
+			args: []string{"--config", "~/gator.json", "--profile=team", "users"},
+			opts: Options{Path: "~/gator.json", Profile: "team", Overrides: map[string]string{}},
+			rest: []string{"users"},
+		},
+		{
+			args: []string{"--db-url", "sqlite://test.db", "--set", "fetch.timeout=1m", "--set=retention.max_age=30d", "agg", "--once"},
+			opts: Options{Overrides: map[string]string{"db_url": "sqlite://test.db", "fetch.timeout": "1m", "retention.max_age": "30d"}},
+			rest: []string{"agg", "--once"},
+		},
+		{
+			args: []string{"--set", "fetch.user_agent=a=b"},
+			opts: Options{Overrides: map[string]string{"fetch.user_agent": "a=b"}},
+			rest: []string{},
+		},
+		{args: []string{"--profile"}, wantErr: "flag --profile requires a value"},
+		{args: []string{"--set", "fetch.timeout", "users"}, wantErr: `flag --set expects <key>=<value>, not "fetch.timeout"`},
+		{args: []string{"--verbose=true", "users"}, wantErr: "unknown flag: --verbose"},
+	}
+	for _, tt := range tests {
+		opts, rest, err := ParseArgs(tt.args)
+		if tt.wantErr != "" {
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("ParseArgs(%q) error = %v, want %q", tt.args, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseArgs(%q): %v", tt.args, err)
+			continue
+		}
+		if !reflect.DeepEqual(opts, tt.opts) || !reflect.DeepEqual(rest, tt.rest) {
+			t.Errorf("ParseArgs(%q) = %+v, %q; want %+v, %q", tt.args, opts, rest, tt.opts, tt.rest)
+		}
+	}
+}
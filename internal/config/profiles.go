@@ -0,0 +1,164 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+)
+
+const (
+	// defaultProfile is used when no profile is chosen. A config file from
+	// before profiles existed is read as this profile.
+	defaultProfile = "default"
+
+	// profileEnv names the environment variable that chooses the profile.
+	profileEnv = "GATOR_PROFILE"
+)
+
+// Profile holds the settings that differ between databases, such as a
+// personal one and a team one. Each profile has its own login.
+type Profile struct {
+	DBUrl			string		`json:"db_url"`
+	DBPasswordFile	string		`json:"db_password_file,omitempty"`	// file holding the password for db_url
+	CurrentUser		string		`json:"current_user_name"`
+	SessionToken	string		`json:"session_token,omitempty"`
+	Fetch			FetchConfig	`json:"fetch"`
+}
+
+// fileConfig is the layout of the config file. Retention applies to every profile.
+type fileConfig struct {
+	*Profile		// settings saved before profiles existed, moved into the default profile
+
+	ActiveProfile	string				`json:"active_profile,omitempty"`
+	Profiles		map[string]Profile	`json:"profiles"`
+	Retention		RetentionConfig		`json:"retention"`
+}
+
+// normalize moves settings saved before profiles existed into the default profile.
+func (f *fileConfig) normalize() {
+	if f.Profiles == nil {
+		f.Profiles = make(map[string]Profile)
+	}
+	if f.Profile != nil {
+		if _, ok := f.Profiles[defaultProfile]; !ok {
+			f.Profiles[defaultProfile] = *f.Profile
+		}
+		f.Profile = nil
+	}
+}
+
+// get returns the settings of the named profile as a Config.
+func (f *fileConfig) get(name string) Config {
+	p := f.Profiles[name]
+	return Config{
+		DBUrl: p.DBUrl,
+		DBPasswordFile: p.DBPasswordFile,
+		CurrentUser: p.CurrentUser,
+		SessionToken: p.SessionToken,
+		Fetch: p.Fetch,
+		Retention: f.Retention,
+	}
+}
+
+// put stores cfg as the named profile, leaving the other profiles alone.
+func (f *fileConfig) put(name string, cfg Config) {
+	f.Profiles[name] = Profile{
+		DBUrl: cfg.DBUrl,
+		DBPasswordFile: cfg.DBPasswordFile,
+		CurrentUser: cfg.CurrentUser,
+		SessionToken: cfg.SessionToken,
+		Fetch: cfg.Fetch,
+	}
+	f.Retention = cfg.Retention
+}
+
+// activeProfile returns the profile to use: the one given on the command
+// line, then $GATOR_PROFILE, then the one saved with `gator profile use`.
+// It must exist unless it is the default profile.
+func (f *fileConfig) activeProfile(name string) (string, error) {
+	if name == "" {
+		name = os.Getenv(profileEnv)
+	}
+	if name == "" {
+		name = f.ActiveProfile
+	}
+	if name == "" {
+		name = defaultProfile
+	}
+
+	if _, ok := f.Profiles[name]; !ok && name != defaultProfile {
+		return "", fmt.Errorf("Profile %q doesn't exist; create it with 'gator profile add %s <db_url>'", name, name)
+	}
+	return name, nil
+}
+
+// Profile returns the name of the active profile.
+func (cfg *Config) Profile() string {
+	return cfg.profile
+}
+
+// ProfileInfo describes a profile for `gator profile list`.
+type ProfileInfo struct {
+	Name		string
+	DBUrl		string		// with any password masked
+	CurrentUser	string
+	Active		bool
+}
+
+// Profiles lists the profiles in the config file by name. The default
+// profile is included even if nothing has been saved to it yet.
+func (cfg *Config) Profiles() ([]ProfileInfo, error) {
+	file, err := readFile(cfg.path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	if _, ok := file.Profiles[defaultProfile]; !ok {
+		file.Profiles[defaultProfile] = Profile{}
+	}
+
+	var profiles []ProfileInfo
+	for name, p := range file.Profiles {
+		if p.DBUrl == "" {
+			p.DBUrl = defaultDBUrl()
+		}
+		profiles = append(profiles, ProfileInfo{
+			Name: name,
+			DBUrl: redactURL(p.DBUrl),
+			CurrentUser: p.CurrentUser,
+			Active: name == cfg.profile,
+		})
+	}
+	sort.Slice(profiles, func(i, j int) bool {
+		return profiles[i].Name < profiles[j].Name
+	})
+	return profiles, nil
+}
+
+// AddProfile creates a profile using the database at dbURL. Its fetch
+// settings start out empty, and nobody is logged in.
+func (cfg *Config) AddProfile(name, dbURL string) error {
+	if name == "" {
+		return fmt.Errorf("Profile name can't be empty")
+	}
+	return cfg.updateFile(func(file *fileConfig) error {
+		if _, ok := file.Profiles[name]; ok {
+			return fmt.Errorf("Profile %q already exists", name)
+		}
+		file.Profiles[name] = Profile{DBUrl: dbURL}
+		return nil
+	})
+}
+
+// UseProfile makes the named profile the one used when neither --profile
+// nor $GATOR_PROFILE chooses another.
+func (cfg *Config) UseProfile(name string) error {
+	return cfg.updateFile(func(file *fileConfig) error {
+		if _, ok := file.Profiles[name]; !ok && name != defaultProfile {
+			return fmt.Errorf("Profile %q doesn't exist", name)
+		}
+		file.ActiveProfile = name
+		return nil
+	})
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// dbPasswordEnv names the environment variable that supplies the database
+// password, for secret managers and service runners that pass secrets in
+// the environment.
+const dbPasswordEnv = "GATOR_DB_PASSWORD"
+
+// DatabaseURL returns db_url with the password taken from $GATOR_DB_PASSWORD
+// or, failing that, the file named by db_password_file, so the password
+// needn't be stored in the config file. A password already in db_url is
+// replaced. SQLite URLs are returned as they are, since they take no password.
+//
+// The password can only be put into a postgres:// URL, so any other db_url,
+// such as a key=value connection string, is an error when a password is given.
+func (cfg *Config) DatabaseURL() (string, error) {
+	source := "$" + dbPasswordEnv
+	password, ok := os.LookupEnv(dbPasswordEnv)
+	if !ok && cfg.DBPasswordFile != "" {
+		path, err := expandHome(cfg.DBPasswordFile)
+		if err != nil {
+			return "", err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("Error reading database password file: %w", err)
+		}
+		source = "db_password_file"
+		password, ok = strings.TrimRight(string(data), "\r\n"), true
+	}
+	if !ok {
+		return cfg.DBUrl, nil
+	}
+
+	scheme, _, _ := strings.Cut(cfg.DBUrl, ":")
+	switch strings.ToLower(scheme) {
+	case "postgres", "postgresql":
+		u, err := url.Parse(cfg.DBUrl)
+		if err != nil {
+			return "", fmt.Errorf("Invalid db_url: %w", err)
+		}
+		u.User = url.UserPassword(u.User.Username(), password)
+		return u.String(), nil
+	case "sqlite", "sqlite3", "file":
+		return cfg.DBUrl, nil
+	default:
+		return "", fmt.Errorf("Can't apply the database password from %s: db_url must be a postgres:// URL to take one", source)
+	}
+}
@@ -0,0 +1,160 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// setting is one configurable value, named by its key in the config file
+// with sections joined by dots, e.g. fetch.timeout.
+type setting struct {
+	Key		string
+	value	any		// *string, *int or *int64 inside a Config
+}
+
+// settings lists every setting of cfg, in the order they are shown.
+// The current user and session token are managed by login and logout.
+func (cfg *Config) settings() []setting {
+	return []setting{
+		{"db_url", &cfg.DBUrl},
+		{"db_password_file", &cfg.DBPasswordFile},
+		{"fetch.timeout", &cfg.Fetch.Timeout},
+		{"fetch.connect_timeout", &cfg.Fetch.ConnectTimeout},
+		{"fetch.read_timeout", &cfg.Fetch.ReadTimeout},
+		{"fetch.max_body_bytes", &cfg.Fetch.MaxBodyBytes},
+		{"fetch.max_redirects", &cfg.Fetch.MaxRedirects},
+		{"fetch.user_agent", &cfg.Fetch.UserAgent},
+		{"fetch.contact_url", &cfg.Fetch.ContactURL},
+		{"fetch.proxy", &cfg.Fetch.Proxy},
+		{"retention.max_age", &cfg.Retention.MaxAge},
+		{"retention.max_posts_per_feed", &cfg.Retention.MaxPostsPerFeed},
+		{"retention.prune_interval", &cfg.Retention.PruneInterval},
+	}
+}
+
+// Keys returns the keys of every setting.
+func Keys() []string {
+	var keys []string
+	for _, s := range (&Config{}).settings() {
+		keys = append(keys, s.Key)
+	}
+	return keys
+}
+
+// envVar returns the environment variable that overrides the setting,
+// e.g. GATOR_FETCH_TIMEOUT for fetch.timeout.
+func (s setting) envVar() string {
+	return "GATOR_" + strings.ToUpper(strings.ReplaceAll(s.Key, ".", "_"))
+}
+
+// String returns the value, or "" if it isn't set.
+func (s setting) String() string {
+	switch v := s.value.(type) {
+	case *string:
+		return *v
+	case *int:
+		if *v != 0 {
+			return strconv.Itoa(*v)
+		}
+	case *int64:
+		if *v != 0 {
+			return strconv.FormatInt(*v, 10)
+		}
+	}
+	return ""
+}
+
+func (s setting) isSet() bool {
+	return s.String() != ""
+}
+
+// parse stores value in the setting. An empty value clears it.
+func (s setting) parse(value string) error {
+	switch v := s.value.(type) {
+	case *string:
+		*v = value
+	case *int:
+		n, err := parseNumber(s.Key, value)
+		*v = int(n)
+		return err
+	case *int64:
+		n, err := parseNumber(s.Key, value)
+		*v = n
+		return err
+	}
+	return nil
+}
+
+func parseNumber(key, value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s must be a whole number, not %q", key, value)
+	}
+	return n, nil
+}
+
+// SettingValue is a setting as shown by `gator config show`.
+type SettingValue struct {
+	Key		string
+	Value	string		// passwords in db_url are masked
+	Source	string		// the config file, an environment variable, "command line" or "default"
+}
+
+// Values returns every setting with its value and where it came from.
+func (cfg *Config) Values() []SettingValue {
+	var values []SettingValue
+	for _, s := range cfg.settings() {
+		value := s.String()
+		if s.Key == "db_url" {
+			value = redactURL(value)
+		}
+		values = append(values, SettingValue{Key: s.Key, Value: value, Source: cfg.Source(s.Key)})
+	}
+	return values
+}
+
+// Overridden reports whether an environment variable or a flag set the
+// setting, so that saving it in the config file has no effect.
+func (cfg *Config) Overridden(key string) bool {
+	source := cfg.sources[key]
+	return source != "" && source != cfg.path
+}
+
+// Source returns where a setting came from, as in SettingValue.
+func (cfg *Config) Source(key string) string {
+	if source, ok := cfg.sources[key]; ok {
+		return source
+	}
+	return "default"
+}
+
+// set parses value into the setting named key and records its source.
+func (cfg *Config) set(key, value, source string) error {
+	for _, s := range cfg.settings() {
+		if s.Key != key {
+			continue
+		}
+		if err := s.parse(value); err != nil {
+			return err
+		}
+		if cfg.sources != nil {
+			cfg.sources[key] = source
+		}
+		return nil
+	}
+	return fmt.Errorf("Unknown setting %q; settings are %s", key, strings.Join(Keys(), ", "))
+}
+
+// redactURL hides the password in a database URL.
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	return u.Redacted()
+}
@@ -7,6 +7,7 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
@@ -14,17 +15,18 @@ import (
 
 const createFeedFollow = `-- name: CreateFeedFollow :one
 WITH inserted_feed_follow AS (
-    INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id)
+    INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id, folder_id)
     VALUES (
         $1,
         $2,
         $3,
         $4,
-        $5
-    ) RETURNING id, created_at, updated_at, user_id, feed_id
+        $5,
+        $6
+    ) RETURNING id, created_at, updated_at, user_id, feed_id, folder_id, display_name
 )
 SELECT
-    inserted_feed_follow.id, inserted_feed_follow.created_at, inserted_feed_follow.updated_at, inserted_feed_follow.user_id, inserted_feed_follow.feed_id,
+    inserted_feed_follow.id, inserted_feed_follow.created_at, inserted_feed_follow.updated_at, inserted_feed_follow.user_id, inserted_feed_follow.feed_id, inserted_feed_follow.folder_id, inserted_feed_follow.display_name,
     feeds.name AS feed_name,
     users.name AS user_name
 FROM inserted_feed_follow
@@ -38,16 +40,19 @@ type CreateFeedFollowParams struct {
 	UpdatedAt time.Time
 	UserID    uuid.UUID
 	FeedID    uuid.UUID
+	FolderID  uuid.NullUUID
 }
 
 type CreateFeedFollowRow struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	UserID    uuid.UUID
-	FeedID    uuid.UUID
-	FeedName  string
-	UserName  string
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	UserID      uuid.UUID
+	FeedID      uuid.UUID
+	FolderID    uuid.NullUUID
+	DisplayName sql.NullString
+	FeedName    string
+	UserName    string
 }
 
 func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowParams) (CreateFeedFollowRow, error) {
@@ -57,6 +62,7 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 		arg.UpdatedAt,
 		arg.UserID,
 		arg.FeedID,
+		arg.FolderID,
 	)
 	var i CreateFeedFollowRow
 	err := row.Scan(
@@ -65,6 +71,8 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 		&i.UpdatedAt,
 		&i.UserID,
 		&i.FeedID,
+		&i.FolderID,
+		&i.DisplayName,
 		&i.FeedName,
 		&i.UserName,
 	)
@@ -72,16 +80,20 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 }
 
 const getFeedFollowsForUser = `-- name: GetFeedFollowsForUser :many
-SELECT feeds.name AS name, users.name AS user_name
+SELECT COALESCE(feed_follows.display_name, feeds.name) AS name, feeds.url AS url, users.name AS user_name, folders.name AS folder_name
 FROM feed_follows
 INNER JOIN feeds ON feed_follows.feed_id = feeds.id
 INNER JOIN users ON feed_follows.user_id = users.id
+LEFT JOIN folders ON feed_follows.folder_id = folders.id
 WHERE feed_follows.user_id = $1
+ORDER BY folders.name ASC NULLS FIRST, COALESCE(feed_follows.display_name, feeds.name) ASC
 `
 
 type GetFeedFollowsForUserRow struct {
-	Name     string
-	UserName string
+	Name       string
+	Url        string
+	UserName   string
+	FolderName sql.NullString
 }
 
 func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]GetFeedFollowsForUserRow, error) {
@@ -93,7 +105,12 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 	var items []GetFeedFollowsForUserRow
 	for rows.Next() {
 		var i GetFeedFollowsForUserRow
-		if err := rows.Scan(&i.Name, &i.UserName); err != nil {
+		if err := rows.Scan(
+			&i.Name,
+			&i.Url,
+			&i.UserName,
+			&i.FolderName,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -107,10 +124,57 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 	return items, nil
 }
 
+const setFeedFollowDisplayName = `-- name: SetFeedFollowDisplayName :execrows
+UPDATE feed_follows
+SET display_name = $3, updated_at = NOW()
+FROM feeds
+WHERE feed_follows.feed_id = feeds.id
+AND feed_follows.user_id = $1
+AND feeds.url = $2
+`
+
+type SetFeedFollowDisplayNameParams struct {
+	UserID      uuid.UUID
+	Url         string
+	DisplayName sql.NullString
+}
+
+func (q *Queries) SetFeedFollowDisplayName(ctx context.Context, arg SetFeedFollowDisplayNameParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setFeedFollowDisplayName, arg.UserID, arg.Url, arg.DisplayName)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setFeedFollowFolder = `-- name: SetFeedFollowFolder :execrows
+UPDATE feed_follows
+SET folder_id = $3, updated_at = NOW()
+FROM feeds
+WHERE feed_follows.feed_id = feeds.id
+AND feed_follows.user_id = $1
+AND feeds.url = $2
+`
+
+type SetFeedFollowFolderParams struct {
+	UserID   uuid.UUID
+	Url      string
+	FolderID uuid.NullUUID
+}
+
+func (q *Queries) SetFeedFollowFolder(ctx context.Context, arg SetFeedFollowFolderParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setFeedFollowFolder, arg.UserID, arg.Url, arg.FolderID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const unfollowFeed = `-- name: UnfollowFeed :exec
 DELETE FROM feed_follows
 USING feeds, users
 WHERE feed_follows.feed_id = feeds.id
+AND feed_follows.user_id = users.id
 AND users.id = $1
 AND feeds.url = $2
 `
@@ -0,0 +1,165 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: feed_url_history.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+const deleteFeed = `-- name: DeleteFeed :exec
+DELETE FROM feeds WHERE id = $1
+`
+
+func (q *Queries) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteFeed, id)
+	return err
+}
+
+const getFeedURLHistory = `-- name: GetFeedURLHistory :many
+SELECT id, changed_at, feed_id, old_url, new_url, merged FROM feed_url_history
+WHERE feed_id = $1
+ORDER BY changed_at ASC
+`
+
+func (q *Queries) GetFeedURLHistory(ctx context.Context, feedID uuid.UUID) ([]FeedUrlHistory, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedURLHistory, feedID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FeedUrlHistory
+	for rows.Next() {
+		var i FeedUrlHistory
+		if err := rows.Scan(
+			&i.ID,
+			&i.ChangedAt,
+			&i.FeedID,
+			&i.OldUrl,
+			&i.NewUrl,
+			&i.Merged,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const moveFeedFilterRules = `-- name: MoveFeedFilterRules :exec
+UPDATE filter_rules
+SET feed_id = $1, updated_at = NOW()
+WHERE feed_id = $2
+`
+
+type MoveFeedFilterRulesParams struct {
+	ToFeedID   uuid.NullUUID
+	FromFeedID uuid.NullUUID
+}
+
+func (q *Queries) MoveFeedFilterRules(ctx context.Context, arg MoveFeedFilterRulesParams) error {
+	_, err := q.db.ExecContext(ctx, moveFeedFilterRules, arg.ToFeedID, arg.FromFeedID)
+	return err
+}
+
+const moveFeedFollows = `-- name: MoveFeedFollows :exec
+UPDATE feed_follows
+SET feed_id = $1, updated_at = NOW()
+WHERE feed_follows.feed_id = $2
+AND feed_follows.user_id NOT IN (
+    SELECT existing.user_id FROM feed_follows AS existing
+    WHERE existing.feed_id = $1
+)
+`
+
+type MoveFeedFollowsParams struct {
+	ToFeedID   uuid.UUID
+	FromFeedID uuid.UUID
+}
+
+func (q *Queries) MoveFeedFollows(ctx context.Context, arg MoveFeedFollowsParams) error {
+	_, err := q.db.ExecContext(ctx, moveFeedFollows, arg.ToFeedID, arg.FromFeedID)
+	return err
+}
+
+const moveFeedPosts = `-- name: MoveFeedPosts :exec
+UPDATE posts
+SET feed_id = $1
+WHERE feed_id = $2
+`
+
+type MoveFeedPostsParams struct {
+	ToFeedID   uuid.UUID
+	FromFeedID uuid.UUID
+}
+
+func (q *Queries) MoveFeedPosts(ctx context.Context, arg MoveFeedPostsParams) error {
+	_, err := q.db.ExecContext(ctx, moveFeedPosts, arg.ToFeedID, arg.FromFeedID)
+	return err
+}
+
+const moveFeedURLHistory = `-- name: MoveFeedURLHistory :exec
+UPDATE feed_url_history
+SET feed_id = $1
+WHERE feed_id = $2
+`
+
+type MoveFeedURLHistoryParams struct {
+	ToFeedID   uuid.UUID
+	FromFeedID uuid.UUID
+}
+
+func (q *Queries) MoveFeedURLHistory(ctx context.Context, arg MoveFeedURLHistoryParams) error {
+	_, err := q.db.ExecContext(ctx, moveFeedURLHistory, arg.ToFeedID, arg.FromFeedID)
+	return err
+}
+
+const recordFeedURLChange = `-- name: RecordFeedURLChange :exec
+INSERT INTO feed_url_history (id, changed_at, feed_id, old_url, new_url, merged)
+VALUES ($1, NOW(), $2, $3, $4, $5)
+`
+
+type RecordFeedURLChangeParams struct {
+	ID     uuid.UUID
+	FeedID uuid.UUID
+	OldUrl string
+	NewUrl string
+	Merged bool
+}
+
+func (q *Queries) RecordFeedURLChange(ctx context.Context, arg RecordFeedURLChangeParams) error {
+	_, err := q.db.ExecContext(ctx, recordFeedURLChange,
+		arg.ID,
+		arg.FeedID,
+		arg.OldUrl,
+		arg.NewUrl,
+		arg.Merged,
+	)
+	return err
+}
+
+const setFeedURL = `-- name: SetFeedURL :exec
+UPDATE feeds
+SET url = $2, updated_at = NOW()
+WHERE id = $1
+`
+
+type SetFeedURLParams struct {
+	ID  uuid.UUID
+	Url string
+}
+
+func (q *Queries) SetFeedURL(ctx context.Context, arg SetFeedURLParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedURL, arg.ID, arg.Url)
+	return err
+}
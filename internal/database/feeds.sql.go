@@ -7,6 +7,7 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
@@ -22,7 +23,7 @@ VALUES(
     $5,
     $6
 )
-RETURNING id, created_at, updated_at, last_fetched_at, name, url, user_id
+RETURNING id, created_at, updated_at, last_fetched_at, name, url, user_id, fetch_full_text, fetch_interval, adaptive_interval, next_fetch_at, ttl_minutes, skip_hours, skip_days, cache_max_age, retry_after, parse_warnings, retention_max_age, retention_max_posts
 `
 
 type AddFeedParams struct {
@@ -52,12 +53,79 @@ func (q *Queries) AddFeed(ctx context.Context, arg AddFeedParams) (Feed, error)
 		&i.Name,
 		&i.Url,
 		&i.UserID,
+		&i.FetchFullText,
+		&i.FetchInterval,
+		&i.AdaptiveInterval,
+		&i.NextFetchAt,
+		&i.TtlMinutes,
+		&i.SkipHours,
+		&i.SkipDays,
+		&i.CacheMaxAge,
+		&i.RetryAfter,
+		&i.ParseWarnings,
+		&i.RetentionMaxAge,
+		&i.RetentionMaxPosts,
 	)
 	return i, err
 }
 
+const deleteFeedIfOrphaned = `-- name: DeleteFeedIfOrphaned :execrows
+DELETE FROM feeds
+WHERE feeds.url = $1
+AND NOT EXISTS (SELECT 1 FROM feed_follows WHERE feed_follows.feed_id = feeds.id)
+AND NOT EXISTS (
+    SELECT 1 FROM saved_posts
+    INNER JOIN posts ON saved_posts.post_id = posts.id
+    WHERE posts.feed_id = feeds.id
+)
+`
+
+// A feed holding posts someone saved is kept, or the saves would cascade away
+func (q *Queries) DeleteFeedIfOrphaned(ctx context.Context, url string) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteFeedIfOrphaned, url)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteOrphanedFeeds = `-- name: DeleteOrphanedFeeds :many
+DELETE FROM feeds
+WHERE NOT EXISTS (SELECT 1 FROM feed_follows WHERE feed_follows.feed_id = feeds.id)
+AND NOT EXISTS (
+    SELECT 1 FROM saved_posts
+    INNER JOIN posts ON saved_posts.post_id = posts.id
+    WHERE posts.feed_id = feeds.id
+)
+RETURNING url
+`
+
+// A feed holding posts someone saved is kept, or the saves would cascade away
+func (q *Queries) DeleteOrphanedFeeds(ctx context.Context) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, deleteOrphanedFeeds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var url string
+		if err := rows.Scan(&url); err != nil {
+			return nil, err
+		}
+		items = append(items, url)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const findFeedsByURL = `-- name: FindFeedsByURL :one
-SELECT id, created_at, updated_at, last_fetched_at, name, url, user_id FROM feeds WHERE url = $1
+SELECT id, created_at, updated_at, last_fetched_at, name, url, user_id, fetch_full_text, fetch_interval, adaptive_interval, next_fetch_at, ttl_minutes, skip_hours, skip_days, cache_max_age, retry_after, parse_warnings, retention_max_age, retention_max_posts FROM feeds WHERE url = $1
 `
 
 func (q *Queries) FindFeedsByURL(ctx context.Context, url string) (Feed, error) {
@@ -71,20 +139,58 @@ func (q *Queries) FindFeedsByURL(ctx context.Context, url string) (Feed, error)
 		&i.Name,
 		&i.Url,
 		&i.UserID,
+		&i.FetchFullText,
+		&i.FetchInterval,
+		&i.AdaptiveInterval,
+		&i.NextFetchAt,
+		&i.TtlMinutes,
+		&i.SkipHours,
+		&i.SkipDays,
+		&i.CacheMaxAge,
+		&i.RetryAfter,
+		&i.ParseWarnings,
+		&i.RetentionMaxAge,
+		&i.RetentionMaxPosts,
 	)
 	return i, err
 }
 
+const getFeedUsage = `-- name: GetFeedUsage :one
+SELECT
+    (SELECT COUNT(*) FROM feed_follows WHERE feed_follows.feed_id = $1) AS followers,
+    (SELECT COUNT(*) FROM posts WHERE posts.feed_id = $1) AS posts,
+    (SELECT COUNT(*) FROM filter_rules WHERE filter_rules.feed_id = $1) AS rules
+`
+
+type GetFeedUsageRow struct {
+	Followers int64
+	Posts     int64
+	Rules     int64
+}
+
+func (q *Queries) GetFeedUsage(ctx context.Context, feedID uuid.UUID) (GetFeedUsageRow, error) {
+	row := q.db.QueryRowContext(ctx, getFeedUsage, feedID)
+	var i GetFeedUsageRow
+	err := row.Scan(&i.Followers, &i.Posts, &i.Rules)
+	return i, err
+}
+
 const printAllFeeds = `-- name: PrintAllFeeds :many
-SELECT feeds.name AS feed_name, feeds.url AS feed_url, users.name AS user_name
+SELECT feeds.name AS feed_name, feeds.url AS feed_url, users.name AS user_name, feeds.fetch_interval, feeds.adaptive_interval, feeds.parse_warnings,
+    feeds.retention_max_age, feeds.retention_max_posts
 FROM feeds
 INNER JOIN users ON feeds.user_id = users.id
 `
 
 type PrintAllFeedsRow struct {
-	FeedName string
-	FeedUrl  string
-	UserName string
+	FeedName          string
+	FeedUrl           string
+	UserName          string
+	FetchInterval     sql.NullInt32
+	AdaptiveInterval  bool
+	ParseWarnings     sql.NullString
+	RetentionMaxAge   sql.NullInt32
+	RetentionMaxPosts sql.NullInt32
 }
 
 func (q *Queries) PrintAllFeeds(ctx context.Context) ([]PrintAllFeedsRow, error) {
@@ -96,7 +202,16 @@ func (q *Queries) PrintAllFeeds(ctx context.Context) ([]PrintAllFeedsRow, error)
 	var items []PrintAllFeedsRow
 	for rows.Next() {
 		var i PrintAllFeedsRow
-		if err := rows.Scan(&i.FeedName, &i.FeedUrl, &i.UserName); err != nil {
+		if err := rows.Scan(
+			&i.FeedName,
+			&i.FeedUrl,
+			&i.UserName,
+			&i.FetchInterval,
+			&i.AdaptiveInterval,
+			&i.ParseWarnings,
+			&i.RetentionMaxAge,
+			&i.RetentionMaxPosts,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -109,3 +224,51 @@ func (q *Queries) PrintAllFeeds(ctx context.Context) ([]PrintAllFeedsRow, error)
 	}
 	return items, nil
 }
+
+const reassignFeeds = `-- name: ReassignFeeds :exec
+UPDATE feeds
+SET user_id = $1, updated_at = NOW()
+WHERE user_id = $2
+`
+
+type ReassignFeedsParams struct {
+	ToUserID   uuid.UUID
+	FromUserID uuid.UUID
+}
+
+func (q *Queries) ReassignFeeds(ctx context.Context, arg ReassignFeedsParams) error {
+	_, err := q.db.ExecContext(ctx, reassignFeeds, arg.ToUserID, arg.FromUserID)
+	return err
+}
+
+const setFeedFullText = `-- name: SetFeedFullText :exec
+UPDATE feeds
+SET fetch_full_text = $2, updated_at = NOW()
+WHERE id = $1
+`
+
+type SetFeedFullTextParams struct {
+	ID            uuid.UUID
+	FetchFullText bool
+}
+
+func (q *Queries) SetFeedFullText(ctx context.Context, arg SetFeedFullTextParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedFullText, arg.ID, arg.FetchFullText)
+	return err
+}
+
+const setFeedOwner = `-- name: SetFeedOwner :exec
+UPDATE feeds
+SET user_id = $2, updated_at = NOW()
+WHERE id = $1
+`
+
+type SetFeedOwnerParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) SetFeedOwner(ctx context.Context, arg SetFeedOwnerParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedOwner, arg.ID, arg.UserID)
+	return err
+}
@@ -7,35 +7,129 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+const deferFeedFetch = `-- name: DeferFeedFetch :exec
+UPDATE feeds
+SET next_fetch_at = $2, retry_after = $3, updated_at = NOW()
+WHERE id = $1
+`
+
+type DeferFeedFetchParams struct {
+	ID          uuid.UUID
+	NextFetchAt sql.NullTime
+	RetryAfter  sql.NullTime
+}
+
+func (q *Queries) DeferFeedFetch(ctx context.Context, arg DeferFeedFetchParams) error {
+	_, err := q.db.ExecContext(ctx, deferFeedFetch, arg.ID, arg.NextFetchAt, arg.RetryAfter)
+	return err
+}
+
 const getNextFeedToFetch = `-- name: GetNextFeedToFetch :one
-SELECT id, url FROM feeds
-ORDER BY last_fetched_at ASC NULLS FIRST
+SELECT id, created_at, updated_at, last_fetched_at, name, url, user_id, fetch_full_text, fetch_interval, adaptive_interval, next_fetch_at, ttl_minutes, skip_hours, skip_days, cache_max_age, retry_after, parse_warnings, retention_max_age, retention_max_posts FROM feeds
+WHERE (next_fetch_at IS NULL OR next_fetch_at <= $1::timestamp)
+AND (last_fetched_at IS NULL OR last_fetched_at < $2::timestamp)
+ORDER BY COALESCE(next_fetch_at, last_fetched_at) ASC NULLS FIRST
 LIMIT 1
 `
 
-type GetNextFeedToFetchRow struct {
-	ID  uuid.UUID
-	Url string
+type GetNextFeedToFetchParams struct {
+	Now           time.Time
+	FetchedBefore time.Time
 }
 
-func (q *Queries) GetNextFeedToFetch(ctx context.Context) (GetNextFeedToFetchRow, error) {
-	row := q.db.QueryRowContext(ctx, getNextFeedToFetch)
-	var i GetNextFeedToFetchRow
-	err := row.Scan(&i.ID, &i.Url)
+// Feeds without a scheduled fetch have been due since they were last fetched
+func (q *Queries) GetNextFeedToFetch(ctx context.Context, arg GetNextFeedToFetchParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getNextFeedToFetch, arg.Now, arg.FetchedBefore)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.LastFetchedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.FetchFullText,
+		&i.FetchInterval,
+		&i.AdaptiveInterval,
+		&i.NextFetchAt,
+		&i.TtlMinutes,
+		&i.SkipHours,
+		&i.SkipDays,
+		&i.CacheMaxAge,
+		&i.RetryAfter,
+		&i.ParseWarnings,
+		&i.RetentionMaxAge,
+		&i.RetentionMaxPosts,
+	)
 	return i, err
 }
 
 const markFeedFetched = `-- name: MarkFeedFetched :exec
 UPDATE feeds
-SET last_fetched_at = NOW(), updated_at = NOW()
+SET last_fetched_at = NOW(),
+    updated_at = NOW(),
+    next_fetch_at = $2,
+    fetch_interval = $3,
+    ttl_minutes = $4,
+    skip_hours = $5,
+    skip_days = $6,
+    cache_max_age = $7,
+    parse_warnings = $8,
+    retry_after = NULL
+WHERE id = $1
+`
+
+type MarkFeedFetchedParams struct {
+	ID            uuid.UUID
+	NextFetchAt   sql.NullTime
+	FetchInterval sql.NullInt32
+	TtlMinutes    sql.NullInt32
+	SkipHours     sql.NullString
+	SkipDays      sql.NullString
+	CacheMaxAge   sql.NullInt32
+	ParseWarnings sql.NullString
+}
+
+func (q *Queries) MarkFeedFetched(ctx context.Context, arg MarkFeedFetchedParams) error {
+	_, err := q.db.ExecContext(ctx, markFeedFetched,
+		arg.ID,
+		arg.NextFetchAt,
+		arg.FetchInterval,
+		arg.TtlMinutes,
+		arg.SkipHours,
+		arg.SkipDays,
+		arg.CacheMaxAge,
+		arg.ParseWarnings,
+	)
+	return err
+}
+
+const setFeedFetchInterval = `-- name: SetFeedFetchInterval :exec
+UPDATE feeds
+SET fetch_interval = $2, adaptive_interval = $3, next_fetch_at = $4, updated_at = NOW()
 WHERE id = $1
 `
 
-func (q *Queries) MarkFeedFetched(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.ExecContext(ctx, markFeedFetched, id)
+type SetFeedFetchIntervalParams struct {
+	ID               uuid.UUID
+	FetchInterval    sql.NullInt32
+	AdaptiveInterval bool
+	NextFetchAt      sql.NullTime
+}
+
+func (q *Queries) SetFeedFetchInterval(ctx context.Context, arg SetFeedFetchIntervalParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedFetchInterval,
+		arg.ID,
+		arg.FetchInterval,
+		arg.AdaptiveInterval,
+		arg.NextFetchAt,
+	)
 	return err
 }
@@ -12,21 +12,64 @@ import (
 )
 
 type Feed struct {
-	ID            uuid.UUID
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	LastFetchedAt sql.NullTime
-	Name          string
-	Url           string
-	UserID        uuid.UUID
+	ID                uuid.UUID
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	LastFetchedAt     sql.NullTime
+	Name              string
+	Url               string
+	UserID            uuid.UUID
+	FetchFullText     bool
+	FetchInterval     sql.NullInt32
+	AdaptiveInterval  bool
+	NextFetchAt       sql.NullTime
+	TtlMinutes        sql.NullInt32
+	SkipHours         sql.NullString
+	SkipDays          sql.NullString
+	CacheMaxAge       sql.NullInt32
+	RetryAfter        sql.NullTime
+	ParseWarnings     sql.NullString
+	RetentionMaxAge   sql.NullInt32
+	RetentionMaxPosts sql.NullInt32
 }
 
 type FeedFollow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	UserID      uuid.UUID
+	FeedID      uuid.UUID
+	FolderID    uuid.NullUUID
+	DisplayName sql.NullString
+}
+
+type FeedUrlHistory struct {
+	ID        uuid.UUID
+	ChangedAt time.Time
+	FeedID    uuid.UUID
+	OldUrl    string
+	NewUrl    string
+	Merged    bool
+}
+
+type FilterRule struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	UserID    uuid.UUID
-	FeedID    uuid.UUID
+	FeedID    uuid.NullUUID
+	Pattern   string
+	IsRegex   bool
+	Field     string
+	Action    string
+}
+
+type Folder struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Name      string
+	UserID    uuid.UUID
 }
 
 type Post struct {
@@ -38,11 +81,36 @@ type Post struct {
 	Description sql.NullString
 	PublishedAt time.Time
 	FeedID      uuid.UUID
+	Content     sql.NullString
+	Author      sql.NullString
+}
+
+type PostRead struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+	ReadAt time.Time
+}
+
+type SavedPost struct {
+	UserID  uuid.UUID
+	PostID  uuid.UUID
+	SavedAt time.Time
+}
+
+type Session struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	LastUsedAt time.Time
+	ExpiresAt  time.Time
+	UserID     uuid.UUID
+	TokenHash  string
 }
 
 type User struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	Name      string
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Name         string
+	PasswordHash sql.NullString
+	Role         string
 }
@@ -13,53 +13,26 @@ import (
 	"github.com/google/uuid"
 )
 
-const createPost = `-- name: CreatePost :exec
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
-VALUES (
-    $1,
-    NOW(),
-    NOW(),
-    $2,
-    $3,
-    $4,
-    $5,
-    $6
-)
-ON CONFLICT (url) DO NOTHING
-`
-
-type CreatePostParams struct {
-	ID          uuid.UUID
-	Title       string
-	Url         string
-	Description sql.NullString
-	PublishedAt time.Time
-	FeedID      uuid.UUID
-}
-
-func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) error {
-	_, err := q.db.ExecContext(ctx, createPost,
-		arg.ID,
-		arg.Title,
-		arg.Url,
-		arg.Description,
-		arg.PublishedAt,
-		arg.FeedID,
-	)
-	return err
-}
-
 const getPostsForUser = `-- name: GetPostsForUser :many
-SELECT posts.id, posts.created_at, posts.updated_at, title, url, description, published_at, posts.feed_id, feed_follows.id, feed_follows.created_at, feed_follows.updated_at, user_id, feed_follows.feed_id FROM posts
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.content, posts.author, COALESCE(feed_follows.display_name, feeds.name) AS feed_name, post_reads.read_at, saved_posts.saved_at
+FROM posts
 INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds ON posts.feed_id = feeds.id
+LEFT JOIN post_reads ON post_reads.post_id = posts.id AND post_reads.user_id = feed_follows.user_id
+LEFT JOIN saved_posts ON saved_posts.post_id = posts.id AND saved_posts.user_id = feed_follows.user_id
 WHERE feed_follows.user_id = $2
+AND ($3::uuid IS NULL OR feed_follows.folder_id = $3)
+AND (NOT $4::boolean OR saved_posts.saved_at IS NOT NULL)
 ORDER BY published_at DESC
-LIMIT $1
+LIMIT $1 OFFSET $5
 `
 
 type GetPostsForUserParams struct {
-	Limit  int32
-	UserID uuid.UUID
+	Limit     int32
+	UserID    uuid.UUID
+	FolderID  uuid.NullUUID
+	SavedOnly bool
+	Offset    int32
 }
 
 type GetPostsForUserRow struct {
@@ -71,15 +44,21 @@ type GetPostsForUserRow struct {
 	Description sql.NullString
 	PublishedAt time.Time
 	FeedID      uuid.UUID
-	ID_2        uuid.UUID
-	CreatedAt_2 time.Time
-	UpdatedAt_2 time.Time
-	UserID      uuid.UUID
-	FeedID_2    uuid.UUID
+	Content     sql.NullString
+	Author      sql.NullString
+	FeedName    string
+	ReadAt      sql.NullTime
+	SavedAt     sql.NullTime
 }
 
 func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]GetPostsForUserRow, error) {
-	rows, err := q.db.QueryContext(ctx, getPostsForUser, arg.Limit, arg.UserID)
+	rows, err := q.db.QueryContext(ctx, getPostsForUser,
+		arg.Limit,
+		arg.UserID,
+		arg.FolderID,
+		arg.SavedOnly,
+		arg.Offset,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -96,11 +75,11 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 			&i.Description,
 			&i.PublishedAt,
 			&i.FeedID,
-			&i.ID_2,
-			&i.CreatedAt_2,
-			&i.UpdatedAt_2,
-			&i.UserID,
-			&i.FeedID_2,
+			&i.Content,
+			&i.Author,
+			&i.FeedName,
+			&i.ReadAt,
+			&i.SavedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -114,3 +93,111 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 	}
 	return items, nil
 }
+
+const getRecentPostTimes = `-- name: GetRecentPostTimes :many
+SELECT published_at FROM posts
+WHERE feed_id = $1
+ORDER BY published_at DESC
+LIMIT $2
+`
+
+type GetRecentPostTimesParams struct {
+	FeedID uuid.UUID
+	Limit  int32
+}
+
+func (q *Queries) GetRecentPostTimes(ctx context.Context, arg GetRecentPostTimesParams) ([]time.Time, error) {
+	rows, err := q.db.QueryContext(ctx, getRecentPostTimes, arg.FeedID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []time.Time
+	for rows.Next() {
+		var published_at time.Time
+		if err := rows.Scan(&published_at); err != nil {
+			return nil, err
+		}
+		items = append(items, published_at)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setPostContent = `-- name: SetPostContent :exec
+UPDATE posts
+SET content = $2, updated_at = NOW()
+WHERE id = $1
+`
+
+type SetPostContentParams struct {
+	ID      uuid.UUID
+	Content sql.NullString
+}
+
+func (q *Queries) SetPostContent(ctx context.Context, arg SetPostContentParams) error {
+	_, err := q.db.ExecContext(ctx, setPostContent, arg.ID, arg.Content)
+	return err
+}
+
+const upsertPost = `-- name: UpsertPost :one
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, author)
+VALUES (
+    $1,
+    NOW(),
+    NOW(),
+    $2,
+    $3,
+    $4,
+    COALESCE($5::timestamp, NOW()),
+    $6,
+    $7
+)
+ON CONFLICT (url) DO UPDATE
+SET title = EXCLUDED.title,
+    description = EXCLUDED.description,
+    published_at = COALESCE($5::timestamp, posts.published_at),
+    author = EXCLUDED.author,
+    updated_at = NOW()
+WHERE posts.feed_id = EXCLUDED.feed_id
+AND (posts.title, posts.description, posts.published_at, posts.author)
+    IS DISTINCT FROM (EXCLUDED.title, EXCLUDED.description, COALESCE($5::timestamp, posts.published_at), EXCLUDED.author)
+RETURNING id, created_at = updated_at AS inserted
+`
+
+type UpsertPostParams struct {
+	ID          uuid.UUID
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt sql.NullTime
+	FeedID      uuid.UUID
+	Author      sql.NullString
+}
+
+type UpsertPostRow struct {
+	ID       uuid.UUID
+	Inserted bool
+}
+
+// A post without a usable date is dated when first seen and keeps that date
+// on later updates, so retention ages it like any other post
+func (q *Queries) UpsertPost(ctx context.Context, arg UpsertPostParams) (UpsertPostRow, error) {
+	row := q.db.QueryRowContext(ctx, upsertPost,
+		arg.ID,
+		arg.Title,
+		arg.Url,
+		arg.Description,
+		arg.PublishedAt,
+		arg.FeedID,
+		arg.Author,
+	)
+	var i UpsertPostRow
+	err := row.Scan(&i.ID, &i.Inserted)
+	return i, err
+}
@@ -0,0 +1,105 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Querier interface {
+	AddFeed(ctx context.Context, arg AddFeedParams) (Feed, error)
+	CountAdmins(ctx context.Context) (int64, error)
+	CreateFeedFollow(ctx context.Context, arg CreateFeedFollowParams) (CreateFeedFollowRow, error)
+	CreateFilterRule(ctx context.Context, arg CreateFilterRuleParams) (FilterRule, error)
+	CreateFolder(ctx context.Context, arg CreateFolderParams) (Folder, error)
+	CreateSession(ctx context.Context, arg CreateSessionParams) error
+	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	DeferFeedFetch(ctx context.Context, arg DeferFeedFetchParams) error
+	DeleteAllUsers(ctx context.Context) error
+	DeleteExpiredSessions(ctx context.Context, now time.Time) error
+	DeleteFeed(ctx context.Context, id uuid.UUID) error
+	// A feed holding posts someone saved is kept, or the saves would cascade away
+	DeleteFeedIfOrphaned(ctx context.Context, url string) (int64, error)
+	DeleteFeedPostsBeyond(ctx context.Context, arg DeleteFeedPostsBeyondParams) (int64, error)
+	DeleteFeedPostsPublishedBefore(ctx context.Context, arg DeleteFeedPostsPublishedBeforeParams) (int64, error)
+	DeleteFilterRule(ctx context.Context, arg DeleteFilterRuleParams) (int64, error)
+	DeleteFolder(ctx context.Context, arg DeleteFolderParams) (int64, error)
+	// A feed holding posts someone saved is kept, or the saves would cascade away
+	DeleteOrphanedFeeds(ctx context.Context) ([]string, error)
+	DeletePosts(ctx context.Context) (int64, error)
+	DeletePostsPublishedBefore(ctx context.Context, publishedAt time.Time) (int64, error)
+	DeleteSession(ctx context.Context, tokenHash string) error
+	DeleteUser(ctx context.Context, id uuid.UUID) error
+	FindFeedsByURL(ctx context.Context, url string) (Feed, error)
+	GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]GetFeedFollowsForUserRow, error)
+	GetFeedURLHistory(ctx context.Context, feedID uuid.UUID) ([]FeedUrlHistory, error)
+	GetFeedUsage(ctx context.Context, feedID uuid.UUID) (GetFeedUsageRow, error)
+	GetFilterRulesForUser(ctx context.Context, userID uuid.UUID) ([]GetFilterRulesForUserRow, error)
+	GetFolderByName(ctx context.Context, arg GetFolderByNameParams) (Folder, error)
+	GetFoldersForUser(ctx context.Context, userID uuid.UUID) ([]Folder, error)
+	// Feeds without a scheduled fetch have been due since they were last fetched
+	GetNextFeedToFetch(ctx context.Context, arg GetNextFeedToFetchParams) (Feed, error)
+	GetPostByURL(ctx context.Context, url string) (Post, error)
+	GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]GetPostsForUserRow, error)
+	GetRecentPostTimes(ctx context.Context, arg GetRecentPostTimesParams) ([]time.Time, error)
+	GetUser(ctx context.Context, name string) (User, error)
+	GetUserForSession(ctx context.Context, arg GetUserForSessionParams) (User, error)
+	GetUsers(ctx context.Context) ([]User, error)
+	ListFeedFollows(ctx context.Context) ([]FeedFollow, error)
+	ListFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]FeedFollow, error)
+	ListFeedURLHistory(ctx context.Context) ([]FeedUrlHistory, error)
+	ListFeeds(ctx context.Context) ([]Feed, error)
+	ListFilterRules(ctx context.Context) ([]FilterRule, error)
+	ListFilterRulesForUser(ctx context.Context, userID uuid.UUID) ([]FilterRule, error)
+	ListFolders(ctx context.Context) ([]Folder, error)
+	ListPostReads(ctx context.Context) ([]PostRead, error)
+	ListPostReadsForUser(ctx context.Context, userID uuid.UUID) ([]PostRead, error)
+	ListPostReadsPublishedBefore(ctx context.Context, publishedAt time.Time) ([]PostRead, error)
+	ListPosts(ctx context.Context) ([]Post, error)
+	ListPostsPublishedBefore(ctx context.Context, publishedAt time.Time) ([]Post, error)
+	ListSavedPosts(ctx context.Context) ([]SavedPost, error)
+	ListSavedPostsForUser(ctx context.Context, userID uuid.UUID) ([]SavedPost, error)
+	ListSavedPostsPublishedBefore(ctx context.Context, publishedAt time.Time) ([]SavedPost, error)
+	MarkFeedFetched(ctx context.Context, arg MarkFeedFetchedParams) error
+	MarkPostRead(ctx context.Context, arg MarkPostReadParams) error
+	MoveFeedFilterRules(ctx context.Context, arg MoveFeedFilterRulesParams) error
+	MoveFeedFollows(ctx context.Context, arg MoveFeedFollowsParams) error
+	MoveFeedPosts(ctx context.Context, arg MoveFeedPostsParams) error
+	MoveFeedURLHistory(ctx context.Context, arg MoveFeedURLHistoryParams) error
+	PrintAllFeeds(ctx context.Context) ([]PrintAllFeedsRow, error)
+	ReassignFeeds(ctx context.Context, arg ReassignFeedsParams) error
+	RecordFeedURLChange(ctx context.Context, arg RecordFeedURLChangeParams) error
+	RestoreFeed(ctx context.Context, arg RestoreFeedParams) (int64, error)
+	RestoreFeedFollow(ctx context.Context, arg RestoreFeedFollowParams) (int64, error)
+	RestoreFeedURLChange(ctx context.Context, arg RestoreFeedURLChangeParams) (int64, error)
+	RestoreFilterRule(ctx context.Context, arg RestoreFilterRuleParams) (int64, error)
+	RestoreFolder(ctx context.Context, arg RestoreFolderParams) (int64, error)
+	RestorePost(ctx context.Context, arg RestorePostParams) (int64, error)
+	RestorePostRead(ctx context.Context, arg RestorePostReadParams) (int64, error)
+	RestoreSavedPost(ctx context.Context, arg RestoreSavedPostParams) (int64, error)
+	RestoreUser(ctx context.Context, arg RestoreUserParams) (int64, error)
+	SavePost(ctx context.Context, arg SavePostParams) error
+	SetFeedFetchInterval(ctx context.Context, arg SetFeedFetchIntervalParams) error
+	SetFeedFollowDisplayName(ctx context.Context, arg SetFeedFollowDisplayNameParams) (int64, error)
+	SetFeedFollowFolder(ctx context.Context, arg SetFeedFollowFolderParams) (int64, error)
+	SetFeedFullText(ctx context.Context, arg SetFeedFullTextParams) error
+	SetFeedOwner(ctx context.Context, arg SetFeedOwnerParams) error
+	SetFeedRetention(ctx context.Context, arg SetFeedRetentionParams) error
+	SetFeedURL(ctx context.Context, arg SetFeedURLParams) error
+	SetPostContent(ctx context.Context, arg SetPostContentParams) error
+	SetUserPassword(ctx context.Context, arg SetUserPasswordParams) error
+	SetUserRole(ctx context.Context, arg SetUserRoleParams) error
+	TouchSession(ctx context.Context, tokenHash string) error
+	UnfollowFeed(ctx context.Context, arg UnfollowFeedParams) error
+	UnsavePost(ctx context.Context, arg UnsavePostParams) (int64, error)
+	// A post without a usable date is dated when first seen and keeps that date
+	// on later updates, so retention ages it like any other post
+	UpsertPost(ctx context.Context, arg UpsertPostParams) (UpsertPostRow, error)
+}
+
+var _ Querier = (*Queries)(nil)
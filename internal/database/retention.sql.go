@@ -0,0 +1,132 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: retention.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const deleteFeedPostsBeyond = `-- name: DeleteFeedPostsBeyond :execrows
+DELETE FROM posts
+WHERE posts.feed_id = $1
+AND NOT EXISTS (SELECT 1 FROM saved_posts WHERE saved_posts.post_id = posts.id)
+AND posts.id NOT IN (
+    SELECT newest.id FROM posts AS newest
+    WHERE newest.feed_id = $1
+    ORDER BY newest.published_at DESC, newest.created_at DESC
+    LIMIT $2
+)
+`
+
+type DeleteFeedPostsBeyondParams struct {
+	FeedID uuid.UUID
+	Keep   int32
+}
+
+func (q *Queries) DeleteFeedPostsBeyond(ctx context.Context, arg DeleteFeedPostsBeyondParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteFeedPostsBeyond, arg.FeedID, arg.Keep)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteFeedPostsPublishedBefore = `-- name: DeleteFeedPostsPublishedBefore :execrows
+DELETE FROM posts
+WHERE posts.feed_id = $1
+AND posts.published_at < $2
+AND NOT EXISTS (SELECT 1 FROM saved_posts WHERE saved_posts.post_id = posts.id)
+`
+
+type DeleteFeedPostsPublishedBeforeParams struct {
+	FeedID      uuid.UUID
+	PublishedAt time.Time
+}
+
+func (q *Queries) DeleteFeedPostsPublishedBefore(ctx context.Context, arg DeleteFeedPostsPublishedBeforeParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteFeedPostsPublishedBefore, arg.FeedID, arg.PublishedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getPostByURL = `-- name: GetPostByURL :one
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, content, author FROM posts WHERE url = $1
+`
+
+func (q *Queries) GetPostByURL(ctx context.Context, url string) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPostByURL, url)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Content,
+		&i.Author,
+	)
+	return i, err
+}
+
+const savePost = `-- name: SavePost :exec
+INSERT INTO saved_posts (user_id, post_id, saved_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING
+`
+
+type SavePostParams struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+}
+
+func (q *Queries) SavePost(ctx context.Context, arg SavePostParams) error {
+	_, err := q.db.ExecContext(ctx, savePost, arg.UserID, arg.PostID)
+	return err
+}
+
+const setFeedRetention = `-- name: SetFeedRetention :exec
+UPDATE feeds
+SET retention_max_age = $2, retention_max_posts = $3, updated_at = NOW()
+WHERE id = $1
+`
+
+type SetFeedRetentionParams struct {
+	ID                uuid.UUID
+	RetentionMaxAge   sql.NullInt32
+	RetentionMaxPosts sql.NullInt32
+}
+
+func (q *Queries) SetFeedRetention(ctx context.Context, arg SetFeedRetentionParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedRetention, arg.ID, arg.RetentionMaxAge, arg.RetentionMaxPosts)
+	return err
+}
+
+const unsavePost = `-- name: UnsavePost :execrows
+DELETE FROM saved_posts
+WHERE user_id = $1 AND post_id = $2
+`
+
+type UnsavePostParams struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+}
+
+func (q *Queries) UnsavePost(ctx context.Context, arg UnsavePostParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, unsavePost, arg.UserID, arg.PostID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,98 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: sessions.sql
+
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const createSession = `-- name: CreateSession :exec
+INSERT INTO sessions (id, created_at, last_used_at, expires_at, user_id, token_hash)
+VALUES (
+    $1,
+    NOW(),
+    NOW(),
+    $2,
+    $3,
+    $4
+)
+`
+
+type CreateSessionParams struct {
+	ID        uuid.UUID
+	ExpiresAt time.Time
+	UserID    uuid.UUID
+	TokenHash string
+}
+
+func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) error {
+	_, err := q.db.ExecContext(ctx, createSession,
+		arg.ID,
+		arg.ExpiresAt,
+		arg.UserID,
+		arg.TokenHash,
+	)
+	return err
+}
+
+const deleteExpiredSessions = `-- name: DeleteExpiredSessions :exec
+DELETE FROM sessions WHERE expires_at <= $1::timestamp
+`
+
+func (q *Queries) DeleteExpiredSessions(ctx context.Context, now time.Time) error {
+	_, err := q.db.ExecContext(ctx, deleteExpiredSessions, now)
+	return err
+}
+
+const deleteSession = `-- name: DeleteSession :exec
+DELETE FROM sessions WHERE token_hash = $1
+`
+
+func (q *Queries) DeleteSession(ctx context.Context, tokenHash string) error {
+	_, err := q.db.ExecContext(ctx, deleteSession, tokenHash)
+	return err
+}
+
+const getUserForSession = `-- name: GetUserForSession :one
+SELECT users.id, users.created_at, users.updated_at, users.name, users.password_hash, users.role
+FROM sessions
+INNER JOIN users ON sessions.user_id = users.id
+WHERE sessions.token_hash = $1
+AND sessions.expires_at > $2::timestamp
+`
+
+type GetUserForSessionParams struct {
+	TokenHash string
+	Now       time.Time
+}
+
+func (q *Queries) GetUserForSession(ctx context.Context, arg GetUserForSessionParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserForSession, arg.TokenHash, arg.Now)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.PasswordHash,
+		&i.Role,
+	)
+	return i, err
+}
+
+const touchSession = `-- name: TouchSession :exec
+UPDATE sessions
+SET last_used_at = NOW()
+WHERE token_hash = $1
+`
+
+func (q *Queries) TouchSession(ctx context.Context, tokenHash string) error {
+	_, err := q.db.ExecContext(ctx, touchSession, tokenHash)
+	return err
+}
@@ -0,0 +1,913 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: snapshots.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const deletePosts = `-- name: DeletePosts :execrows
+DELETE FROM posts
+`
+
+func (q *Queries) DeletePosts(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deletePosts)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deletePostsPublishedBefore = `-- name: DeletePostsPublishedBefore :execrows
+DELETE FROM posts WHERE published_at < $1
+`
+
+func (q *Queries) DeletePostsPublishedBefore(ctx context.Context, publishedAt time.Time) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deletePostsPublishedBefore, publishedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const listFeedFollows = `-- name: ListFeedFollows :many
+SELECT id, created_at, updated_at, user_id, feed_id, folder_id, display_name FROM feed_follows ORDER BY created_at
+`
+
+func (q *Queries) ListFeedFollows(ctx context.Context) ([]FeedFollow, error) {
+	rows, err := q.db.QueryContext(ctx, listFeedFollows)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FeedFollow
+	for rows.Next() {
+		var i FeedFollow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.FeedID,
+			&i.FolderID,
+			&i.DisplayName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listFeedFollowsForUser = `-- name: ListFeedFollowsForUser :many
+SELECT id, created_at, updated_at, user_id, feed_id, folder_id, display_name FROM feed_follows WHERE user_id = $1 ORDER BY created_at
+`
+
+func (q *Queries) ListFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]FeedFollow, error) {
+	rows, err := q.db.QueryContext(ctx, listFeedFollowsForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FeedFollow
+	for rows.Next() {
+		var i FeedFollow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.FeedID,
+			&i.FolderID,
+			&i.DisplayName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listFeedURLHistory = `-- name: ListFeedURLHistory :many
+SELECT id, changed_at, feed_id, old_url, new_url, merged FROM feed_url_history ORDER BY changed_at
+`
+
+func (q *Queries) ListFeedURLHistory(ctx context.Context) ([]FeedUrlHistory, error) {
+	rows, err := q.db.QueryContext(ctx, listFeedURLHistory)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FeedUrlHistory
+	for rows.Next() {
+		var i FeedUrlHistory
+		if err := rows.Scan(
+			&i.ID,
+			&i.ChangedAt,
+			&i.FeedID,
+			&i.OldUrl,
+			&i.NewUrl,
+			&i.Merged,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listFeeds = `-- name: ListFeeds :many
+SELECT id, created_at, updated_at, last_fetched_at, name, url, user_id, fetch_full_text, fetch_interval, adaptive_interval, next_fetch_at, ttl_minutes, skip_hours, skip_days, cache_max_age, retry_after, parse_warnings, retention_max_age, retention_max_posts FROM feeds ORDER BY created_at
+`
+
+func (q *Queries) ListFeeds(ctx context.Context) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, listFeeds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.LastFetchedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.FetchFullText,
+			&i.FetchInterval,
+			&i.AdaptiveInterval,
+			&i.NextFetchAt,
+			&i.TtlMinutes,
+			&i.SkipHours,
+			&i.SkipDays,
+			&i.CacheMaxAge,
+			&i.RetryAfter,
+			&i.ParseWarnings,
+			&i.RetentionMaxAge,
+			&i.RetentionMaxPosts,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listFilterRules = `-- name: ListFilterRules :many
+SELECT id, created_at, updated_at, user_id, feed_id, pattern, is_regex, field, action FROM filter_rules ORDER BY created_at
+`
+
+func (q *Queries) ListFilterRules(ctx context.Context) ([]FilterRule, error) {
+	rows, err := q.db.QueryContext(ctx, listFilterRules)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FilterRule
+	for rows.Next() {
+		var i FilterRule
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.FeedID,
+			&i.Pattern,
+			&i.IsRegex,
+			&i.Field,
+			&i.Action,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listFilterRulesForUser = `-- name: ListFilterRulesForUser :many
+SELECT id, created_at, updated_at, user_id, feed_id, pattern, is_regex, field, action FROM filter_rules WHERE user_id = $1 ORDER BY created_at
+`
+
+func (q *Queries) ListFilterRulesForUser(ctx context.Context, userID uuid.UUID) ([]FilterRule, error) {
+	rows, err := q.db.QueryContext(ctx, listFilterRulesForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FilterRule
+	for rows.Next() {
+		var i FilterRule
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.FeedID,
+			&i.Pattern,
+			&i.IsRegex,
+			&i.Field,
+			&i.Action,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listFolders = `-- name: ListFolders :many
+SELECT id, created_at, updated_at, name, user_id FROM folders ORDER BY created_at
+`
+
+func (q *Queries) ListFolders(ctx context.Context) ([]Folder, error) {
+	rows, err := q.db.QueryContext(ctx, listFolders)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Folder
+	for rows.Next() {
+		var i Folder
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.UserID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPostReads = `-- name: ListPostReads :many
+SELECT user_id, post_id, read_at FROM post_reads ORDER BY read_at
+`
+
+func (q *Queries) ListPostReads(ctx context.Context) ([]PostRead, error) {
+	rows, err := q.db.QueryContext(ctx, listPostReads)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PostRead
+	for rows.Next() {
+		var i PostRead
+		if err := rows.Scan(&i.UserID, &i.PostID, &i.ReadAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPostReadsForUser = `-- name: ListPostReadsForUser :many
+SELECT user_id, post_id, read_at FROM post_reads WHERE user_id = $1 ORDER BY read_at
+`
+
+func (q *Queries) ListPostReadsForUser(ctx context.Context, userID uuid.UUID) ([]PostRead, error) {
+	rows, err := q.db.QueryContext(ctx, listPostReadsForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PostRead
+	for rows.Next() {
+		var i PostRead
+		if err := rows.Scan(&i.UserID, &i.PostID, &i.ReadAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPostReadsPublishedBefore = `-- name: ListPostReadsPublishedBefore :many
+SELECT post_reads.user_id, post_reads.post_id, post_reads.read_at
+FROM post_reads
+INNER JOIN posts ON post_reads.post_id = posts.id
+WHERE posts.published_at < $1
+ORDER BY post_reads.read_at
+`
+
+func (q *Queries) ListPostReadsPublishedBefore(ctx context.Context, publishedAt time.Time) ([]PostRead, error) {
+	rows, err := q.db.QueryContext(ctx, listPostReadsPublishedBefore, publishedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PostRead
+	for rows.Next() {
+		var i PostRead
+		if err := rows.Scan(&i.UserID, &i.PostID, &i.ReadAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPosts = `-- name: ListPosts :many
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, content, author FROM posts ORDER BY created_at
+`
+
+func (q *Queries) ListPosts(ctx context.Context) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, listPosts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Post
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Content,
+			&i.Author,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPostsPublishedBefore = `-- name: ListPostsPublishedBefore :many
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, content, author FROM posts WHERE published_at < $1 ORDER BY created_at
+`
+
+func (q *Queries) ListPostsPublishedBefore(ctx context.Context, publishedAt time.Time) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, listPostsPublishedBefore, publishedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Post
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Content,
+			&i.Author,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSavedPosts = `-- name: ListSavedPosts :many
+SELECT user_id, post_id, saved_at FROM saved_posts ORDER BY saved_at
+`
+
+func (q *Queries) ListSavedPosts(ctx context.Context) ([]SavedPost, error) {
+	rows, err := q.db.QueryContext(ctx, listSavedPosts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SavedPost
+	for rows.Next() {
+		var i SavedPost
+		if err := rows.Scan(&i.UserID, &i.PostID, &i.SavedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSavedPostsForUser = `-- name: ListSavedPostsForUser :many
+SELECT user_id, post_id, saved_at FROM saved_posts WHERE user_id = $1 ORDER BY saved_at
+`
+
+func (q *Queries) ListSavedPostsForUser(ctx context.Context, userID uuid.UUID) ([]SavedPost, error) {
+	rows, err := q.db.QueryContext(ctx, listSavedPostsForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SavedPost
+	for rows.Next() {
+		var i SavedPost
+		if err := rows.Scan(&i.UserID, &i.PostID, &i.SavedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSavedPostsPublishedBefore = `-- name: ListSavedPostsPublishedBefore :many
+SELECT saved_posts.user_id, saved_posts.post_id, saved_posts.saved_at
+FROM saved_posts
+INNER JOIN posts ON saved_posts.post_id = posts.id
+WHERE posts.published_at < $1
+ORDER BY saved_posts.saved_at
+`
+
+func (q *Queries) ListSavedPostsPublishedBefore(ctx context.Context, publishedAt time.Time) ([]SavedPost, error) {
+	rows, err := q.db.QueryContext(ctx, listSavedPostsPublishedBefore, publishedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SavedPost
+	for rows.Next() {
+		var i SavedPost
+		if err := rows.Scan(&i.UserID, &i.PostID, &i.SavedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const restoreFeed = `-- name: RestoreFeed :execrows
+INSERT INTO feeds (
+    id, created_at, updated_at, last_fetched_at, name, url, user_id,
+    fetch_full_text, fetch_interval, adaptive_interval, next_fetch_at,
+    ttl_minutes, skip_hours, skip_days, cache_max_age, retry_after, parse_warnings,
+    retention_max_age, retention_max_posts
+)
+SELECT
+    $1::uuid,
+    $2::timestamp,
+    $3::timestamp,
+    $4::timestamp,
+    $5::text,
+    $6::text,
+    users.id,
+    $7::boolean,
+    $8::integer,
+    $9::boolean,
+    $10::timestamp,
+    $11::integer,
+    $12::text,
+    $13::text,
+    $14::integer,
+    $15::timestamp,
+    $16::text,
+    $17::integer,
+    $18::integer
+FROM users
+WHERE users.id = $19
+ON CONFLICT DO NOTHING
+`
+
+type RestoreFeedParams struct {
+	ID                uuid.UUID
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	LastFetchedAt     sql.NullTime
+	Name              string
+	Url               string
+	FetchFullText     bool
+	FetchInterval     sql.NullInt32
+	AdaptiveInterval  bool
+	NextFetchAt       sql.NullTime
+	TtlMinutes        sql.NullInt32
+	SkipHours         sql.NullString
+	SkipDays          sql.NullString
+	CacheMaxAge       sql.NullInt32
+	RetryAfter        sql.NullTime
+	ParseWarnings     sql.NullString
+	RetentionMaxAge   sql.NullInt32
+	RetentionMaxPosts sql.NullInt32
+	UserID            uuid.UUID
+}
+
+func (q *Queries) RestoreFeed(ctx context.Context, arg RestoreFeedParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, restoreFeed,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.LastFetchedAt,
+		arg.Name,
+		arg.Url,
+		arg.FetchFullText,
+		arg.FetchInterval,
+		arg.AdaptiveInterval,
+		arg.NextFetchAt,
+		arg.TtlMinutes,
+		arg.SkipHours,
+		arg.SkipDays,
+		arg.CacheMaxAge,
+		arg.RetryAfter,
+		arg.ParseWarnings,
+		arg.RetentionMaxAge,
+		arg.RetentionMaxPosts,
+		arg.UserID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const restoreFeedFollow = `-- name: RestoreFeedFollow :execrows
+INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id, folder_id, display_name)
+SELECT
+    $1::uuid,
+    $2::timestamp,
+    $3::timestamp,
+    users.id,
+    feeds.id,
+    (SELECT folders.id FROM folders WHERE folders.id = $4::uuid),
+    $5::text
+FROM users, feeds
+WHERE users.id = $6
+AND feeds.id = $7
+ON CONFLICT DO NOTHING
+`
+
+type RestoreFeedFollowParams struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	FolderID    uuid.NullUUID
+	DisplayName sql.NullString
+	UserID      uuid.UUID
+	FeedID      uuid.UUID
+}
+
+func (q *Queries) RestoreFeedFollow(ctx context.Context, arg RestoreFeedFollowParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, restoreFeedFollow,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.FolderID,
+		arg.DisplayName,
+		arg.UserID,
+		arg.FeedID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const restoreFeedURLChange = `-- name: RestoreFeedURLChange :execrows
+INSERT INTO feed_url_history (id, changed_at, feed_id, old_url, new_url, merged)
+SELECT
+    $1::uuid,
+    $2::timestamp,
+    feeds.id,
+    $3::text,
+    $4::text,
+    $5::boolean
+FROM feeds
+WHERE feeds.id = $6
+ON CONFLICT DO NOTHING
+`
+
+type RestoreFeedURLChangeParams struct {
+	ID        uuid.UUID
+	ChangedAt time.Time
+	OldUrl    string
+	NewUrl    string
+	Merged    bool
+	FeedID    uuid.UUID
+}
+
+func (q *Queries) RestoreFeedURLChange(ctx context.Context, arg RestoreFeedURLChangeParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, restoreFeedURLChange,
+		arg.ID,
+		arg.ChangedAt,
+		arg.OldUrl,
+		arg.NewUrl,
+		arg.Merged,
+		arg.FeedID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const restoreFilterRule = `-- name: RestoreFilterRule :execrows
+INSERT INTO filter_rules (id, created_at, updated_at, user_id, feed_id, pattern, is_regex, field, action)
+SELECT
+    $1::uuid,
+    $2::timestamp,
+    $3::timestamp,
+    users.id,
+    $4::uuid,
+    $5::text,
+    $6::boolean,
+    $7::text,
+    $8::text
+FROM users
+WHERE users.id = $9
+AND ($4::uuid IS NULL OR EXISTS (SELECT 1 FROM feeds WHERE feeds.id = $4::uuid))
+ON CONFLICT DO NOTHING
+`
+
+type RestoreFilterRuleParams struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	FeedID    uuid.NullUUID
+	Pattern   string
+	IsRegex   bool
+	Field     string
+	Action    string
+	UserID    uuid.UUID
+}
+
+func (q *Queries) RestoreFilterRule(ctx context.Context, arg RestoreFilterRuleParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, restoreFilterRule,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.FeedID,
+		arg.Pattern,
+		arg.IsRegex,
+		arg.Field,
+		arg.Action,
+		arg.UserID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const restoreFolder = `-- name: RestoreFolder :execrows
+INSERT INTO folders (id, created_at, updated_at, name, user_id)
+SELECT
+    $1::uuid,
+    $2::timestamp,
+    $3::timestamp,
+    $4::text,
+    users.id
+FROM users
+WHERE users.id = $5
+ON CONFLICT DO NOTHING
+`
+
+type RestoreFolderParams struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Name      string
+	UserID    uuid.UUID
+}
+
+func (q *Queries) RestoreFolder(ctx context.Context, arg RestoreFolderParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, restoreFolder,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Name,
+		arg.UserID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const restorePost = `-- name: RestorePost :execrows
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, content, author)
+SELECT
+    $1::uuid,
+    $2::timestamp,
+    $3::timestamp,
+    $4::text,
+    $5::text,
+    $6::text,
+    $7::timestamp,
+    feeds.id,
+    $8::text,
+    $9::text
+FROM feeds
+WHERE feeds.id = $10
+ON CONFLICT DO NOTHING
+`
+
+type RestorePostParams struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt time.Time
+	Content     sql.NullString
+	Author      sql.NullString
+	FeedID      uuid.UUID
+}
+
+func (q *Queries) RestorePost(ctx context.Context, arg RestorePostParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, restorePost,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Title,
+		arg.Url,
+		arg.Description,
+		arg.PublishedAt,
+		arg.Content,
+		arg.Author,
+		arg.FeedID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const restorePostRead = `-- name: RestorePostRead :execrows
+INSERT INTO post_reads (user_id, post_id, read_at)
+SELECT users.id, posts.id, $1::timestamp
+FROM users, posts
+WHERE users.id = $2
+AND posts.id = $3
+ON CONFLICT DO NOTHING
+`
+
+type RestorePostReadParams struct {
+	ReadAt time.Time
+	UserID uuid.UUID
+	PostID uuid.UUID
+}
+
+func (q *Queries) RestorePostRead(ctx context.Context, arg RestorePostReadParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, restorePostRead, arg.ReadAt, arg.UserID, arg.PostID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const restoreSavedPost = `-- name: RestoreSavedPost :execrows
+INSERT INTO saved_posts (user_id, post_id, saved_at)
+SELECT users.id, posts.id, $1::timestamp
+FROM users, posts
+WHERE users.id = $2
+AND posts.id = $3
+ON CONFLICT DO NOTHING
+`
+
+type RestoreSavedPostParams struct {
+	SavedAt time.Time
+	UserID  uuid.UUID
+	PostID  uuid.UUID
+}
+
+func (q *Queries) RestoreSavedPost(ctx context.Context, arg RestoreSavedPostParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, restoreSavedPost, arg.SavedAt, arg.UserID, arg.PostID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const restoreUser = `-- name: RestoreUser :execrows
+INSERT INTO users (id, created_at, updated_at, name, password_hash, role)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT DO NOTHING
+`
+
+type RestoreUserParams struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Name         string
+	PasswordHash sql.NullString
+	Role         string
+}
+
+func (q *Queries) RestoreUser(ctx context.Context, arg RestoreUserParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, restoreUser,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Name,
+		arg.PasswordHash,
+		arg.Role,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
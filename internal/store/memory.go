@@ -0,0 +1,308 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nhdewitt/blog-aggregator/internal/app"
+	"github.com/nhdewitt/blog-aggregator/internal/database"
+)
+
+// memoryStore keeps everything in maps, for tests that exercise handlers and
+// the scraper without a database server. It mirrors the behavior of the SQL
+// queries, including unique constraints, foreign keys and cascading deletes,
+// but the data is lost when the process exits.
+type memoryStore struct {
+	mu		*sync.Mutex
+	data	*memoryData
+	inTx	bool
+}
+
+// NewMemory returns an empty Store that keeps its data in memory.
+func NewMemory() app.Store {
+	return &memoryStore{mu: &sync.Mutex{}, data: newMemoryData()}
+}
+
+// memoryData holds one row per key for each table.
+type memoryData struct {
+	users			map[uuid.UUID]database.User
+	sessions		map[uuid.UUID]database.Session
+	feeds			map[uuid.UUID]database.Feed
+	feedURLHistory	map[uuid.UUID]database.FeedUrlHistory
+	folders			map[uuid.UUID]database.Folder
+	feedFollows		map[uuid.UUID]database.FeedFollow
+	posts			map[uuid.UUID]database.Post
+	filterRules		map[uuid.UUID]database.FilterRule
+	postReads		map[userPost]database.PostRead
+	savedPosts		map[userPost]database.SavedPost
+}
+
+// userPost is the primary key of post_reads and saved_posts.
+type userPost struct {
+	UserID	uuid.UUID
+	PostID	uuid.UUID
+}
+
+func newMemoryData() *memoryData {
+	return &memoryData{
+		users: make(map[uuid.UUID]database.User),
+		sessions: make(map[uuid.UUID]database.Session),
+		feeds: make(map[uuid.UUID]database.Feed),
+		feedURLHistory: make(map[uuid.UUID]database.FeedUrlHistory),
+		folders: make(map[uuid.UUID]database.Folder),
+		feedFollows: make(map[uuid.UUID]database.FeedFollow),
+		posts: make(map[uuid.UUID]database.Post),
+		filterRules: make(map[uuid.UUID]database.FilterRule),
+		postReads: make(map[userPost]database.PostRead),
+		savedPosts: make(map[userPost]database.SavedPost),
+	}
+}
+
+// clone copies every table. Rows are plain values, so copying the maps is enough.
+func (d *memoryData) clone() *memoryData {
+	c := newMemoryData()
+	copyMap(c.users, d.users)
+	copyMap(c.sessions, d.sessions)
+	copyMap(c.feeds, d.feeds)
+	copyMap(c.feedURLHistory, d.feedURLHistory)
+	copyMap(c.folders, d.folders)
+	copyMap(c.feedFollows, d.feedFollows)
+	copyMap(c.posts, d.posts)
+	copyMap(c.filterRules, d.filterRules)
+	copyMap(c.postReads, d.postReads)
+	copyMap(c.savedPosts, d.savedPosts)
+	return c
+}
+
+func copyMap[K comparable, V any](dst, src map[K]V) {
+	for k, v := range src {
+		dst[k] = v
+	}
+}
+
+// InTx runs fn against a copy of the data, which replaces the original only
+// if fn succeeds. The store stays locked until fn returns, so calls made
+// outside the transaction wait for it as they would for a database write lock.
+func (s *memoryStore) InTx(ctx context.Context, fn func(q app.Store) error) error {
+	if s.inTx {
+		return fn(s)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx := &memoryStore{mu: &sync.Mutex{}, data: s.data.clone(), inTx: true}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	s.data = tx.data
+	return nil
+}
+
+func (s *memoryStore) Close() error {
+	return nil
+}
+
+// lock returns the data with the store locked; call the returned function to unlock.
+func (s *memoryStore) lock() (*memoryData, func()) {
+	s.mu.Lock()
+	return s.data, s.mu.Unlock
+}
+
+// now is the time the SQL queries get from NOW().
+func now() time.Time {
+	return time.Now().UTC()
+}
+
+// errUnique mimics a unique constraint violation.
+func errUnique(constraint string) error {
+	return fmt.Errorf("duplicate key value violates unique constraint %q", constraint)
+}
+
+// errForeignKey mimics a foreign key violation.
+func errForeignKey(table, column string) error {
+	return fmt.Errorf("insert or update on table %q violates foreign key constraint on %s", table, column)
+}
+
+// deleteUser removes a user and every row that cascades from it.
+func (d *memoryData) deleteUser(id uuid.UUID) {
+	delete(d.users, id)
+	for sessionID, session := range d.sessions {
+		if session.UserID == id {
+			delete(d.sessions, sessionID)
+		}
+	}
+	for feedID, feed := range d.feeds {
+		if feed.UserID == id {
+			d.deleteFeed(feedID)
+		}
+	}
+	for folderID, folder := range d.folders {
+		if folder.UserID == id {
+			d.deleteFolder(folderID)
+		}
+	}
+	for followID, follow := range d.feedFollows {
+		if follow.UserID == id {
+			delete(d.feedFollows, followID)
+		}
+	}
+	for ruleID, rule := range d.filterRules {
+		if rule.UserID == id {
+			delete(d.filterRules, ruleID)
+		}
+	}
+	for key := range d.postReads {
+		if key.UserID == id {
+			delete(d.postReads, key)
+		}
+	}
+	for key := range d.savedPosts {
+		if key.UserID == id {
+			delete(d.savedPosts, key)
+		}
+	}
+}
+
+// deleteFeed removes a feed and its follows, posts, feed rules and URL history.
+func (d *memoryData) deleteFeed(id uuid.UUID) {
+	delete(d.feeds, id)
+	for followID, follow := range d.feedFollows {
+		if follow.FeedID == id {
+			delete(d.feedFollows, followID)
+		}
+	}
+	for postID, post := range d.posts {
+		if post.FeedID == id {
+			d.deletePost(postID)
+		}
+	}
+	for ruleID, rule := range d.filterRules {
+		if rule.FeedID.Valid && rule.FeedID.UUID == id {
+			delete(d.filterRules, ruleID)
+		}
+	}
+	for historyID, history := range d.feedURLHistory {
+		if history.FeedID == id {
+			delete(d.feedURLHistory, historyID)
+		}
+	}
+}
+
+// deletePost removes a post and the read markers and saves that refer to it.
+func (d *memoryData) deletePost(id uuid.UUID) {
+	delete(d.posts, id)
+	for key := range d.postReads {
+		if key.PostID == id {
+			delete(d.postReads, key)
+		}
+	}
+	for key := range d.savedPosts {
+		if key.PostID == id {
+			delete(d.savedPosts, key)
+		}
+	}
+}
+
+// deleteFolder removes a folder, leaving the feeds in it unfiled.
+func (d *memoryData) deleteFolder(id uuid.UUID) {
+	delete(d.folders, id)
+	for followID, follow := range d.feedFollows {
+		if follow.FolderID.Valid && follow.FolderID.UUID == id {
+			follow.FolderID = uuid.NullUUID{}
+			d.feedFollows[followID] = follow
+		}
+	}
+}
+
+// userByName returns the user called name.
+func (d *memoryData) userByName(name string) (database.User, bool) {
+	for _, user := range d.users {
+		if user.Name == name {
+			return user, true
+		}
+	}
+	return database.User{}, false
+}
+
+// feedByURL returns the feed stored under url.
+func (d *memoryData) feedByURL(url string) (database.Feed, bool) {
+	for _, feed := range d.feeds {
+		if feed.Url == url {
+			return feed, true
+		}
+	}
+	return database.Feed{}, false
+}
+
+// postByURL returns the post stored under url.
+func (d *memoryData) postByURL(url string) (database.Post, bool) {
+	for _, post := range d.posts {
+		if post.Url == url {
+			return post, true
+		}
+	}
+	return database.Post{}, false
+}
+
+// follow returns userID's follow of feedID.
+func (d *memoryData) follow(userID, feedID uuid.UUID) (database.FeedFollow, bool) {
+	for _, follow := range d.feedFollows {
+		if follow.UserID == userID && follow.FeedID == feedID {
+			return follow, true
+		}
+	}
+	return database.FeedFollow{}, false
+}
+
+// folderNamed returns userID's folder called name.
+func (d *memoryData) folderNamed(userID uuid.UUID, name string) (database.Folder, bool) {
+	for _, folder := range d.folders {
+		if folder.UserID == userID && folder.Name == name {
+			return folder, true
+		}
+	}
+	return database.Folder{}, false
+}
+
+// isFollowed reports whether anyone follows feedID.
+func (d *memoryData) isFollowed(feedID uuid.UUID) bool {
+	for _, follow := range d.feedFollows {
+		if follow.FeedID == feedID {
+			return true
+		}
+	}
+	return false
+}
+
+// isSaved reports whether any user saved postID.
+func (d *memoryData) isSaved(postID uuid.UUID) bool {
+	for key := range d.savedPosts {
+		if key.PostID == postID {
+			return true
+		}
+	}
+	return false
+}
+
+// hasSavedPosts reports whether any user saved a post of feedID.
+func (d *memoryData) hasSavedPosts(feedID uuid.UUID) bool {
+	for key := range d.savedPosts {
+		if d.posts[key.PostID].FeedID == feedID {
+			return true
+		}
+	}
+	return false
+}
+
+// values returns the rows of a table in no particular order.
+func values[K comparable, V any](m map[K]V) []V {
+	rows := make([]V, 0, len(m))
+	for _, v := range m {
+		rows = append(rows, v)
+	}
+	return rows
+}
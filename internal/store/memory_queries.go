@@ -0,0 +1,1577 @@
+package store
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nhdewitt/blog-aggregator/internal/database"
+)
+
+// Each method below mirrors the sqlc query of the same name in sql/queries.
+
+// users.sql
+
+func (s *memoryStore) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	if _, ok := d.users[arg.ID]; ok {
+		return database.User{}, errUnique("users_pkey")
+	}
+	if _, ok := d.userByName(arg.Name); ok {
+		return database.User{}, errUnique("users_name_key")
+	}
+
+	// The first user to register becomes the administrator
+	role := "user"
+	if len(d.users) == 0 {
+		role = "admin"
+	}
+	user := database.User{
+		ID: arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		Name: arg.Name,
+		PasswordHash: arg.PasswordHash,
+		Role: role,
+	}
+	d.users[user.ID] = user
+	return user, nil
+}
+
+func (s *memoryStore) GetUser(ctx context.Context, name string) (database.User, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	user, ok := d.userByName(name)
+	if !ok {
+		return database.User{}, sql.ErrNoRows
+	}
+	return user, nil
+}
+
+func (s *memoryStore) DeleteAllUsers(ctx context.Context) error {
+	d, unlock := s.lock()
+	defer unlock()
+
+	for id := range d.users {
+		d.deleteUser(id)
+	}
+	return nil
+}
+
+func (s *memoryStore) GetUsers(ctx context.Context) ([]database.User, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	users := values(d.users)
+	slices.SortFunc(users, func(a, b database.User) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return users, nil
+}
+
+func (s *memoryStore) SetUserPassword(ctx context.Context, arg database.SetUserPasswordParams) error {
+	d, unlock := s.lock()
+	defer unlock()
+
+	if user, ok := d.users[arg.ID]; ok {
+		user.PasswordHash = arg.PasswordHash
+		user.UpdatedAt = now()
+		d.users[user.ID] = user
+	}
+	return nil
+}
+
+func (s *memoryStore) SetUserRole(ctx context.Context, arg database.SetUserRoleParams) error {
+	d, unlock := s.lock()
+	defer unlock()
+
+	if user, ok := d.users[arg.ID]; ok {
+		user.Role = arg.Role
+		user.UpdatedAt = now()
+		d.users[user.ID] = user
+	}
+	return nil
+}
+
+func (s *memoryStore) CountAdmins(ctx context.Context) (int64, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	var admins int64
+	for _, user := range d.users {
+		if user.Role == "admin" {
+			admins++
+		}
+	}
+	return admins, nil
+}
+
+func (s *memoryStore) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	d, unlock := s.lock()
+	defer unlock()
+
+	d.deleteUser(id)
+	return nil
+}
+
+// sessions.sql
+
+func (s *memoryStore) CreateSession(ctx context.Context, arg database.CreateSessionParams) error {
+	d, unlock := s.lock()
+	defer unlock()
+
+	if _, ok := d.sessions[arg.ID]; ok {
+		return errUnique("sessions_pkey")
+	}
+	for _, session := range d.sessions {
+		if session.TokenHash == arg.TokenHash {
+			return errUnique("sessions_token_hash_key")
+		}
+	}
+	if _, ok := d.users[arg.UserID]; !ok {
+		return errForeignKey("sessions", "user_id")
+	}
+
+	t := now()
+	d.sessions[arg.ID] = database.Session{
+		ID: arg.ID,
+		CreatedAt: t,
+		LastUsedAt: t,
+		ExpiresAt: arg.ExpiresAt,
+		UserID: arg.UserID,
+		TokenHash: arg.TokenHash,
+	}
+	return nil
+}
+
+func (s *memoryStore) GetUserForSession(ctx context.Context, arg database.GetUserForSessionParams) (database.User, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	for _, session := range d.sessions {
+		if session.TokenHash == arg.TokenHash && session.ExpiresAt.After(arg.Now) {
+			if user, ok := d.users[session.UserID]; ok {
+				return user, nil
+			}
+		}
+	}
+	return database.User{}, sql.ErrNoRows
+}
+
+func (s *memoryStore) TouchSession(ctx context.Context, tokenHash string) error {
+	d, unlock := s.lock()
+	defer unlock()
+
+	for id, session := range d.sessions {
+		if session.TokenHash == tokenHash {
+			session.LastUsedAt = now()
+			d.sessions[id] = session
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) DeleteSession(ctx context.Context, tokenHash string) error {
+	d, unlock := s.lock()
+	defer unlock()
+
+	for id, session := range d.sessions {
+		if session.TokenHash == tokenHash {
+			delete(d.sessions, id)
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) DeleteExpiredSessions(ctx context.Context, t time.Time) error {
+	d, unlock := s.lock()
+	defer unlock()
+
+	for id, session := range d.sessions {
+		if !session.ExpiresAt.After(t) {
+			delete(d.sessions, id)
+		}
+	}
+	return nil
+}
+
+// feeds.sql
+
+func (s *memoryStore) AddFeed(ctx context.Context, arg database.AddFeedParams) (database.Feed, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	if _, ok := d.feeds[arg.ID]; ok {
+		return database.Feed{}, errUnique("feeds_pkey")
+	}
+	if _, ok := d.feedByURL(arg.Url); ok {
+		return database.Feed{}, errUnique("feeds_url_key")
+	}
+	if _, ok := d.users[arg.UserID]; !ok {
+		return database.Feed{}, errForeignKey("feeds", "user_id")
+	}
+
+	feed := database.Feed{
+		ID: arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		Name: arg.Name,
+		Url: arg.Url,
+		UserID: arg.UserID,
+	}
+	d.feeds[feed.ID] = feed
+	return feed, nil
+}
+
+func (s *memoryStore) PrintAllFeeds(ctx context.Context) ([]database.PrintAllFeedsRow, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	var rows []database.PrintAllFeedsRow
+	for _, feed := range sortedFeeds(d) {
+		user, ok := d.users[feed.UserID]
+		if !ok {
+			continue
+		}
+		rows = append(rows, database.PrintAllFeedsRow{
+			FeedName: feed.Name,
+			FeedUrl: feed.Url,
+			UserName: user.Name,
+			FetchInterval: feed.FetchInterval,
+			AdaptiveInterval: feed.AdaptiveInterval,
+			ParseWarnings: feed.ParseWarnings,
+			RetentionMaxAge: feed.RetentionMaxAge,
+			RetentionMaxPosts: feed.RetentionMaxPosts,
+		})
+	}
+	return rows, nil
+}
+
+func (s *memoryStore) FindFeedsByURL(ctx context.Context, url string) (database.Feed, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	feed, ok := d.feedByURL(url)
+	if !ok {
+		return database.Feed{}, sql.ErrNoRows
+	}
+	return feed, nil
+}
+
+func (s *memoryStore) SetFeedFullText(ctx context.Context, arg database.SetFeedFullTextParams) error {
+	return s.updateFeed(arg.ID, func(feed *database.Feed) {
+		feed.FetchFullText = arg.FetchFullText
+		feed.UpdatedAt = now()
+	})
+}
+
+func (s *memoryStore) ReassignFeeds(ctx context.Context, arg database.ReassignFeedsParams) error {
+	d, unlock := s.lock()
+	defer unlock()
+
+	for id, feed := range d.feeds {
+		if feed.UserID != arg.FromUserID {
+			continue
+		}
+		if _, ok := d.users[arg.ToUserID]; !ok {
+			return errForeignKey("feeds", "user_id")
+		}
+		feed.UserID = arg.ToUserID
+		feed.UpdatedAt = now()
+		d.feeds[id] = feed
+	}
+	return nil
+}
+
+func (s *memoryStore) GetFeedUsage(ctx context.Context, feedID uuid.UUID) (database.GetFeedUsageRow, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	var usage database.GetFeedUsageRow
+	for _, follow := range d.feedFollows {
+		if follow.FeedID == feedID {
+			usage.Followers++
+		}
+	}
+	for _, post := range d.posts {
+		if post.FeedID == feedID {
+			usage.Posts++
+		}
+	}
+	for _, rule := range d.filterRules {
+		if rule.FeedID.Valid && rule.FeedID.UUID == feedID {
+			usage.Rules++
+		}
+	}
+	return usage, nil
+}
+
+func (s *memoryStore) SetFeedOwner(ctx context.Context, arg database.SetFeedOwnerParams) error {
+	d, unlock := s.lock()
+	defer unlock()
+
+	feed, ok := d.feeds[arg.ID]
+	if !ok {
+		return nil
+	}
+	if _, ok := d.users[arg.UserID]; !ok {
+		return errForeignKey("feeds", "user_id")
+	}
+	feed.UserID = arg.UserID
+	feed.UpdatedAt = now()
+	d.feeds[feed.ID] = feed
+	return nil
+}
+
+func (s *memoryStore) DeleteFeedIfOrphaned(ctx context.Context, url string) (int64, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	feed, ok := d.feedByURL(url)
+	if !ok || d.isFollowed(feed.ID) || d.hasSavedPosts(feed.ID) {
+		return 0, nil
+	}
+	d.deleteFeed(feed.ID)
+	return 1, nil
+}
+
+func (s *memoryStore) DeleteOrphanedFeeds(ctx context.Context) ([]string, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	var urls []string
+	for _, feed := range sortedFeeds(d) {
+		if !d.isFollowed(feed.ID) && !d.hasSavedPosts(feed.ID) {
+			d.deleteFeed(feed.ID)
+			urls = append(urls, feed.Url)
+		}
+	}
+	return urls, nil
+}
+
+// updateFeed applies change to the feed with the given ID, if it exists.
+func (s *memoryStore) updateFeed(id uuid.UUID, change func(feed *database.Feed)) error {
+	d, unlock := s.lock()
+	defer unlock()
+
+	if feed, ok := d.feeds[id]; ok {
+		change(&feed)
+		d.feeds[id] = feed
+	}
+	return nil
+}
+
+// sortedFeeds returns every feed, oldest first.
+func sortedFeeds(d *memoryData) []database.Feed {
+	feeds := values(d.feeds)
+	slices.SortFunc(feeds, func(a, b database.Feed) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return feeds
+}
+
+// mark_feed_fetched.sql
+
+func (s *memoryStore) MarkFeedFetched(ctx context.Context, arg database.MarkFeedFetchedParams) error {
+	return s.updateFeed(arg.ID, func(feed *database.Feed) {
+		t := now()
+		feed.LastFetchedAt = sql.NullTime{Time: t, Valid: true}
+		feed.UpdatedAt = t
+		feed.NextFetchAt = arg.NextFetchAt
+		feed.FetchInterval = arg.FetchInterval
+		feed.TtlMinutes = arg.TtlMinutes
+		feed.SkipHours = arg.SkipHours
+		feed.SkipDays = arg.SkipDays
+		feed.CacheMaxAge = arg.CacheMaxAge
+		feed.ParseWarnings = arg.ParseWarnings
+		feed.RetryAfter = sql.NullTime{}
+	})
+}
+
+func (s *memoryStore) DeferFeedFetch(ctx context.Context, arg database.DeferFeedFetchParams) error {
+	return s.updateFeed(arg.ID, func(feed *database.Feed) {
+		feed.NextFetchAt = arg.NextFetchAt
+		feed.RetryAfter = arg.RetryAfter
+		feed.UpdatedAt = now()
+	})
+}
+
+func (s *memoryStore) GetNextFeedToFetch(ctx context.Context, arg database.GetNextFeedToFetchParams) (database.Feed, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	// Feeds without a scheduled fetch have been due since they were last fetched
+	dueSince := func(feed database.Feed) sql.NullTime {
+		if feed.NextFetchAt.Valid {
+			return feed.NextFetchAt
+		}
+		return feed.LastFetchedAt
+	}
+
+	var next database.Feed
+	found := false
+	for _, feed := range sortedFeeds(d) {
+		if feed.NextFetchAt.Valid && feed.NextFetchAt.Time.After(arg.Now) {
+			continue
+		}
+		if feed.LastFetchedAt.Valid && !feed.LastFetchedAt.Time.Before(arg.FetchedBefore) {
+			continue
+		}
+		if !found || compareNullTime(dueSince(feed), dueSince(next)) < 0 {
+			next, found = feed, true
+		}
+	}
+	if !found {
+		return database.Feed{}, sql.ErrNoRows
+	}
+	return next, nil
+}
+
+func (s *memoryStore) SetFeedFetchInterval(ctx context.Context, arg database.SetFeedFetchIntervalParams) error {
+	return s.updateFeed(arg.ID, func(feed *database.Feed) {
+		feed.FetchInterval = arg.FetchInterval
+		feed.AdaptiveInterval = arg.AdaptiveInterval
+		feed.NextFetchAt = arg.NextFetchAt
+		feed.UpdatedAt = now()
+	})
+}
+
+// compareNullTime orders times ascending with NULL first.
+func compareNullTime(a, b sql.NullTime) int {
+	switch {
+	case !a.Valid && !b.Valid:
+		return 0
+	case !a.Valid:
+		return -1
+	case !b.Valid:
+		return 1
+	}
+	return a.Time.Compare(b.Time)
+}
+
+// retention.sql
+
+func (s *memoryStore) SetFeedRetention(ctx context.Context, arg database.SetFeedRetentionParams) error {
+	return s.updateFeed(arg.ID, func(feed *database.Feed) {
+		feed.RetentionMaxAge = arg.RetentionMaxAge
+		feed.RetentionMaxPosts = arg.RetentionMaxPosts
+		feed.UpdatedAt = now()
+	})
+}
+
+func (s *memoryStore) DeleteFeedPostsPublishedBefore(ctx context.Context, arg database.DeleteFeedPostsPublishedBeforeParams) (int64, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	var deleted int64
+	for id, post := range d.posts {
+		if post.FeedID == arg.FeedID && post.PublishedAt.Before(arg.PublishedAt) && !d.isSaved(id) {
+			d.deletePost(id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (s *memoryStore) DeleteFeedPostsBeyond(ctx context.Context, arg database.DeleteFeedPostsBeyondParams) (int64, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	var posts []database.Post
+	for _, post := range d.posts {
+		if post.FeedID == arg.FeedID {
+			posts = append(posts, post)
+		}
+	}
+	slices.SortFunc(posts, func(a, b database.Post) int {
+		if c := b.PublishedAt.Compare(a.PublishedAt); c != 0 {
+			return c
+		}
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+
+	var deleted int64
+	for i, post := range posts {
+		if i >= int(arg.Keep) && !d.isSaved(post.ID) {
+			d.deletePost(post.ID)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (s *memoryStore) GetPostByURL(ctx context.Context, url string) (database.Post, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	post, ok := d.postByURL(url)
+	if !ok {
+		return database.Post{}, sql.ErrNoRows
+	}
+	return post, nil
+}
+
+func (s *memoryStore) SavePost(ctx context.Context, arg database.SavePostParams) error {
+	d, unlock := s.lock()
+	defer unlock()
+
+	key := userPost{UserID: arg.UserID, PostID: arg.PostID}
+	if _, ok := d.savedPosts[key]; ok {
+		return nil
+	}
+	if _, ok := d.users[arg.UserID]; !ok {
+		return errForeignKey("saved_posts", "user_id")
+	}
+	if _, ok := d.posts[arg.PostID]; !ok {
+		return errForeignKey("saved_posts", "post_id")
+	}
+	d.savedPosts[key] = database.SavedPost{UserID: arg.UserID, PostID: arg.PostID, SavedAt: now()}
+	return nil
+}
+
+func (s *memoryStore) UnsavePost(ctx context.Context, arg database.UnsavePostParams) (int64, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	key := userPost{UserID: arg.UserID, PostID: arg.PostID}
+	if _, ok := d.savedPosts[key]; !ok {
+		return 0, nil
+	}
+	delete(d.savedPosts, key)
+	return 1, nil
+}
+
+// feed_url_history.sql
+
+func (s *memoryStore) SetFeedURL(ctx context.Context, arg database.SetFeedURLParams) error {
+	d, unlock := s.lock()
+	defer unlock()
+
+	feed, ok := d.feeds[arg.ID]
+	if !ok {
+		return nil
+	}
+	if other, ok := d.feedByURL(arg.Url); ok && other.ID != feed.ID {
+		return errUnique("feeds_url_key")
+	}
+	feed.Url = arg.Url
+	feed.UpdatedAt = now()
+	d.feeds[feed.ID] = feed
+	return nil
+}
+
+func (s *memoryStore) MoveFeedFollows(ctx context.Context, arg database.MoveFeedFollowsParams) error {
+	d, unlock := s.lock()
+	defer unlock()
+
+	for id, follow := range d.feedFollows {
+		if follow.FeedID != arg.FromFeedID {
+			continue
+		}
+		if _, ok := d.follow(follow.UserID, arg.ToFeedID); ok {
+			continue
+		}
+		if _, ok := d.feeds[arg.ToFeedID]; !ok {
+			return errForeignKey("feed_follows", "feed_id")
+		}
+		follow.FeedID = arg.ToFeedID
+		follow.UpdatedAt = now()
+		d.feedFollows[id] = follow
+	}
+	return nil
+}
+
+func (s *memoryStore) MoveFeedPosts(ctx context.Context, arg database.MoveFeedPostsParams) error {
+	d, unlock := s.lock()
+	defer unlock()
+
+	for id, post := range d.posts {
+		if post.FeedID != arg.FromFeedID {
+			continue
+		}
+		if _, ok := d.feeds[arg.ToFeedID]; !ok {
+			return errForeignKey("posts", "feed_id")
+		}
+		post.FeedID = arg.ToFeedID
+		d.posts[id] = post
+	}
+	return nil
+}
+
+func (s *memoryStore) MoveFeedFilterRules(ctx context.Context, arg database.MoveFeedFilterRulesParams) error {
+	d, unlock := s.lock()
+	defer unlock()
+
+	if !arg.FromFeedID.Valid {
+		return nil
+	}
+	for id, rule := range d.filterRules {
+		if !rule.FeedID.Valid || rule.FeedID.UUID != arg.FromFeedID.UUID {
+			continue
+		}
+		if _, ok := d.feeds[arg.ToFeedID.UUID]; arg.ToFeedID.Valid && !ok {
+			return errForeignKey("filter_rules", "feed_id")
+		}
+		rule.FeedID = arg.ToFeedID
+		rule.UpdatedAt = now()
+		d.filterRules[id] = rule
+	}
+	return nil
+}
+
+func (s *memoryStore) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	d, unlock := s.lock()
+	defer unlock()
+
+	d.deleteFeed(id)
+	return nil
+}
+
+func (s *memoryStore) RecordFeedURLChange(ctx context.Context, arg database.RecordFeedURLChangeParams) error {
+	d, unlock := s.lock()
+	defer unlock()
+
+	if _, ok := d.feedURLHistory[arg.ID]; ok {
+		return errUnique("feed_url_history_pkey")
+	}
+	if _, ok := d.feeds[arg.FeedID]; !ok {
+		return errForeignKey("feed_url_history", "feed_id")
+	}
+	d.feedURLHistory[arg.ID] = database.FeedUrlHistory{
+		ID: arg.ID,
+		ChangedAt: now(),
+		FeedID: arg.FeedID,
+		OldUrl: arg.OldUrl,
+		NewUrl: arg.NewUrl,
+		Merged: arg.Merged,
+	}
+	return nil
+}
+
+func (s *memoryStore) GetFeedURLHistory(ctx context.Context, feedID uuid.UUID) ([]database.FeedUrlHistory, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	var history []database.FeedUrlHistory
+	for _, change := range d.feedURLHistory {
+		if change.FeedID == feedID {
+			history = append(history, change)
+		}
+	}
+	slices.SortFunc(history, func(a, b database.FeedUrlHistory) int {
+		return a.ChangedAt.Compare(b.ChangedAt)
+	})
+	return history, nil
+}
+
+func (s *memoryStore) MoveFeedURLHistory(ctx context.Context, arg database.MoveFeedURLHistoryParams) error {
+	d, unlock := s.lock()
+	defer unlock()
+
+	for id, change := range d.feedURLHistory {
+		if change.FeedID != arg.FromFeedID {
+			continue
+		}
+		if _, ok := d.feeds[arg.ToFeedID]; !ok {
+			return errForeignKey("feed_url_history", "feed_id")
+		}
+		change.FeedID = arg.ToFeedID
+		d.feedURLHistory[id] = change
+	}
+	return nil
+}
+
+// feed_follows.sql
+
+func (s *memoryStore) CreateFeedFollow(ctx context.Context, arg database.CreateFeedFollowParams) (database.CreateFeedFollowRow, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	if _, ok := d.feedFollows[arg.ID]; ok {
+		return database.CreateFeedFollowRow{}, errUnique("feed_follows_pkey")
+	}
+	if _, ok := d.follow(arg.UserID, arg.FeedID); ok {
+		return database.CreateFeedFollowRow{}, errUnique("feed_follows_user_id_feed_id_key")
+	}
+	user, ok := d.users[arg.UserID]
+	if !ok {
+		return database.CreateFeedFollowRow{}, errForeignKey("feed_follows", "user_id")
+	}
+	feed, ok := d.feeds[arg.FeedID]
+	if !ok {
+		return database.CreateFeedFollowRow{}, errForeignKey("feed_follows", "feed_id")
+	}
+	if _, ok := d.folders[arg.FolderID.UUID]; arg.FolderID.Valid && !ok {
+		return database.CreateFeedFollowRow{}, errForeignKey("feed_follows", "folder_id")
+	}
+
+	d.feedFollows[arg.ID] = database.FeedFollow{
+		ID: arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		UserID: arg.UserID,
+		FeedID: arg.FeedID,
+		FolderID: arg.FolderID,
+	}
+	return database.CreateFeedFollowRow{
+		ID: arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		UserID: arg.UserID,
+		FeedID: arg.FeedID,
+		FolderID: arg.FolderID,
+		FeedName: feed.Name,
+		UserName: user.Name,
+	}, nil
+}
+
+func (s *memoryStore) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetFeedFollowsForUserRow, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	user, ok := d.users[userID]
+	if !ok {
+		return nil, nil
+	}
+
+	var rows []database.GetFeedFollowsForUserRow
+	for _, follow := range d.feedFollows {
+		if follow.UserID != userID {
+			continue
+		}
+		feed := d.feeds[follow.FeedID]
+		row := database.GetFeedFollowsForUserRow{
+			Name: feed.Name,
+			Url: feed.Url,
+			UserName: user.Name,
+		}
+		if follow.DisplayName.Valid {
+			row.Name = follow.DisplayName.String
+		}
+		if folder, ok := d.folders[follow.FolderID.UUID]; follow.FolderID.Valid && ok {
+			row.FolderName = sql.NullString{String: folder.Name, Valid: true}
+		}
+		rows = append(rows, row)
+	}
+	slices.SortFunc(rows, func(a, b database.GetFeedFollowsForUserRow) int {
+		if c := compareNullString(a.FolderName, b.FolderName); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Name, b.Name)
+	})
+	return rows, nil
+}
+
+func (s *memoryStore) UnfollowFeed(ctx context.Context, arg database.UnfollowFeedParams) error {
+	d, unlock := s.lock()
+	defer unlock()
+
+	feed, ok := d.feedByURL(arg.Url)
+	if !ok {
+		return nil
+	}
+	if follow, ok := d.follow(arg.ID, feed.ID); ok {
+		delete(d.feedFollows, follow.ID)
+	}
+	return nil
+}
+
+func (s *memoryStore) SetFeedFollowFolder(ctx context.Context, arg database.SetFeedFollowFolderParams) (int64, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	if _, ok := d.folders[arg.FolderID.UUID]; arg.FolderID.Valid && !ok {
+		return 0, errForeignKey("feed_follows", "folder_id")
+	}
+	return s.updateFollow(d, arg.UserID, arg.Url, func(follow *database.FeedFollow) {
+		follow.FolderID = arg.FolderID
+	}), nil
+}
+
+func (s *memoryStore) SetFeedFollowDisplayName(ctx context.Context, arg database.SetFeedFollowDisplayNameParams) (int64, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	return s.updateFollow(d, arg.UserID, arg.Url, func(follow *database.FeedFollow) {
+		follow.DisplayName = arg.DisplayName
+	}), nil
+}
+
+// updateFollow applies change to userID's follow of the feed at url and
+// returns the number of follows changed.
+func (s *memoryStore) updateFollow(d *memoryData, userID uuid.UUID, url string, change func(follow *database.FeedFollow)) int64 {
+	feed, ok := d.feedByURL(url)
+	if !ok {
+		return 0
+	}
+	follow, ok := d.follow(userID, feed.ID)
+	if !ok {
+		return 0
+	}
+	change(&follow)
+	follow.UpdatedAt = now()
+	d.feedFollows[follow.ID] = follow
+	return 1
+}
+
+// compareNullString orders strings ascending with NULL first.
+func compareNullString(a, b sql.NullString) int {
+	switch {
+	case !a.Valid && !b.Valid:
+		return 0
+	case !a.Valid:
+		return -1
+	case !b.Valid:
+		return 1
+	}
+	return cmp.Compare(a.String, b.String)
+}
+
+// folders.sql
+
+func (s *memoryStore) CreateFolder(ctx context.Context, arg database.CreateFolderParams) (database.Folder, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	if _, ok := d.folders[arg.ID]; ok {
+		return database.Folder{}, errUnique("folders_pkey")
+	}
+	if _, ok := d.folderNamed(arg.UserID, arg.Name); ok {
+		return database.Folder{}, errUnique("folders_user_id_name_key")
+	}
+	if _, ok := d.users[arg.UserID]; !ok {
+		return database.Folder{}, errForeignKey("folders", "user_id")
+	}
+
+	folder := database.Folder{
+		ID: arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		Name: arg.Name,
+		UserID: arg.UserID,
+	}
+	d.folders[folder.ID] = folder
+	return folder, nil
+}
+
+func (s *memoryStore) GetFolderByName(ctx context.Context, arg database.GetFolderByNameParams) (database.Folder, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	folder, ok := d.folderNamed(arg.UserID, arg.Name)
+	if !ok {
+		return database.Folder{}, sql.ErrNoRows
+	}
+	return folder, nil
+}
+
+func (s *memoryStore) GetFoldersForUser(ctx context.Context, userID uuid.UUID) ([]database.Folder, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	var folders []database.Folder
+	for _, folder := range d.folders {
+		if folder.UserID == userID {
+			folders = append(folders, folder)
+		}
+	}
+	slices.SortFunc(folders, func(a, b database.Folder) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+	return folders, nil
+}
+
+func (s *memoryStore) DeleteFolder(ctx context.Context, arg database.DeleteFolderParams) (int64, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	folder, ok := d.folderNamed(arg.UserID, arg.Name)
+	if !ok {
+		return 0, nil
+	}
+	d.deleteFolder(folder.ID)
+	return 1, nil
+}
+
+// filter_rules.sql
+
+func (s *memoryStore) CreateFilterRule(ctx context.Context, arg database.CreateFilterRuleParams) (database.FilterRule, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	if _, ok := d.filterRules[arg.ID]; ok {
+		return database.FilterRule{}, errUnique("filter_rules_pkey")
+	}
+	if _, ok := d.users[arg.UserID]; !ok {
+		return database.FilterRule{}, errForeignKey("filter_rules", "user_id")
+	}
+	if _, ok := d.feeds[arg.FeedID.UUID]; arg.FeedID.Valid && !ok {
+		return database.FilterRule{}, errForeignKey("filter_rules", "feed_id")
+	}
+
+	rule := database.FilterRule{
+		ID: arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		UserID: arg.UserID,
+		FeedID: arg.FeedID,
+		Pattern: arg.Pattern,
+		IsRegex: arg.IsRegex,
+		Field: arg.Field,
+		Action: arg.Action,
+	}
+	d.filterRules[rule.ID] = rule
+	return rule, nil
+}
+
+func (s *memoryStore) GetFilterRulesForUser(ctx context.Context, userID uuid.UUID) ([]database.GetFilterRulesForUserRow, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	var rows []database.GetFilterRulesForUserRow
+	for _, rule := range sortedFilterRules(d, &userID) {
+		row := database.GetFilterRulesForUserRow{
+			ID: rule.ID,
+			CreatedAt: rule.CreatedAt,
+			UpdatedAt: rule.UpdatedAt,
+			UserID: rule.UserID,
+			FeedID: rule.FeedID,
+			Pattern: rule.Pattern,
+			IsRegex: rule.IsRegex,
+			Field: rule.Field,
+			Action: rule.Action,
+		}
+		if feed, ok := d.feeds[rule.FeedID.UUID]; rule.FeedID.Valid && ok {
+			row.FeedUrl = sql.NullString{String: feed.Url, Valid: true}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func (s *memoryStore) DeleteFilterRule(ctx context.Context, arg database.DeleteFilterRuleParams) (int64, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	rule, ok := d.filterRules[arg.ID]
+	if !ok || rule.UserID != arg.UserID {
+		return 0, nil
+	}
+	delete(d.filterRules, rule.ID)
+	return 1, nil
+}
+
+func (s *memoryStore) MarkPostRead(ctx context.Context, arg database.MarkPostReadParams) error {
+	d, unlock := s.lock()
+	defer unlock()
+
+	key := userPost{UserID: arg.UserID, PostID: arg.PostID}
+	if _, ok := d.postReads[key]; ok {
+		return nil
+	}
+	if _, ok := d.users[arg.UserID]; !ok {
+		return errForeignKey("post_reads", "user_id")
+	}
+	if _, ok := d.posts[arg.PostID]; !ok {
+		return errForeignKey("post_reads", "post_id")
+	}
+	d.postReads[key] = database.PostRead{UserID: arg.UserID, PostID: arg.PostID, ReadAt: now()}
+	return nil
+}
+
+// sortedFilterRules returns the filter rules of userID, or of everyone if
+// userID is nil, oldest first.
+func sortedFilterRules(d *memoryData, userID *uuid.UUID) []database.FilterRule {
+	var rules []database.FilterRule
+	for _, rule := range d.filterRules {
+		if userID == nil || rule.UserID == *userID {
+			rules = append(rules, rule)
+		}
+	}
+	slices.SortFunc(rules, func(a, b database.FilterRule) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return rules
+}
+
+// posts.sql
+
+func (s *memoryStore) UpsertPost(ctx context.Context, arg database.UpsertPostParams) (database.UpsertPostRow, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	t := now()
+	if existing, ok := d.postByURL(arg.Url); ok {
+		// An undated post keeps the date it was first stored with
+		published := existing.PublishedAt
+		if arg.PublishedAt.Valid {
+			published = arg.PublishedAt.Time
+		}
+		// Only changed posts of the same feed are updated; otherwise no row comes back
+		unchanged := existing.Title == arg.Title &&
+			existing.Description == arg.Description &&
+			existing.PublishedAt.Equal(published) &&
+			existing.Author == arg.Author
+		if existing.FeedID != arg.FeedID || unchanged {
+			return database.UpsertPostRow{}, sql.ErrNoRows
+		}
+		existing.Title = arg.Title
+		existing.Description = arg.Description
+		existing.PublishedAt = published
+		existing.Author = arg.Author
+		existing.UpdatedAt = t
+		d.posts[existing.ID] = existing
+		return database.UpsertPostRow{ID: existing.ID}, nil
+	}
+
+	if _, ok := d.posts[arg.ID]; ok {
+		return database.UpsertPostRow{}, errUnique("posts_pkey")
+	}
+	if _, ok := d.feeds[arg.FeedID]; !ok {
+		return database.UpsertPostRow{}, errForeignKey("posts", "feed_id")
+	}
+	// An undated post is dated when it is first seen
+	published := t
+	if arg.PublishedAt.Valid {
+		published = arg.PublishedAt.Time
+	}
+	d.posts[arg.ID] = database.Post{
+		ID: arg.ID,
+		CreatedAt: t,
+		UpdatedAt: t,
+		Title: arg.Title,
+		Url: arg.Url,
+		Description: arg.Description,
+		PublishedAt: published,
+		FeedID: arg.FeedID,
+		Author: arg.Author,
+	}
+	return database.UpsertPostRow{ID: arg.ID, Inserted: true}, nil
+}
+
+func (s *memoryStore) GetPostsForUser(ctx context.Context, arg database.GetPostsForUserParams) ([]database.GetPostsForUserRow, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	var rows []database.GetPostsForUserRow
+	for _, post := range d.posts {
+		follow, ok := d.follow(arg.UserID, post.FeedID)
+		if !ok {
+			continue
+		}
+		if arg.FolderID.Valid && follow.FolderID != arg.FolderID {
+			continue
+		}
+		saved, isSaved := d.savedPosts[userPost{UserID: arg.UserID, PostID: post.ID}]
+		if arg.SavedOnly && !isSaved {
+			continue
+		}
+
+		row := database.GetPostsForUserRow{
+			ID: post.ID,
+			CreatedAt: post.CreatedAt,
+			UpdatedAt: post.UpdatedAt,
+			Title: post.Title,
+			Url: post.Url,
+			Description: post.Description,
+			PublishedAt: post.PublishedAt,
+			FeedID: post.FeedID,
+			Content: post.Content,
+			Author: post.Author,
+			FeedName: d.feeds[post.FeedID].Name,
+		}
+		if follow.DisplayName.Valid {
+			row.FeedName = follow.DisplayName.String
+		}
+		if read, ok := d.postReads[userPost{UserID: arg.UserID, PostID: post.ID}]; ok {
+			row.ReadAt = sql.NullTime{Time: read.ReadAt, Valid: true}
+		}
+		if isSaved {
+			row.SavedAt = sql.NullTime{Time: saved.SavedAt, Valid: true}
+		}
+		rows = append(rows, row)
+	}
+
+	slices.SortFunc(rows, func(a, b database.GetPostsForUserRow) int {
+		return b.PublishedAt.Compare(a.PublishedAt)
+	})
+	return page(rows, arg.Offset, arg.Limit), nil
+}
+
+func (s *memoryStore) SetPostContent(ctx context.Context, arg database.SetPostContentParams) error {
+	d, unlock := s.lock()
+	defer unlock()
+
+	if post, ok := d.posts[arg.ID]; ok {
+		post.Content = arg.Content
+		post.UpdatedAt = now()
+		d.posts[post.ID] = post
+	}
+	return nil
+}
+
+func (s *memoryStore) GetRecentPostTimes(ctx context.Context, arg database.GetRecentPostTimesParams) ([]time.Time, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	var times []time.Time
+	for _, post := range d.posts {
+		if post.FeedID == arg.FeedID {
+			times = append(times, post.PublishedAt)
+		}
+	}
+	slices.SortFunc(times, func(a, b time.Time) int {
+		return b.Compare(a)
+	})
+	return page(times, 0, arg.Limit), nil
+}
+
+// page applies OFFSET and LIMIT to rows.
+func page[T any](rows []T, offset, limit int32) []T {
+	start := min(max(int(offset), 0), len(rows))
+	end := min(start+max(int(limit), 0), len(rows))
+	return rows[start:end]
+}
+
+// snapshots.sql
+
+func (s *memoryStore) ListFolders(ctx context.Context) ([]database.Folder, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	folders := values(d.folders)
+	slices.SortFunc(folders, func(a, b database.Folder) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return folders, nil
+}
+
+func (s *memoryStore) ListFeeds(ctx context.Context) ([]database.Feed, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	return sortedFeeds(d), nil
+}
+
+func (s *memoryStore) ListFeedURLHistory(ctx context.Context) ([]database.FeedUrlHistory, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	history := values(d.feedURLHistory)
+	slices.SortFunc(history, func(a, b database.FeedUrlHistory) int {
+		return a.ChangedAt.Compare(b.ChangedAt)
+	})
+	return history, nil
+}
+
+func (s *memoryStore) ListFeedFollows(ctx context.Context) ([]database.FeedFollow, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	return sortedFeedFollows(d, nil), nil
+}
+
+func (s *memoryStore) ListFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]database.FeedFollow, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	return sortedFeedFollows(d, &userID), nil
+}
+
+func (s *memoryStore) ListPosts(ctx context.Context) ([]database.Post, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	return sortedPosts(d, time.Time{}), nil
+}
+
+func (s *memoryStore) ListPostsPublishedBefore(ctx context.Context, publishedAt time.Time) ([]database.Post, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	return sortedPosts(d, publishedAt), nil
+}
+
+func (s *memoryStore) ListFilterRules(ctx context.Context) ([]database.FilterRule, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	return sortedFilterRules(d, nil), nil
+}
+
+func (s *memoryStore) ListFilterRulesForUser(ctx context.Context, userID uuid.UUID) ([]database.FilterRule, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	return sortedFilterRules(d, &userID), nil
+}
+
+func (s *memoryStore) ListPostReads(ctx context.Context) ([]database.PostRead, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	return sortedPostReads(d, func(database.PostRead) bool { return true }), nil
+}
+
+func (s *memoryStore) ListPostReadsForUser(ctx context.Context, userID uuid.UUID) ([]database.PostRead, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	return sortedPostReads(d, func(read database.PostRead) bool {
+		return read.UserID == userID
+	}), nil
+}
+
+func (s *memoryStore) ListPostReadsPublishedBefore(ctx context.Context, publishedAt time.Time) ([]database.PostRead, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	return sortedPostReads(d, func(read database.PostRead) bool {
+		return d.posts[read.PostID].PublishedAt.Before(publishedAt)
+	}), nil
+}
+
+func (s *memoryStore) ListSavedPosts(ctx context.Context) ([]database.SavedPost, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	return sortedSavedPosts(d, func(database.SavedPost) bool { return true }), nil
+}
+
+func (s *memoryStore) ListSavedPostsForUser(ctx context.Context, userID uuid.UUID) ([]database.SavedPost, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	return sortedSavedPosts(d, func(saved database.SavedPost) bool {
+		return saved.UserID == userID
+	}), nil
+}
+
+func (s *memoryStore) ListSavedPostsPublishedBefore(ctx context.Context, publishedAt time.Time) ([]database.SavedPost, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	return sortedSavedPosts(d, func(saved database.SavedPost) bool {
+		return d.posts[saved.PostID].PublishedAt.Before(publishedAt)
+	}), nil
+}
+
+func (s *memoryStore) DeletePosts(ctx context.Context) (int64, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	deleted := int64(len(d.posts))
+	for id := range d.posts {
+		d.deletePost(id)
+	}
+	return deleted, nil
+}
+
+func (s *memoryStore) DeletePostsPublishedBefore(ctx context.Context, publishedAt time.Time) (int64, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	var deleted int64
+	for id, post := range d.posts {
+		if post.PublishedAt.Before(publishedAt) {
+			d.deletePost(id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// The Restore queries skip rows that already exist or whose parents are
+// missing, returning 0 instead of an error, like INSERT ... ON CONFLICT DO NOTHING.
+
+func (s *memoryStore) RestoreUser(ctx context.Context, arg database.RestoreUserParams) (int64, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	if _, ok := d.users[arg.ID]; ok {
+		return 0, nil
+	}
+	if _, ok := d.userByName(arg.Name); ok {
+		return 0, nil
+	}
+	d.users[arg.ID] = database.User{
+		ID: arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		Name: arg.Name,
+		PasswordHash: arg.PasswordHash,
+		Role: arg.Role,
+	}
+	return 1, nil
+}
+
+func (s *memoryStore) RestoreFolder(ctx context.Context, arg database.RestoreFolderParams) (int64, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	if _, ok := d.users[arg.UserID]; !ok {
+		return 0, nil
+	}
+	if _, ok := d.folders[arg.ID]; ok {
+		return 0, nil
+	}
+	if _, ok := d.folderNamed(arg.UserID, arg.Name); ok {
+		return 0, nil
+	}
+	d.folders[arg.ID] = database.Folder{
+		ID: arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		Name: arg.Name,
+		UserID: arg.UserID,
+	}
+	return 1, nil
+}
+
+func (s *memoryStore) RestoreFeed(ctx context.Context, arg database.RestoreFeedParams) (int64, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	if _, ok := d.users[arg.UserID]; !ok {
+		return 0, nil
+	}
+	if _, ok := d.feeds[arg.ID]; ok {
+		return 0, nil
+	}
+	if _, ok := d.feedByURL(arg.Url); ok {
+		return 0, nil
+	}
+	d.feeds[arg.ID] = database.Feed{
+		ID: arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		LastFetchedAt: arg.LastFetchedAt,
+		Name: arg.Name,
+		Url: arg.Url,
+		UserID: arg.UserID,
+		FetchFullText: arg.FetchFullText,
+		FetchInterval: arg.FetchInterval,
+		AdaptiveInterval: arg.AdaptiveInterval,
+		NextFetchAt: arg.NextFetchAt,
+		TtlMinutes: arg.TtlMinutes,
+		SkipHours: arg.SkipHours,
+		SkipDays: arg.SkipDays,
+		CacheMaxAge: arg.CacheMaxAge,
+		RetryAfter: arg.RetryAfter,
+		ParseWarnings: arg.ParseWarnings,
+		RetentionMaxAge: arg.RetentionMaxAge,
+		RetentionMaxPosts: arg.RetentionMaxPosts,
+	}
+	return 1, nil
+}
+
+func (s *memoryStore) RestoreFeedURLChange(ctx context.Context, arg database.RestoreFeedURLChangeParams) (int64, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	if _, ok := d.feeds[arg.FeedID]; !ok {
+		return 0, nil
+	}
+	if _, ok := d.feedURLHistory[arg.ID]; ok {
+		return 0, nil
+	}
+	d.feedURLHistory[arg.ID] = database.FeedUrlHistory{
+		ID: arg.ID,
+		ChangedAt: arg.ChangedAt,
+		FeedID: arg.FeedID,
+		OldUrl: arg.OldUrl,
+		NewUrl: arg.NewUrl,
+		Merged: arg.Merged,
+	}
+	return 1, nil
+}
+
+func (s *memoryStore) RestoreFeedFollow(ctx context.Context, arg database.RestoreFeedFollowParams) (int64, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	if _, ok := d.users[arg.UserID]; !ok {
+		return 0, nil
+	}
+	if _, ok := d.feeds[arg.FeedID]; !ok {
+		return 0, nil
+	}
+	if _, ok := d.feedFollows[arg.ID]; ok {
+		return 0, nil
+	}
+	if _, ok := d.follow(arg.UserID, arg.FeedID); ok {
+		return 0, nil
+	}
+
+	// A follow whose folder is gone is restored unfiled
+	folderID := arg.FolderID
+	if _, ok := d.folders[folderID.UUID]; !ok {
+		folderID = uuid.NullUUID{}
+	}
+	d.feedFollows[arg.ID] = database.FeedFollow{
+		ID: arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		UserID: arg.UserID,
+		FeedID: arg.FeedID,
+		FolderID: folderID,
+		DisplayName: arg.DisplayName,
+	}
+	return 1, nil
+}
+
+func (s *memoryStore) RestorePost(ctx context.Context, arg database.RestorePostParams) (int64, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	if _, ok := d.feeds[arg.FeedID]; !ok {
+		return 0, nil
+	}
+	if _, ok := d.posts[arg.ID]; ok {
+		return 0, nil
+	}
+	if _, ok := d.postByURL(arg.Url); ok {
+		return 0, nil
+	}
+	d.posts[arg.ID] = database.Post{
+		ID: arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		Title: arg.Title,
+		Url: arg.Url,
+		Description: arg.Description,
+		PublishedAt: arg.PublishedAt,
+		FeedID: arg.FeedID,
+		Content: arg.Content,
+		Author: arg.Author,
+	}
+	return 1, nil
+}
+
+func (s *memoryStore) RestoreFilterRule(ctx context.Context, arg database.RestoreFilterRuleParams) (int64, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	if _, ok := d.users[arg.UserID]; !ok {
+		return 0, nil
+	}
+	if _, ok := d.feeds[arg.FeedID.UUID]; arg.FeedID.Valid && !ok {
+		return 0, nil
+	}
+	if _, ok := d.filterRules[arg.ID]; ok {
+		return 0, nil
+	}
+	d.filterRules[arg.ID] = database.FilterRule{
+		ID: arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		UserID: arg.UserID,
+		FeedID: arg.FeedID,
+		Pattern: arg.Pattern,
+		IsRegex: arg.IsRegex,
+		Field: arg.Field,
+		Action: arg.Action,
+	}
+	return 1, nil
+}
+
+func (s *memoryStore) RestorePostRead(ctx context.Context, arg database.RestorePostReadParams) (int64, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	key := userPost{UserID: arg.UserID, PostID: arg.PostID}
+	if !d.userPostExists(key) {
+		return 0, nil
+	}
+	if _, ok := d.postReads[key]; ok {
+		return 0, nil
+	}
+	d.postReads[key] = database.PostRead{UserID: arg.UserID, PostID: arg.PostID, ReadAt: arg.ReadAt}
+	return 1, nil
+}
+
+func (s *memoryStore) RestoreSavedPost(ctx context.Context, arg database.RestoreSavedPostParams) (int64, error) {
+	d, unlock := s.lock()
+	defer unlock()
+
+	key := userPost{UserID: arg.UserID, PostID: arg.PostID}
+	if !d.userPostExists(key) {
+		return 0, nil
+	}
+	if _, ok := d.savedPosts[key]; ok {
+		return 0, nil
+	}
+	d.savedPosts[key] = database.SavedPost{UserID: arg.UserID, PostID: arg.PostID, SavedAt: arg.SavedAt}
+	return 1, nil
+}
+
+// userPostExists reports whether both the user and the post of key exist.
+func (d *memoryData) userPostExists(key userPost) bool {
+	_, userOK := d.users[key.UserID]
+	_, postOK := d.posts[key.PostID]
+	return userOK && postOK
+}
+
+// sortedFeedFollows returns the follows of userID, or of everyone if userID
+// is nil, oldest first.
+func sortedFeedFollows(d *memoryData, userID *uuid.UUID) []database.FeedFollow {
+	var follows []database.FeedFollow
+	for _, follow := range d.feedFollows {
+		if userID == nil || follow.UserID == *userID {
+			follows = append(follows, follow)
+		}
+	}
+	slices.SortFunc(follows, func(a, b database.FeedFollow) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return follows
+}
+
+// sortedPosts returns the posts published before publishedBefore, or every
+// post if it is zero, oldest first.
+func sortedPosts(d *memoryData, publishedBefore time.Time) []database.Post {
+	var posts []database.Post
+	for _, post := range d.posts {
+		if publishedBefore.IsZero() || post.PublishedAt.Before(publishedBefore) {
+			posts = append(posts, post)
+		}
+	}
+	slices.SortFunc(posts, func(a, b database.Post) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return posts
+}
+
+// sortedPostReads returns the read markers matching keep, in the order they were read.
+func sortedPostReads(d *memoryData, keep func(database.PostRead) bool) []database.PostRead {
+	var reads []database.PostRead
+	for _, read := range d.postReads {
+		if keep(read) {
+			reads = append(reads, read)
+		}
+	}
+	slices.SortFunc(reads, func(a, b database.PostRead) int {
+		return a.ReadAt.Compare(b.ReadAt)
+	})
+	return reads
+}
+
+// sortedSavedPosts returns the saved posts matching keep, in the order they were saved.
+func sortedSavedPosts(d *memoryData, keep func(database.SavedPost) bool) []database.SavedPost {
+	var saved []database.SavedPost
+	for _, s := range d.savedPosts {
+		if keep(s) {
+			saved = append(saved, s)
+		}
+	}
+	slices.SortFunc(saved, func(a, b database.SavedPost) int {
+		return a.SavedAt.Compare(b.SavedAt)
+	})
+	return saved
+}
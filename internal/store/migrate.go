@@ -0,0 +1,317 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nhdewitt/blog-aggregator/internal/app"
+	pgschema "github.com/nhdewitt/blog-aggregator/sql/schema"
+	sqliteschema "github.com/nhdewitt/blog-aggregator/sql/sqlite/schema"
+)
+
+// migration is one goose migration file.
+type migration struct {
+	Version	int64
+	Name	string
+	Up		string
+	Down	string
+}
+
+// MigrationStatus reports whether one migration has been applied.
+type MigrationStatus struct {
+	Version		int64
+	Name		string
+	Applied		bool
+	AppliedAt	time.Time	// zero if not applied or not recorded
+}
+
+// Migrator applies the migrations embedded in gator to its database. It keeps
+// track of them in goose's goose_db_version table, so a database set up with
+// the goose CLI can be managed by gator and the other way around.
+type Migrator struct {
+	db				*sql.DB
+	store			app.Store
+	versionTable	string
+	migrations		[]migration
+}
+
+// OpenMigrator connects to the database at dbURL without checking its schema.
+// Closing the Migrator closes the connection.
+func OpenMigrator(ctx context.Context, dbURL string) (*Migrator, error) {
+	scheme, _, found := strings.Cut(dbURL, ":")
+	if !found {
+		return nil, fmt.Errorf("db_url %q has no scheme, expected postgres:// or sqlite://", dbURL)
+	}
+
+	var m *Migrator
+	var err error
+	switch strings.ToLower(scheme) {
+	case "postgres", "postgresql":
+		var s *postgresStore
+		if s, err = openPostgres(dbURL); err == nil {
+			m, err = newMigrator(s.db, s, postgresVersionTable, pgschema.Migrations)
+		}
+	case "sqlite", "sqlite3", "file":
+		var s *sqliteStore
+		if s, err = openSQLite(dbURL); err == nil {
+			m, err = newMigrator(s.db, s, sqliteVersionTable, sqliteschema.Migrations)
+		}
+	default:
+		return nil, fmt.Errorf("Unsupported database scheme %q, expected postgres:// or sqlite://", scheme)
+	}
+	return m, err
+}
+
+func newMigrator(db *sql.DB, store app.Store, versionTable string, fsys fs.FS) (*Migrator, error) {
+	migrations, err := loadMigrations(fsys)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Migrator{db: db, store: store, versionTable: versionTable, migrations: migrations}, nil
+}
+
+// Close closes the database connection.
+func (m *Migrator) Close() error {
+	return m.db.Close()
+}
+
+// Status lists every migration gator knows about, oldest first.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := m.appliedVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		appliedAt, ok := applied[mig.Version]
+		statuses = append(statuses, MigrationStatus{
+			Version: mig.Version,
+			Name: mig.Name,
+			Applied: ok,
+			AppliedAt: appliedAt,
+		})
+	}
+	return statuses, nil
+}
+
+// Up applies every pending migration, each in its own transaction, and
+// returns the names of the migrations it applied.
+func (m *Migrator) Up(ctx context.Context) ([]string, error) {
+	applied, err := m.appliedVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, mig := range m.migrations {
+		if _, ok := applied[mig.Version]; ok {
+			continue
+		}
+		err := inTx(ctx, m.db, func(tx *sql.Tx) error {
+			if _, err := tx.ExecContext(ctx, mig.Up); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx, "INSERT INTO goose_db_version (version_id, is_applied) VALUES ($1, TRUE)", mig.Version)
+			return err
+		})
+		if err != nil {
+			return names, fmt.Errorf("Error applying migration %s: %w", mig.Name, err)
+		}
+		names = append(names, mig.Name)
+	}
+	return names, nil
+}
+
+// Down rolls back the most recently applied migration and returns its name,
+// or "" if no migrations are applied.
+func (m *Migrator) Down(ctx context.Context) (string, error) {
+	applied, err := m.appliedVersions(ctx)
+	if err != nil {
+		return "", err
+	}
+	latest := latestVersion(applied)
+	if latest == 0 {
+		return "", nil
+	}
+
+	i := indexVersion(m.migrations, latest)
+	if i < 0 {
+		return "", fmt.Errorf("Migration %d was applied by a newer gator and can't be rolled back by this one", latest)
+	}
+	mig := m.migrations[i]
+
+	err = inTx(ctx, m.db, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, mig.Down); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, "DELETE FROM goose_db_version WHERE version_id = $1", mig.Version)
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("Error rolling back migration %s: %w", mig.Name, err)
+	}
+	return mig.Name, nil
+}
+
+// checkSchema returns an error explaining what to do unless the database has
+// exactly the migrations this build of gator expects.
+func (m *Migrator) checkSchema(ctx context.Context) error {
+	applied, err := m.appliedVersions(ctx)
+	if err != nil {
+		return err
+	}
+
+	if latest := latestVersion(applied); latest > 0 && indexVersion(m.migrations, latest) < 0 {
+		return fmt.Errorf("The database schema is at version %d, which is newer than this gator supports (%d); upgrade gator",
+			latest, m.migrations[len(m.migrations)-1].Version)
+	}
+
+	var pending []string
+	for _, mig := range m.migrations {
+		if _, ok := applied[mig.Version]; !ok {
+			pending = append(pending, mig.Name)
+		}
+	}
+	switch {
+	case len(pending) == len(m.migrations):
+		return fmt.Errorf("The database has no schema yet; run 'gator migrate up' to create it")
+	case len(pending) > 0:
+		return fmt.Errorf("The database schema is out of date, %d migrations are pending (%s); run 'gator migrate up' to apply them",
+			len(pending), strings.Join(pending, ", "))
+	}
+	return nil
+}
+
+// appliedVersions returns the migration versions recorded in goose_db_version
+// and when they were applied, ignoring the version 0 row goose starts with.
+func (m *Migrator) appliedVersions(ctx context.Context) (map[int64]time.Time, error) {
+	if err := m.ensureVersionTable(ctx); err != nil {
+		return nil, err
+	}
+
+	rows, err := m.db.QueryContext(ctx, "SELECT version_id, is_applied, tstamp FROM goose_db_version ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("Error reading goose_db_version: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var isApplied bool
+		var tstamp sql.NullTime
+		if err := rows.Scan(&version, &isApplied, &tstamp); err != nil {
+			return nil, fmt.Errorf("Error reading goose_db_version: %w", err)
+		}
+		// Later rows win, as they do for goose
+		if isApplied && version > 0 {
+			applied[version] = tstamp.Time
+		} else {
+			delete(applied, version)
+		}
+	}
+	return applied, rows.Err()
+}
+
+// ensureVersionTable creates goose_db_version the way goose does, with a
+// version 0 row, if it doesn't exist yet.
+func (m *Migrator) ensureVersionTable(ctx context.Context) error {
+	if _, err := m.db.ExecContext(ctx, "SELECT 1 FROM goose_db_version LIMIT 1"); err == nil {
+		return nil
+	}
+
+	err := inTx(ctx, m.db, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, m.versionTable); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, "INSERT INTO goose_db_version (version_id, is_applied) VALUES (0, TRUE)")
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("Error creating goose_db_version: %w", err)
+	}
+	return nil
+}
+
+// latestVersion returns the highest applied version, or 0 if none are.
+func latestVersion(applied map[int64]time.Time) int64 {
+	var latest int64
+	for version := range applied {
+		latest = max(latest, version)
+	}
+	return latest
+}
+
+// indexVersion returns the index of the migration with the given version, or -1.
+func indexVersion(migrations []migration, version int64) int {
+	for i, mig := range migrations {
+		if mig.Version == version {
+			return i
+		}
+	}
+	return -1
+}
+
+// loadMigrations reads the goose migration files in fsys, sorted by version.
+// File names start with the version, e.g. 0115_retention.sql.
+func loadMigrations(fsys fs.FS) ([]migration, error) {
+	names, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []migration
+	for _, name := range names {
+		prefix, _, _ := strings.Cut(name, "_")
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Migration %s doesn't start with a version number", name)
+		}
+
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading migration %s: %w", name, err)
+		}
+		up, down := splitMigration(string(data))
+		migrations = append(migrations, migration{
+			Version: version,
+			Name: strings.TrimSuffix(path.Base(name), ".sql"),
+			Up: up,
+			Down: down,
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// splitMigration returns the statements in the Up and Down sections of a goose migration.
+func splitMigration(text string) (up, down string) {
+	var section *strings.Builder
+	var upSQL, downSQL strings.Builder
+	for _, line := range strings.SplitAfter(text, "\n") {
+		switch strings.TrimSpace(line) {
+		case "-- +goose Up":
+			section = &upSQL
+			continue
+		case "-- +goose Down":
+			section = &downSQL
+			continue
+		}
+		if section != nil {
+			section.WriteString(line)
+		}
+	}
+	return strings.TrimSpace(upSQL.String()), strings.TrimSpace(downSQL.String())
+}
@@ -0,0 +1,215 @@
+package store
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	pgschema "github.com/nhdewitt/blog-aggregator/sql/schema"
+	sqliteschema "github.com/nhdewitt/blog-aggregator/sql/sqlite/schema"
+)
+
+func TestSplitMigration(t *testing.T) {
+	tests := []struct {
+		name		string
+		text		string
+		up, down	string
+	}{
+		{
+			name: "both sections",
+			text: "-- +goose Up\nCREATE TABLE a(id INT);\n\n-- +goose Down\nDROP TABLE a;\n",
+			up: "CREATE TABLE a(id INT);",
+			down: "DROP TABLE a;",
+		},
+		{
+			name: "text before Up is ignored",
+			text: "-- a note\n-- +goose Up\nCREATE TABLE a(id INT);\n-- +goose Down\nDROP TABLE a;",
+			up: "CREATE TABLE a(id INT);",
+			down: "DROP TABLE a;",
+		},
+		{
+			name: "no Down",
+			text: "-- +goose Up\nCREATE TABLE a(id INT);\nCREATE TABLE b(id INT);\n",
+			up: "CREATE TABLE a(id INT);\nCREATE TABLE b(id INT);",
+		},
+		{
+			name: "markers with surrounding spaces",
+			text: "  -- +goose Up  \nCREATE TABLE a(id INT);\n\t-- +goose Down\nDROP TABLE a;",
+			up: "CREATE TABLE a(id INT);",
+			down: "DROP TABLE a;",
+		},
+		{
+			name: "no markers",
+			text: "CREATE TABLE a(id INT);",
+		},
+	}
+	for _, tt := range tests {
+		up, down := splitMigration(tt.text)
+		if up != tt.up || down != tt.down {
+			t.Errorf("%s: splitMigration = %q, %q; want %q, %q", tt.name, up, down, tt.up, tt.down)
+		}
+	}
+}
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0010_ten.sql":	{Data: []byte("-- +goose Up\nCREATE TABLE ten(id INT);\n-- +goose Down\nDROP TABLE ten;")},
+		"0002_two.sql":	{Data: []byte("-- +goose Up\nCREATE TABLE two(id INT);")},
+		"0001_one.sql":	{Data: []byte("-- +goose Up\nCREATE TABLE one(id INT);")},
+		"embed.go":		{Data: []byte("package schema")},
+	}
+	migrations, err := loadMigrations(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, mig := range migrations {
+		names = append(names, mig.Name)
+	}
+	if got := strings.Join(names, " "); got != "0001_one 0002_two 0010_ten" {
+		t.Errorf("migrations = %s, want them in version order", got)
+	}
+	if last := migrations[len(migrations)-1]; last.Version != 10 || last.Down != "DROP TABLE ten;" {
+		t.Errorf("last migration = %+v", last)
+	}
+
+	fsys["latest.sql"] = &fstest.MapFile{Data: []byte("-- +goose Up\n")}
+	if _, err := loadMigrations(fsys); err == nil || !strings.Contains(err.Error(), "latest.sql doesn't start with a version number") {
+		t.Errorf("loadMigrations with an unnumbered file = %v", err)
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	pg, err := loadMigrations(pgschema.Migrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lite, err := loadMigrations(sqliteschema.Migrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, mig := range append(pg, lite...) {
+		if mig.Up == "" || mig.Down == "" {
+			t.Errorf("migration %s is missing its Up or Down section", mig.Name)
+		}
+	}
+	// Every SQLite migration stands for the Postgres one with the same version
+	for _, mig := range lite {
+		if indexVersion(pg, mig.Version) < 0 {
+			t.Errorf("SQLite migration %s has no Postgres counterpart", mig.Name)
+		}
+	}
+	if pgLast, liteLast := pg[len(pg)-1], lite[len(lite)-1]; pgLast.Version != liteLast.Version {
+		t.Errorf("latest migrations are %s for Postgres and %s for SQLite, want the same version", pgLast.Name, liteLast.Name)
+	}
+}
+
+// testMigrator returns a Migrator for a fresh SQLite file with the given
+// migrations instead of the embedded ones.
+func testMigrator(t *testing.T, migrations fstest.MapFS) *Migrator {
+	t.Helper()
+	s, err := openSQLite("sqlite://" + filepath.Join(t.TempDir(), "gator.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := newMigrator(s.db, s, sqliteVersionTable, migrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { m.Close() })
+	return m
+}
+
+func TestCheckSchema(t *testing.T) {
+	ctx := context.Background()
+	one := fstest.MapFS{
+		"0001_a.sql": {Data: []byte("-- +goose Up\nCREATE TABLE a(id INT);\n-- +goose Down\nDROP TABLE a;")},
+	}
+	two := fstest.MapFS{
+		"0001_a.sql": one["0001_a.sql"],
+		"0002_b.sql": {Data: []byte("-- +goose Up\nCREATE TABLE b(id INT);\n-- +goose Down\nDROP TABLE b;")},
+	}
+	three := fstest.MapFS{
+		"0001_a.sql": two["0001_a.sql"],
+		"0002_b.sql": two["0002_b.sql"],
+		"0003_c.sql": {Data: []byte("-- +goose Up\nCREATE TABLE c(id INT);\n-- +goose Down\nDROP TABLE c;")},
+	}
+
+	m := testMigrator(t, two)
+	check := func(want string) {
+		t.Helper()
+		err := m.checkSchema(ctx)
+		switch {
+		case want == "" && err != nil:
+			t.Errorf("checkSchema = %v, want nil", err)
+		case want != "" && (err == nil || !strings.Contains(err.Error(), want)):
+			t.Errorf("checkSchema = %v, want an error containing %q", err, want)
+		}
+	}
+
+	check("no schema yet; run 'gator migrate up'")
+
+	// Bring the database to version 1 with a build that only knows that one
+	older := &Migrator{db: m.db, store: m.store, versionTable: m.versionTable}
+	older.migrations, _ = loadMigrations(one)
+	if _, err := older.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	check("1 migrations are pending (0002_b)")
+
+	if names, err := m.Up(ctx); err != nil || len(names) != 1 || names[0] != "0002_b" {
+		t.Fatalf("Up = %v, %v; want 0002_b applied", names, err)
+	}
+	check("")
+
+	// A newer build applies version 3, which this one doesn't know
+	newer := &Migrator{db: m.db, store: m.store, versionTable: m.versionTable}
+	newer.migrations, _ = loadMigrations(three)
+	if _, err := newer.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	check("schema is at version 3, which is newer than this gator supports (2)")
+	if _, err := m.Down(ctx); err == nil || !strings.Contains(err.Error(), "applied by a newer gator") {
+		t.Errorf("Down past a newer migration = %v", err)
+	}
+
+	// Rolling back with the newer build brings the two in line again
+	if name, err := newer.Down(ctx); err != nil || name != "0003_c" {
+		t.Fatalf("Down = %q, %v; want 0003_c rolled back", name, err)
+	}
+	check("")
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range statuses {
+		if !status.Applied || status.AppliedAt.IsZero() {
+			t.Errorf("status of %s = %+v, want applied with a time", status.Name, status)
+		}
+	}
+}
+
+func TestOpenRequiresMigrations(t *testing.T) {
+	ctx := context.Background()
+	dbURL := "sqlite://" + filepath.Join(t.TempDir(), "gator.db")
+	if _, err := Open(ctx, dbURL); err == nil || !strings.Contains(err.Error(), "run 'gator migrate up'") {
+		t.Fatalf("Open of an empty database = %v, want a hint to migrate", err)
+	}
+
+	m, err := OpenMigrator(ctx, dbURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	s, err := Open(ctx, dbURL)
+	if err != nil {
+		t.Fatalf("Open after migrating: %v", err)
+	}
+	s.Close()
+}
@@ -0,0 +1,50 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	_ "github.com/lib/pq"
+	"github.com/nhdewitt/blog-aggregator/internal/app"
+	"github.com/nhdewitt/blog-aggregator/internal/database"
+)
+
+// postgresVersionTable matches the goose_db_version table goose creates for Postgres.
+const postgresVersionTable = `CREATE TABLE IF NOT EXISTS goose_db_version (
+    id serial NOT NULL,
+    version_id bigint NOT NULL,
+    is_applied boolean NOT NULL,
+    tstamp timestamp NULL DEFAULT now(),
+    PRIMARY KEY(id)
+)`
+
+// postgresStore runs the sqlc queries against Postgres.
+type postgresStore struct {
+	*database.Queries
+	db	*sql.DB		// nil when bound to a transaction
+}
+
+func openPostgres(dbURL string) (*postgresStore, error) {
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		return nil, fmt.Errorf("Error opening database: %w", err)
+	}
+	return &postgresStore{Queries: database.New(db), db: db}, nil
+}
+
+func (s *postgresStore) InTx(ctx context.Context, fn func(q app.Store) error) error {
+	if s.db == nil {
+		return fn(s)
+	}
+	return inTx(ctx, s.db, func(tx *sql.Tx) error {
+		return fn(&postgresStore{Queries: s.Queries.WithTx(tx)})
+	})
+}
+
+func (s *postgresStore) Close() error {
+	if s.db == nil {
+		return nil
+	}
+	return s.db.Close()
+}
@@ -0,0 +1,191 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/nhdewitt/blog-aggregator/internal/app"
+	"github.com/nhdewitt/blog-aggregator/internal/database"
+	_ "modernc.org/sqlite"
+)
+
+// sqliteTimeFormat is how times are stored in SQLite. Times are always UTC,
+// so comparing and sorting the text gives the same order as the times.
+const sqliteTimeFormat = "2006-01-02 15:04:05.999999999-07:00"
+
+// sqliteOptions are added to every SQLite connection: enforce foreign keys,
+// wait on a busy database instead of failing, let readers run alongside a
+// writer, and take the write lock when a transaction starts.
+const sqliteOptions = "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=synchronous(NORMAL)&_time_format=sqlite&_txlock=immediate"
+
+// sqliteVersionTable matches the goose_db_version table goose creates for SQLite.
+const sqliteVersionTable = `CREATE TABLE IF NOT EXISTS goose_db_version (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    version_id INTEGER NOT NULL,
+    is_applied INTEGER NOT NULL,
+    tstamp TIMESTAMP DEFAULT (datetime('now'))
+)`
+
+// sqliteStore runs the sqlc queries, which are written for Postgres, against
+// SQLite. sqliteConn rewrites the few constructs SQLite spells differently and
+// the queries SQLite can't run at all are overridden below.
+type sqliteStore struct {
+	*database.Queries
+	conn	sqliteConn
+	db		*sql.DB		// nil when bound to a transaction
+}
+
+func openSQLite(dbURL string) (*sqliteStore, error) {
+	path, err := sqlitePath(dbURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("Error creating database directory: %w", err)
+	}
+
+	db, err := sql.Open("sqlite", "file:"+path+"?"+sqliteOptions)
+	if err != nil {
+		return nil, fmt.Errorf("Error opening database: %w", err)
+	}
+
+	return newSQLiteStore(db, db), nil
+}
+
+// sqlitePath returns the database file named by a sqlite URL. Both
+// sqlite://relative/path.db and sqlite:///absolute/path.db are accepted,
+// as is a leading ~/ for the home directory.
+func sqlitePath(dbURL string) (string, error) {
+	_, path, _ := strings.Cut(dbURL, ":")
+	path = strings.TrimPrefix(path, "//")
+	path, _, _ = strings.Cut(path, "?")
+	if path == "" {
+		return "", fmt.Errorf("db_url %q doesn't name a database file", dbURL)
+	}
+
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("Error finding home directory: %w", err)
+		}
+		path = filepath.Join(home, rest)
+	}
+	return path, nil
+}
+
+func newSQLiteStore(db *sql.DB, dbtx database.DBTX) *sqliteStore {
+	conn := sqliteConn{dbtx}
+	return &sqliteStore{Queries: database.New(conn), conn: conn, db: db}
+}
+
+func (s *sqliteStore) InTx(ctx context.Context, fn func(q app.Store) error) error {
+	if s.db == nil {
+		return fn(s)
+	}
+	return inTx(ctx, s.db, func(tx *sql.Tx) error {
+		return fn(newSQLiteStore(nil, tx))
+	})
+}
+
+func (s *sqliteStore) Close() error {
+	if s.db == nil {
+		return nil
+	}
+	return s.db.Close()
+}
+
+// CreateFeedFollow inserts the follow and then reads the names that the
+// Postgres query returns from a data-modifying CTE, which SQLite lacks.
+func (s *sqliteStore) CreateFeedFollow(ctx context.Context, arg database.CreateFeedFollowParams) (database.CreateFeedFollowRow, error) {
+	_, err := s.conn.ExecContext(ctx, `INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id, folder_id)
+VALUES ($1, $2, $3, $4, $5, $6)`,
+		arg.ID, arg.CreatedAt, arg.UpdatedAt, arg.UserID, arg.FeedID, arg.FolderID)
+	if err != nil {
+		return database.CreateFeedFollowRow{}, err
+	}
+
+	row := s.conn.QueryRowContext(ctx, `SELECT feed_follows.id, feed_follows.created_at, feed_follows.updated_at,
+    feed_follows.user_id, feed_follows.feed_id, feed_follows.folder_id, feed_follows.display_name,
+    feeds.name AS feed_name, users.name AS user_name
+FROM feed_follows
+INNER JOIN feeds ON feed_follows.feed_id = feeds.id
+INNER JOIN users ON feed_follows.user_id = users.id
+WHERE feed_follows.id = $1`, arg.ID)
+	var i database.CreateFeedFollowRow
+	err = row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.FeedID,
+		&i.FolderID,
+		&i.DisplayName,
+		&i.FeedName,
+		&i.UserName,
+	)
+	return i, err
+}
+
+// UnfollowFeed replaces the Postgres DELETE ... USING form.
+func (s *sqliteStore) UnfollowFeed(ctx context.Context, arg database.UnfollowFeedParams) error {
+	_, err := s.conn.ExecContext(ctx, `DELETE FROM feed_follows
+WHERE user_id = $1
+AND feed_id IN (SELECT id FROM feeds WHERE url = $2)`, arg.ID, arg.Url)
+	return err
+}
+
+// castPattern matches Postgres type casts such as ::uuid or ::timestamp.
+var castPattern = regexp.MustCompile(`::[a-z]+`)
+
+// sqliteConn adapts Postgres queries to SQLite on their way to the driver:
+// type casts are dropped, NOW() becomes the current time in sqliteTimeFormat,
+// and time arguments are converted to UTC. $1 style parameters work as is.
+type sqliteConn struct {
+	db database.DBTX
+}
+
+func (c sqliteConn) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return c.db.ExecContext(ctx, toSQLite(query), sqliteArgs(args)...)
+}
+
+func (c sqliteConn) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return c.db.PrepareContext(ctx, toSQLite(query))
+}
+
+func (c sqliteConn) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return c.db.QueryContext(ctx, toSQLite(query), sqliteArgs(args)...)
+}
+
+func (c sqliteConn) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return c.db.QueryRowContext(ctx, toSQLite(query), sqliteArgs(args)...)
+}
+
+// toSQLite rewrites a Postgres query for SQLite. Every NOW() in a statement
+// gets the same time, as it does in Postgres.
+func toSQLite(query string) string {
+	query = castPattern.ReplaceAllString(query, "")
+	if strings.Contains(query, "NOW()") {
+		now := "'" + time.Now().UTC().Format(sqliteTimeFormat) + "'"
+		query = strings.ReplaceAll(query, "NOW()", now)
+	}
+	return query
+}
+
+// sqliteArgs converts time arguments to UTC so stored times sort correctly.
+func sqliteArgs(args []interface{}) []interface{} {
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case time.Time:
+			args[i] = v.UTC()
+		case sql.NullTime:
+			args[i] = sql.NullTime{Time: v.Time.UTC(), Valid: v.Valid}
+		}
+	}
+	return args
+}
@@ -0,0 +1,142 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSQLitePath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	tests := []struct {
+		url		string
+		want	string
+		ok		bool
+	}{
+		{"sqlite://gator.db", "gator.db", true},
+		{"sqlite://data/gator.db", "data/gator.db", true},
+		{"sqlite:///var/lib/gator/gator.db", "/var/lib/gator/gator.db", true},
+		{"sqlite://~/.gator/gator.db", filepath.Join(home, ".gator/gator.db"), true},
+		{"sqlite:gator.db", "gator.db", true},
+		{"sqlite3://gator.db", "gator.db", true},
+		{"file:gator.db", "gator.db", true},
+		{"sqlite://gator.db?mode=ro", "gator.db", true},
+		{"sqlite://", "", false},
+		{"sqlite://?mode=memory", "", false},
+	}
+	for _, tt := range tests {
+		got, err := sqlitePath(tt.url)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("sqlitePath(%q) = %q, %v; want %q, ok %v", tt.url, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestToSQLite(t *testing.T) {
+	tests := []struct {
+		query	string
+		want	string
+	}{
+		{"SELECT * FROM users WHERE name = $1", "SELECT * FROM users WHERE name = $1"},
+		{"SELECT sqlc.arg('id')::uuid, $2::timestamp", "SELECT sqlc.arg('id'), $2"},
+		{"WHERE (sqlc.narg('folder_id')::uuid IS NULL)", "WHERE (sqlc.narg('folder_id') IS NULL)"},
+		{"UPDATE feeds SET updated_at = NOW() WHERE id = $1", "UPDATE feeds SET updated_at = <now> WHERE id = $1"},
+		{"VALUES ($1, NOW(), NOW())", "VALUES ($1, <now>, <now>)"},
+	}
+	stamp := regexp.MustCompile(`'\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}(\.\d+)?\+00:00'`)
+	for _, tt := range tests {
+		got := toSQLite(tt.query)
+		// Every NOW() in one statement is the same time
+		if stamps := stamp.FindAllString(got, -1); len(stamps) > 1 && stamps[0] != stamps[1] {
+			t.Errorf("toSQLite(%q) = %q, want one time for every NOW()", tt.query, got)
+		}
+		if got := stamp.ReplaceAllString(got, "<now>"); got != tt.want {
+			t.Errorf("toSQLite(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestSQLiteArgs(t *testing.T) {
+	zone := time.FixedZone("UTC-5", -5*60*60)
+	local := time.Date(2024, 3, 1, 7, 0, 0, 0, zone)
+	utc := local.UTC()
+
+	got := sqliteArgs([]interface{}{
+		local,
+		sql.NullTime{Time: local, Valid: true},
+		sql.NullTime{},
+		"text",
+		int64(3),
+	})
+	want := []interface{}{
+		utc,
+		sql.NullTime{Time: utc, Valid: true},
+		sql.NullTime{Time: time.Time{}.UTC()},
+		"text",
+		int64(3),
+	}
+	for i := range want {
+		switch w := want[i].(type) {
+		case time.Time:
+			if g, ok := got[i].(time.Time); !ok || !g.Equal(w) || g.Location() != time.UTC {
+				t.Errorf("arg %d = %v, want %v in UTC", i, got[i], w)
+			}
+		case sql.NullTime:
+			if g, ok := got[i].(sql.NullTime); !ok || g.Valid != w.Valid || !g.Time.Equal(w.Time) || g.Time.Location() != time.UTC {
+				t.Errorf("arg %d = %v, want %v in UTC", i, got[i], w)
+			}
+		default:
+			if got[i] != w {
+				t.Errorf("arg %d = %v, want %v", i, got[i], w)
+			}
+		}
+	}
+}
+
+func TestOpenMigratorRejectsURL(t *testing.T) {
+	tests := []struct {
+		url		string
+		want	string
+	}{
+		{"gator.db", "has no scheme"},
+		{"mysql://localhost/gator", `Unsupported database scheme "mysql"`},
+		{"sqlite://", "doesn't name a database file"},
+	}
+	for _, tt := range tests {
+		_, err := OpenMigrator(context.Background(), tt.url)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("OpenMigrator(%q) = %v, want an error containing %q", tt.url, err, tt.want)
+		}
+	}
+}
+
+func TestOpenSQLiteCreatesDirectory(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "nested", "dir", "gator.db")
+	m, err := OpenMigrator(ctx, "sqlite://"+path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o700 {
+		t.Errorf("database directory permissions = %o, want 700", perm)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("database file: %v", err)
+	}
+}
@@ -0,0 +1,48 @@
+// Package store opens the database gator stores its data in.
+// The backend is chosen by the scheme of the db_url setting.
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/nhdewitt/blog-aggregator/internal/app"
+)
+
+// Open connects to the database at dbURL and returns a Store for it, or an
+// error saying how to bring the schema up to date if it isn't.
+//
+// postgres:// and postgresql:// URLs use Postgres. sqlite: URLs name a local
+// database file, e.g. sqlite://gator.db or sqlite:///home/me/.gator/gator.db,
+// which is created if it doesn't exist. Either way the schema is created and
+// upgraded with 'gator migrate up'.
+func Open(ctx context.Context, dbURL string) (app.Store, error) {
+	m, err := OpenMigrator(ctx, dbURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.checkSchema(ctx); err != nil {
+		m.Close()
+		return nil, err
+	}
+	return m.store, nil
+}
+
+// inTx runs fn in a transaction on db, committing if fn succeeds and rolling
+// back if it returns an error.
+func inTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("Error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("Error committing transaction: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,357 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nhdewitt/blog-aggregator/internal/app"
+	"github.com/nhdewitt/blog-aggregator/internal/database"
+)
+
+// eachStore runs test against the in-memory store and a migrated SQLite
+// database, so both behave the way the handlers expect.
+func eachStore(t *testing.T, test func(t *testing.T, s app.Store)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, NewMemory())
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		ctx := context.Background()
+		dbURL := "sqlite://" + filepath.Join(t.TempDir(), "gator.db")
+		m, err := OpenMigrator(ctx, dbURL)
+		if err != nil {
+			t.Fatalf("Error opening database: %v", err)
+		}
+		if _, err := m.Up(ctx); err != nil {
+			t.Fatalf("Error migrating database: %v", err)
+		}
+		m.Close()
+
+		s, err := Open(ctx, dbURL)
+		if err != nil {
+			t.Fatalf("Error opening store: %v", err)
+		}
+		t.Cleanup(func() { s.Close() })
+		test(t, s)
+	})
+}
+
+func createUser(t *testing.T, s app.Store, name string) database.User {
+	t.Helper()
+	user, err := s.CreateUser(context.Background(), database.CreateUserParams{
+		ID: uuid.New(),
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+		Name: name,
+	})
+	if err != nil {
+		t.Fatalf("Error creating user %s: %v", name, err)
+	}
+	return user
+}
+
+func addFeed(t *testing.T, s app.Store, user database.User, url string) database.Feed {
+	t.Helper()
+	feed, err := s.AddFeed(context.Background(), database.AddFeedParams{
+		ID: uuid.New(),
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+		Name: url,
+		Url: url,
+		UserID: user.ID,
+	})
+	if err != nil {
+		t.Fatalf("Error adding feed %s: %v", url, err)
+	}
+	return feed
+}
+
+func TestUsers(t *testing.T) {
+	eachStore(t, func(t *testing.T, s app.Store) {
+		ctx := context.Background()
+		alice := createUser(t, s, "alice")
+		bob := createUser(t, s, "bob")
+		if alice.Role != "admin" || bob.Role != "user" {
+			t.Errorf("roles = %s, %s; want the first user to be admin", alice.Role, bob.Role)
+		}
+
+		if _, err := s.CreateUser(ctx, database.CreateUserParams{ID: uuid.New(), Name: "alice"}); err == nil {
+			t.Error("creating a second alice succeeded")
+		}
+		if _, err := s.GetUser(ctx, "carol"); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("GetUser of a missing user = %v, want sql.ErrNoRows", err)
+		}
+		if n, err := s.CountAdmins(ctx); err != nil || n != 1 {
+			t.Errorf("CountAdmins = %d, %v; want 1", n, err)
+		}
+	})
+}
+
+func TestUpsertPost(t *testing.T) {
+	eachStore(t, func(t *testing.T, s app.Store) {
+		ctx := context.Background()
+		user := createUser(t, s, "alice")
+		feed := addFeed(t, s, user, "https://blog.example/rss")
+		other := addFeed(t, s, user, "https://other.example/rss")
+		published := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+		post := database.UpsertPostParams{
+			ID: uuid.New(),
+			Title: "Post",
+			Url: "https://blog.example/post",
+			PublishedAt: sql.NullTime{Time: published, Valid: true},
+			FeedID: feed.ID,
+		}
+		edited := post
+		edited.ID = uuid.New()
+		edited.Title = "Post, edited"
+		elsewhere := edited
+		elsewhere.ID = uuid.New()
+		elsewhere.Title = "Copied"
+		elsewhere.FeedID = other.ID
+		undated := edited
+		undated.ID = uuid.New()
+		undated.PublishedAt = sql.NullTime{}
+
+		tests := []struct {
+			name		string
+			arg			database.UpsertPostParams
+			wantErr		error
+			inserted	bool
+		}{
+			{"new post", post, nil, true},
+			{"unchanged", post, sql.ErrNoRows, false},
+			{"edited", edited, nil, false},
+			{"date gone missing", undated, sql.ErrNoRows, false},
+			{"same URL in another feed", elsewhere, sql.ErrNoRows, false},
+		}
+		for _, tt := range tests {
+			row, err := s.UpsertPost(ctx, tt.arg)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("%s: UpsertPost error = %v, want %v", tt.name, err, tt.wantErr)
+			}
+			if err == nil && (row.ID != post.ID || row.Inserted != tt.inserted) {
+				t.Errorf("%s: UpsertPost = %+v, want ID %s inserted %v", tt.name, row, post.ID, tt.inserted)
+			}
+		}
+
+		stored, err := s.GetPostByURL(ctx, post.Url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored.Title != "Post, edited" || stored.FeedID != feed.ID || !stored.PublishedAt.Equal(published) {
+			t.Errorf("stored post = %+v, want the edit in the original feed", stored)
+		}
+
+		// A post that never had a date is dated when first stored, and keeps it
+		before := time.Now().UTC().Add(-time.Second)
+		first := database.UpsertPostParams{ID: uuid.New(), Title: "Undated", Url: "https://blog.example/undated", FeedID: feed.ID}
+		if _, err := s.UpsertPost(ctx, first); err != nil {
+			t.Fatal(err)
+		}
+		again := first
+		again.ID = uuid.New()
+		if _, err := s.UpsertPost(ctx, again); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("UpsertPost of the same undated post = %v, want sql.ErrNoRows", err)
+		}
+		stored, err = s.GetPostByURL(ctx, first.Url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored.PublishedAt.Before(before) || stored.PublishedAt.After(time.Now().UTC().Add(time.Second)) {
+			t.Errorf("undated post published at %v, want about now", stored.PublishedAt)
+		}
+	})
+}
+
+func TestGetNextFeedToFetch(t *testing.T) {
+	eachStore(t, func(t *testing.T, s app.Store) {
+		ctx := context.Background()
+		user := createUser(t, s, "alice")
+		a := addFeed(t, s, user, "https://a.example/rss")
+		b := addFeed(t, s, user, "https://b.example/rss")
+
+		next := func(fetchedBefore time.Time) (uuid.UUID, error) {
+			feed, err := s.GetNextFeedToFetch(ctx, database.GetNextFeedToFetchParams{
+				Now: time.Now().UTC(),
+				FetchedBefore: fetchedBefore,
+			})
+			return feed.ID, err
+		}
+
+		passStart := time.Now().UTC()
+		if err := s.MarkFeedFetched(ctx, database.MarkFeedFetchedParams{ID: a.ID}); err != nil {
+			t.Fatal(err)
+		}
+		if id, err := next(passStart.Add(time.Hour)); err != nil || id != b.ID {
+			t.Fatalf("next feed = %v, %v; want the never fetched feed first", id, err)
+		}
+		if err := s.DeferFeedFetch(ctx, database.DeferFeedFetchParams{
+			ID: b.ID,
+			NextFetchAt: sql.NullTime{Time: time.Now().Add(time.Hour), Valid: true},
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if id, err := next(passStart.Add(time.Hour)); err != nil || id != a.ID {
+			t.Errorf("next feed = %v, %v; want the deferred feed skipped", id, err)
+		}
+		if _, err := next(passStart.Add(-time.Minute)); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("next feed fetched before the pass = %v, want sql.ErrNoRows", err)
+		}
+	})
+}
+
+func TestDeleteOrphanedFeedsKeepsSavedPosts(t *testing.T) {
+	eachStore(t, func(t *testing.T, s app.Store) {
+		ctx := context.Background()
+		user := createUser(t, s, "alice")
+		saved := addFeed(t, s, user, "https://saved.example/rss")
+		addFeed(t, s, user, "https://plain.example/rss")
+		addFeed(t, s, user, "https://other.example/rss")
+
+		post, err := s.UpsertPost(ctx, database.UpsertPostParams{
+			ID: uuid.New(),
+			Title: "Keeper",
+			Url: "https://saved.example/keeper",
+			PublishedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+			FeedID: saved.ID,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.SavePost(ctx, database.SavePostParams{UserID: user.ID, PostID: post.ID}); err != nil {
+			t.Fatal(err)
+		}
+
+		tests := []struct {
+			url		string
+			removed	int64
+		}{
+			{"https://saved.example/rss", 0},
+			{"https://plain.example/rss", 1},
+			{"https://missing.example/rss", 0},
+		}
+		for _, tt := range tests {
+			removed, err := s.DeleteFeedIfOrphaned(ctx, tt.url)
+			if err != nil || removed != tt.removed {
+				t.Errorf("DeleteFeedIfOrphaned(%s) = %d, %v; want %d", tt.url, removed, err, tt.removed)
+			}
+		}
+
+		urls, err := s.DeleteOrphanedFeeds(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(urls) != 1 || urls[0] != "https://other.example/rss" {
+			t.Errorf("DeleteOrphanedFeeds = %q, want only the feed without saved posts", urls)
+		}
+		if _, err := s.GetPostByURL(ctx, "https://saved.example/keeper"); err != nil {
+			t.Errorf("saved post after removing orphaned feeds: %v", err)
+		}
+	})
+}
+
+func TestFollowAndUnfollow(t *testing.T) {
+	// SQLite runs its own versions of these two queries
+	eachStore(t, func(t *testing.T, s app.Store) {
+		ctx := context.Background()
+		alice := createUser(t, s, "alice")
+		feed := addFeed(t, s, alice, "https://blog.example/rss")
+
+		params := database.CreateFeedFollowParams{
+			ID: uuid.New(),
+			CreatedAt: time.Now().UTC(),
+			UpdatedAt: time.Now().UTC(),
+			UserID: alice.ID,
+			FeedID: feed.ID,
+		}
+		row, err := s.CreateFeedFollow(ctx, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if row.ID != params.ID || row.FeedName != feed.Name || row.UserName != "alice" {
+			t.Errorf("CreateFeedFollow = %+v, want the follow with feed and user names", row)
+		}
+		params.ID = uuid.New()
+		if _, err := s.CreateFeedFollow(ctx, params); err == nil {
+			t.Error("CreateFeedFollow followed the same feed twice")
+		}
+
+		err = s.UnfollowFeed(ctx, database.UnfollowFeedParams{ID: alice.ID, Url: feed.Url})
+		if err != nil {
+			t.Fatal(err)
+		}
+		follows, err := s.GetFeedFollowsForUser(ctx, alice.ID)
+		if err != nil || len(follows) != 0 {
+			t.Errorf("follows after UnfollowFeed = %v, %v; want none", follows, err)
+		}
+	})
+}
+
+func TestInTxRollsBack(t *testing.T) {
+	eachStore(t, func(t *testing.T, s app.Store) {
+		ctx := context.Background()
+		failed := errors.New("failed")
+		err := s.InTx(ctx, func(q app.Store) error {
+			createUser(t, q, "alice")
+			return failed
+		})
+		if !errors.Is(err, failed) {
+			t.Fatalf("InTx = %v, want the error returned by fn", err)
+		}
+		if _, err := s.GetUser(ctx, "alice"); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("GetUser after a rolled back transaction = %v, want sql.ErrNoRows", err)
+		}
+	})
+}
+
+func TestUndatedPostsMigration(t *testing.T) {
+	ctx := context.Background()
+	dbURL := "sqlite://" + filepath.Join(t.TempDir(), "gator.db")
+	m, err := OpenMigrator(ctx, dbURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	s, err := Open(ctx, dbURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	// A post stored with the zero date by an older gator
+	feed := addFeed(t, s, createUser(t, s, "alice"), "https://blog.example/rss")
+	created := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	_, err = s.RestorePost(ctx, database.RestorePostParams{
+		ID: uuid.New(),
+		CreatedAt: created,
+		UpdatedAt: created,
+		Title: "Undated",
+		Url: "https://blog.example/undated",
+		FeedID: feed.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.Down(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	post, err := s.GetPostByURL(ctx, "https://blog.example/undated")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !post.PublishedAt.Equal(created) {
+		t.Errorf("undated post published at %v after migrating, want its creation time %v", post.PublishedAt, created)
+	}
+}
@@ -1,12 +1,13 @@
 -- name: CreateFeedFollow :one
 WITH inserted_feed_follow AS (
-    INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id)
+    INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id, folder_id)
     VALUES (
         $1,
         $2,
         $3,
         $4,
-        $5
+        $5,
+        $6
     ) RETURNING *
 )
 SELECT
@@ -18,15 +19,34 @@ INNER JOIN feeds ON inserted_feed_follow.feed_id = feeds.id
 INNER JOIN users ON inserted_feed_follow.user_id = users.id;
 
 -- name: GetFeedFollowsForUser :many
-SELECT feeds.name AS name, users.name AS user_name
+SELECT COALESCE(feed_follows.display_name, feeds.name) AS name, feeds.url AS url, users.name AS user_name, folders.name AS folder_name
 FROM feed_follows
 INNER JOIN feeds ON feed_follows.feed_id = feeds.id
 INNER JOIN users ON feed_follows.user_id = users.id
-WHERE feed_follows.user_id = $1;
+LEFT JOIN folders ON feed_follows.folder_id = folders.id
+WHERE feed_follows.user_id = $1
+ORDER BY folders.name ASC NULLS FIRST, COALESCE(feed_follows.display_name, feeds.name) ASC;
 
 -- name: UnfollowFeed :exec
 DELETE FROM feed_follows
 USING feeds, users
 WHERE feed_follows.feed_id = feeds.id
+AND feed_follows.user_id = users.id
 AND users.id = $1
+AND feeds.url = $2;
+
+-- name: SetFeedFollowFolder :execrows
+UPDATE feed_follows
+SET folder_id = $3, updated_at = NOW()
+FROM feeds
+WHERE feed_follows.feed_id = feeds.id
+AND feed_follows.user_id = $1
+AND feeds.url = $2;
+
+-- name: SetFeedFollowDisplayName :execrows
+UPDATE feed_follows
+SET display_name = $3, updated_at = NOW()
+FROM feeds
+WHERE feed_follows.feed_id = feeds.id
+AND feed_follows.user_id = $1
 AND feeds.url = $2;
\ No newline at end of file
@@ -0,0 +1,40 @@
+-- name: SetFeedURL :exec
+UPDATE feeds
+SET url = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: MoveFeedFollows :exec
+UPDATE feed_follows
+SET feed_id = sqlc.arg('to_feed_id'), updated_at = NOW()
+WHERE feed_follows.feed_id = sqlc.arg('from_feed_id')
+AND feed_follows.user_id NOT IN (
+    SELECT existing.user_id FROM feed_follows AS existing
+    WHERE existing.feed_id = sqlc.arg('to_feed_id')
+);
+
+-- name: MoveFeedPosts :exec
+UPDATE posts
+SET feed_id = sqlc.arg('to_feed_id')
+WHERE feed_id = sqlc.arg('from_feed_id');
+
+-- name: MoveFeedFilterRules :exec
+UPDATE filter_rules
+SET feed_id = sqlc.arg('to_feed_id'), updated_at = NOW()
+WHERE feed_id = sqlc.arg('from_feed_id');
+
+-- name: DeleteFeed :exec
+DELETE FROM feeds WHERE id = $1;
+
+-- name: RecordFeedURLChange :exec
+INSERT INTO feed_url_history (id, changed_at, feed_id, old_url, new_url, merged)
+VALUES ($1, NOW(), $2, $3, $4, $5);
+
+-- name: GetFeedURLHistory :many
+SELECT * FROM feed_url_history
+WHERE feed_id = $1
+ORDER BY changed_at ASC;
+
+-- name: MoveFeedURLHistory :exec
+UPDATE feed_url_history
+SET feed_id = sqlc.arg('to_feed_id')
+WHERE feed_id = sqlc.arg('from_feed_id');
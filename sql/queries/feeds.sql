@@ -11,9 +11,54 @@ VALUES(
 RETURNING *;
 
 -- name: PrintAllFeeds :many
-SELECT feeds.name AS feed_name, feeds.url AS feed_url, users.name AS user_name
+SELECT feeds.name AS feed_name, feeds.url AS feed_url, users.name AS user_name, feeds.fetch_interval, feeds.adaptive_interval, feeds.parse_warnings,
+    feeds.retention_max_age, feeds.retention_max_posts
 FROM feeds
 INNER JOIN users ON feeds.user_id = users.id;
 
 -- name: FindFeedsByURL :one
-SELECT * FROM feeds WHERE url = $1;
\ No newline at end of file
+SELECT * FROM feeds WHERE url = $1;
+
+-- name: SetFeedFullText :exec
+UPDATE feeds
+SET fetch_full_text = $2, updated_at = NOW()
+WHERE id = $1;
+
+
+-- name: ReassignFeeds :exec
+UPDATE feeds
+SET user_id = sqlc.arg('to_user_id'), updated_at = NOW()
+WHERE user_id = sqlc.arg('from_user_id');
+
+-- name: GetFeedUsage :one
+SELECT
+    (SELECT COUNT(*) FROM feed_follows WHERE feed_follows.feed_id = $1) AS followers,
+    (SELECT COUNT(*) FROM posts WHERE posts.feed_id = $1) AS posts,
+    (SELECT COUNT(*) FROM filter_rules WHERE filter_rules.feed_id = $1) AS rules;
+
+-- name: SetFeedOwner :exec
+UPDATE feeds
+SET user_id = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: DeleteFeedIfOrphaned :execrows
+-- A feed holding posts someone saved is kept, or the saves would cascade away
+DELETE FROM feeds
+WHERE feeds.url = $1
+AND NOT EXISTS (SELECT 1 FROM feed_follows WHERE feed_follows.feed_id = feeds.id)
+AND NOT EXISTS (
+    SELECT 1 FROM saved_posts
+    INNER JOIN posts ON saved_posts.post_id = posts.id
+    WHERE posts.feed_id = feeds.id
+);
+
+-- name: DeleteOrphanedFeeds :many
+-- A feed holding posts someone saved is kept, or the saves would cascade away
+DELETE FROM feeds
+WHERE NOT EXISTS (SELECT 1 FROM feed_follows WHERE feed_follows.feed_id = feeds.id)
+AND NOT EXISTS (
+    SELECT 1 FROM saved_posts
+    INNER JOIN posts ON saved_posts.post_id = posts.id
+    WHERE posts.feed_id = feeds.id
+)
+RETURNING url;
\ No newline at end of file
@@ -1,9 +1,31 @@
 -- name: MarkFeedFetched :exec
 UPDATE feeds
-SET last_fetched_at = NOW(), updated_at = NOW()
+SET last_fetched_at = NOW(),
+    updated_at = NOW(),
+    next_fetch_at = $2,
+    fetch_interval = $3,
+    ttl_minutes = $4,
+    skip_hours = $5,
+    skip_days = $6,
+    cache_max_age = $7,
+    parse_warnings = $8,
+    retry_after = NULL
+WHERE id = $1;
+
+-- name: DeferFeedFetch :exec
+UPDATE feeds
+SET next_fetch_at = $2, retry_after = $3, updated_at = NOW()
 WHERE id = $1;
 
 -- name: GetNextFeedToFetch :one
-SELECT id, url FROM feeds
-ORDER BY last_fetched_at ASC NULLS FIRST
-LIMIT 1;
\ No newline at end of file
+-- Feeds without a scheduled fetch have been due since they were last fetched
+SELECT * FROM feeds
+WHERE (next_fetch_at IS NULL OR next_fetch_at <= sqlc.arg('now')::timestamp)
+AND (last_fetched_at IS NULL OR last_fetched_at < sqlc.arg('fetched_before')::timestamp)
+ORDER BY COALESCE(next_fetch_at, last_fetched_at) ASC NULLS FIRST
+LIMIT 1;
+
+-- name: SetFeedFetchInterval :exec
+UPDATE feeds
+SET fetch_interval = $2, adaptive_interval = $3, next_fetch_at = $4, updated_at = NOW()
+WHERE id = $1;
\ No newline at end of file
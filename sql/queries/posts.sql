@@ -1,20 +1,49 @@
--- name: CreatePost :exec
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
+-- name: UpsertPost :one
+-- A post without a usable date is dated when first seen and keeps that date
+-- on later updates, so retention ages it like any other post
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, author)
 VALUES (
-    $1,
+    sqlc.arg('id'),
     NOW(),
     NOW(),
-    $2,
-    $3,
-    $4,
-    $5,
-    $6
+    sqlc.arg('title'),
+    sqlc.arg('url'),
+    sqlc.narg('description'),
+    COALESCE(sqlc.narg('published_at')::timestamp, NOW()),
+    sqlc.arg('feed_id'),
+    sqlc.narg('author')
 )
-ON CONFLICT (url) DO NOTHING;
+ON CONFLICT (url) DO UPDATE
+SET title = EXCLUDED.title,
+    description = EXCLUDED.description,
+    published_at = COALESCE(sqlc.narg('published_at')::timestamp, posts.published_at),
+    author = EXCLUDED.author,
+    updated_at = NOW()
+WHERE posts.feed_id = EXCLUDED.feed_id
+AND (posts.title, posts.description, posts.published_at, posts.author)
+    IS DISTINCT FROM (EXCLUDED.title, EXCLUDED.description, COALESCE(sqlc.narg('published_at')::timestamp, posts.published_at), EXCLUDED.author)
+RETURNING id, created_at = updated_at AS inserted;
 
 -- name: GetPostsForUser :many
-SELECT * FROM posts
+SELECT posts.*, COALESCE(feed_follows.display_name, feeds.name) AS feed_name, post_reads.read_at, saved_posts.saved_at
+FROM posts
 INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds ON posts.feed_id = feeds.id
+LEFT JOIN post_reads ON post_reads.post_id = posts.id AND post_reads.user_id = feed_follows.user_id
+LEFT JOIN saved_posts ON saved_posts.post_id = posts.id AND saved_posts.user_id = feed_follows.user_id
 WHERE feed_follows.user_id = $2
+AND (sqlc.narg('folder_id')::uuid IS NULL OR feed_follows.folder_id = sqlc.narg('folder_id'))
+AND (NOT sqlc.arg('saved_only')::boolean OR saved_posts.saved_at IS NOT NULL)
 ORDER BY published_at DESC
-LIMIT $1;
\ No newline at end of file
+LIMIT $1 OFFSET sqlc.arg('offset');
+
+-- name: SetPostContent :exec
+UPDATE posts
+SET content = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: GetRecentPostTimes :many
+SELECT published_at FROM posts
+WHERE feed_id = $1
+ORDER BY published_at DESC
+LIMIT $2;
\ No newline at end of file
@@ -0,0 +1,33 @@
+-- name: SetFeedRetention :exec
+UPDATE feeds
+SET retention_max_age = $2, retention_max_posts = $3, updated_at = NOW()
+WHERE id = $1;
+
+-- name: DeleteFeedPostsPublishedBefore :execrows
+DELETE FROM posts
+WHERE posts.feed_id = $1
+AND posts.published_at < $2
+AND NOT EXISTS (SELECT 1 FROM saved_posts WHERE saved_posts.post_id = posts.id);
+
+-- name: DeleteFeedPostsBeyond :execrows
+DELETE FROM posts
+WHERE posts.feed_id = sqlc.arg('feed_id')
+AND NOT EXISTS (SELECT 1 FROM saved_posts WHERE saved_posts.post_id = posts.id)
+AND posts.id NOT IN (
+    SELECT newest.id FROM posts AS newest
+    WHERE newest.feed_id = sqlc.arg('feed_id')
+    ORDER BY newest.published_at DESC, newest.created_at DESC
+    LIMIT sqlc.arg('keep')
+);
+
+-- name: GetPostByURL :one
+SELECT * FROM posts WHERE url = $1;
+
+-- name: SavePost :exec
+INSERT INTO saved_posts (user_id, post_id, saved_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: UnsavePost :execrows
+DELETE FROM saved_posts
+WHERE user_id = $1 AND post_id = $2;
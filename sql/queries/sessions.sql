@@ -0,0 +1,28 @@
+-- name: CreateSession :exec
+INSERT INTO sessions (id, created_at, last_used_at, expires_at, user_id, token_hash)
+VALUES (
+    $1,
+    NOW(),
+    NOW(),
+    $2,
+    $3,
+    $4
+);
+
+-- name: GetUserForSession :one
+SELECT users.*
+FROM sessions
+INNER JOIN users ON sessions.user_id = users.id
+WHERE sessions.token_hash = $1
+AND sessions.expires_at > sqlc.arg('now')::timestamp;
+
+-- name: TouchSession :exec
+UPDATE sessions
+SET last_used_at = NOW()
+WHERE token_hash = $1;
+
+-- name: DeleteSession :exec
+DELETE FROM sessions WHERE token_hash = $1;
+
+-- name: DeleteExpiredSessions :exec
+DELETE FROM sessions WHERE expires_at <= sqlc.arg('now')::timestamp;
@@ -0,0 +1,184 @@
+-- name: ListFolders :many
+SELECT * FROM folders ORDER BY created_at;
+
+-- name: ListFeeds :many
+SELECT * FROM feeds ORDER BY created_at;
+
+-- name: ListFeedURLHistory :many
+SELECT * FROM feed_url_history ORDER BY changed_at;
+
+-- name: ListFeedFollows :many
+SELECT * FROM feed_follows ORDER BY created_at;
+
+-- name: ListPosts :many
+SELECT * FROM posts ORDER BY created_at;
+
+-- name: ListFilterRules :many
+SELECT * FROM filter_rules ORDER BY created_at;
+
+-- name: ListPostReads :many
+SELECT * FROM post_reads ORDER BY read_at;
+
+-- name: ListFeedFollowsForUser :many
+SELECT * FROM feed_follows WHERE user_id = $1 ORDER BY created_at;
+
+-- name: ListFilterRulesForUser :many
+SELECT * FROM filter_rules WHERE user_id = $1 ORDER BY created_at;
+
+-- name: ListPostReadsForUser :many
+SELECT * FROM post_reads WHERE user_id = $1 ORDER BY read_at;
+
+-- name: ListPostsPublishedBefore :many
+SELECT * FROM posts WHERE published_at < $1 ORDER BY created_at;
+
+-- name: ListPostReadsPublishedBefore :many
+SELECT post_reads.*
+FROM post_reads
+INNER JOIN posts ON post_reads.post_id = posts.id
+WHERE posts.published_at < $1
+ORDER BY post_reads.read_at;
+
+-- name: DeletePosts :execrows
+DELETE FROM posts;
+
+-- name: DeletePostsPublishedBefore :execrows
+DELETE FROM posts WHERE published_at < $1;
+
+-- name: RestoreUser :execrows
+INSERT INTO users (id, created_at, updated_at, name, password_hash, role)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT DO NOTHING;
+
+-- name: RestoreFolder :execrows
+INSERT INTO folders (id, created_at, updated_at, name, user_id)
+SELECT
+    sqlc.arg('id')::uuid,
+    sqlc.arg('created_at')::timestamp,
+    sqlc.arg('updated_at')::timestamp,
+    sqlc.arg('name')::text,
+    users.id
+FROM users
+WHERE users.id = sqlc.arg('user_id')
+ON CONFLICT DO NOTHING;
+
+-- name: RestoreFeed :execrows
+INSERT INTO feeds (
+    id, created_at, updated_at, last_fetched_at, name, url, user_id,
+    fetch_full_text, fetch_interval, adaptive_interval, next_fetch_at,
+    ttl_minutes, skip_hours, skip_days, cache_max_age, retry_after, parse_warnings,
+    retention_max_age, retention_max_posts
+)
+SELECT
+    sqlc.arg('id')::uuid,
+    sqlc.arg('created_at')::timestamp,
+    sqlc.arg('updated_at')::timestamp,
+    sqlc.narg('last_fetched_at')::timestamp,
+    sqlc.arg('name')::text,
+    sqlc.arg('url')::text,
+    users.id,
+    sqlc.arg('fetch_full_text')::boolean,
+    sqlc.narg('fetch_interval')::integer,
+    sqlc.arg('adaptive_interval')::boolean,
+    sqlc.narg('next_fetch_at')::timestamp,
+    sqlc.narg('ttl_minutes')::integer,
+    sqlc.narg('skip_hours')::text,
+    sqlc.narg('skip_days')::text,
+    sqlc.narg('cache_max_age')::integer,
+    sqlc.narg('retry_after')::timestamp,
+    sqlc.narg('parse_warnings')::text,
+    sqlc.narg('retention_max_age')::integer,
+    sqlc.narg('retention_max_posts')::integer
+FROM users
+WHERE users.id = sqlc.arg('user_id')
+ON CONFLICT DO NOTHING;
+
+-- name: RestoreFeedURLChange :execrows
+INSERT INTO feed_url_history (id, changed_at, feed_id, old_url, new_url, merged)
+SELECT
+    sqlc.arg('id')::uuid,
+    sqlc.arg('changed_at')::timestamp,
+    feeds.id,
+    sqlc.arg('old_url')::text,
+    sqlc.arg('new_url')::text,
+    sqlc.arg('merged')::boolean
+FROM feeds
+WHERE feeds.id = sqlc.arg('feed_id')
+ON CONFLICT DO NOTHING;
+
+-- name: RestoreFeedFollow :execrows
+INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id, folder_id, display_name)
+SELECT
+    sqlc.arg('id')::uuid,
+    sqlc.arg('created_at')::timestamp,
+    sqlc.arg('updated_at')::timestamp,
+    users.id,
+    feeds.id,
+    (SELECT folders.id FROM folders WHERE folders.id = sqlc.narg('folder_id')::uuid),
+    sqlc.narg('display_name')::text
+FROM users, feeds
+WHERE users.id = sqlc.arg('user_id')
+AND feeds.id = sqlc.arg('feed_id')
+ON CONFLICT DO NOTHING;
+
+-- name: RestorePost :execrows
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, content, author)
+SELECT
+    sqlc.arg('id')::uuid,
+    sqlc.arg('created_at')::timestamp,
+    sqlc.arg('updated_at')::timestamp,
+    sqlc.arg('title')::text,
+    sqlc.arg('url')::text,
+    sqlc.narg('description')::text,
+    sqlc.arg('published_at')::timestamp,
+    feeds.id,
+    sqlc.narg('content')::text,
+    sqlc.narg('author')::text
+FROM feeds
+WHERE feeds.id = sqlc.arg('feed_id')
+ON CONFLICT DO NOTHING;
+
+-- name: RestoreFilterRule :execrows
+INSERT INTO filter_rules (id, created_at, updated_at, user_id, feed_id, pattern, is_regex, field, action)
+SELECT
+    sqlc.arg('id')::uuid,
+    sqlc.arg('created_at')::timestamp,
+    sqlc.arg('updated_at')::timestamp,
+    users.id,
+    sqlc.narg('feed_id')::uuid,
+    sqlc.arg('pattern')::text,
+    sqlc.arg('is_regex')::boolean,
+    sqlc.arg('field')::text,
+    sqlc.arg('action')::text
+FROM users
+WHERE users.id = sqlc.arg('user_id')
+AND (sqlc.narg('feed_id')::uuid IS NULL OR EXISTS (SELECT 1 FROM feeds WHERE feeds.id = sqlc.narg('feed_id')::uuid))
+ON CONFLICT DO NOTHING;
+
+-- name: RestorePostRead :execrows
+INSERT INTO post_reads (user_id, post_id, read_at)
+SELECT users.id, posts.id, sqlc.arg('read_at')::timestamp
+FROM users, posts
+WHERE users.id = sqlc.arg('user_id')
+AND posts.id = sqlc.arg('post_id')
+ON CONFLICT DO NOTHING;
+
+-- name: ListSavedPosts :many
+SELECT * FROM saved_posts ORDER BY saved_at;
+
+-- name: ListSavedPostsForUser :many
+SELECT * FROM saved_posts WHERE user_id = $1 ORDER BY saved_at;
+
+-- name: ListSavedPostsPublishedBefore :many
+SELECT saved_posts.*
+FROM saved_posts
+INNER JOIN posts ON saved_posts.post_id = posts.id
+WHERE posts.published_at < $1
+ORDER BY saved_posts.saved_at;
+
+-- name: RestoreSavedPost :execrows
+INSERT INTO saved_posts (user_id, post_id, saved_at)
+SELECT users.id, posts.id, sqlc.arg('saved_at')::timestamp
+FROM users, posts
+WHERE users.id = sqlc.arg('user_id')
+AND posts.id = sqlc.arg('post_id')
+ON CONFLICT DO NOTHING;
@@ -1,10 +1,13 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, name)
+INSERT INTO users (id, created_at, updated_at, name, password_hash, role)
 VALUES (
     $1,
     $2,
     $3,
-    $4
+    $4,
+    $5,
+    -- The first user to register becomes the administrator
+    CASE WHEN EXISTS (SELECT 1 FROM users) THEN 'user' ELSE 'admin' END
 )
 RETURNING *;
 
@@ -15,4 +18,20 @@ SELECT * FROM users WHERE name = $1;
 DELETE FROM users;
 
 -- name: GetUsers :many
-SELECT * FROM users;
\ No newline at end of file
+SELECT * FROM users ORDER BY created_at ASC;
+
+-- name: SetUserPassword :exec
+UPDATE users
+SET password_hash = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: SetUserRole :exec
+UPDATE users
+SET role = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: CountAdmins :one
+SELECT COUNT(*) FROM users WHERE role = 'admin';
+
+-- name: DeleteUser :exec
+DELETE FROM users WHERE id = $1;
\ No newline at end of file
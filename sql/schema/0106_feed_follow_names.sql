@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE feed_follows ADD COLUMN display_name TEXT;
+
+-- +goose Down
+ALTER TABLE feed_follows DROP COLUMN display_name;
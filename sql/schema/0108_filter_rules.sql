@@ -0,0 +1,23 @@
+-- +goose Up
+CREATE TABLE filter_rules(
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    feed_id UUID REFERENCES feeds (id) ON DELETE CASCADE,
+    pattern TEXT NOT NULL,
+    is_regex BOOLEAN NOT NULL DEFAULT FALSE,
+    field TEXT NOT NULL DEFAULT 'any' CHECK (field IN ('any', 'title', 'author')),
+    action TEXT NOT NULL CHECK (action IN ('hide', 'highlight', 'mark_read'))
+);
+
+CREATE TABLE post_reads(
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    post_id UUID NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+    read_at TIMESTAMP NOT NULL,
+    PRIMARY KEY(user_id, post_id)
+);
+
+-- +goose Down
+DROP TABLE post_reads;
+DROP TABLE filter_rules;
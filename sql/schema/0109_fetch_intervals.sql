@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE feeds ADD COLUMN fetch_interval INTEGER;
+ALTER TABLE feeds ADD COLUMN adaptive_interval BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE feeds ADD COLUMN next_fetch_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE feeds DROP COLUMN next_fetch_at;
+ALTER TABLE feeds DROP COLUMN adaptive_interval;
+ALTER TABLE feeds DROP COLUMN fetch_interval;
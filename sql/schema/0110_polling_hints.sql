@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE feeds ADD COLUMN ttl_minutes INTEGER;
+ALTER TABLE feeds ADD COLUMN skip_hours TEXT;
+ALTER TABLE feeds ADD COLUMN skip_days TEXT;
+ALTER TABLE feeds ADD COLUMN cache_max_age INTEGER;
+ALTER TABLE feeds ADD COLUMN retry_after TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE feeds DROP COLUMN retry_after;
+ALTER TABLE feeds DROP COLUMN cache_max_age;
+ALTER TABLE feeds DROP COLUMN skip_days;
+ALTER TABLE feeds DROP COLUMN skip_hours;
+ALTER TABLE feeds DROP COLUMN ttl_minutes;
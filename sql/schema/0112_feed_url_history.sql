@@ -0,0 +1,12 @@
+-- +goose Up
+CREATE TABLE feed_url_history(
+    id UUID PRIMARY KEY,
+    changed_at TIMESTAMP NOT NULL,
+    feed_id UUID NOT NULL REFERENCES feeds (id) ON DELETE CASCADE,
+    old_url TEXT NOT NULL,
+    new_url TEXT NOT NULL,
+    merged BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+-- +goose Down
+DROP TABLE feed_url_history;
@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'user' CHECK (role IN ('admin', 'user'));
+
+-- The earliest registered user administers an existing installation
+UPDATE users SET role = 'admin'
+WHERE id = (SELECT id FROM users ORDER BY created_at ASC LIMIT 1);
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN role;
@@ -0,0 +1,16 @@
+-- +goose Up
+-- NULL uses the global retention settings, 0 keeps posts forever
+ALTER TABLE feeds ADD COLUMN retention_max_age INTEGER;
+ALTER TABLE feeds ADD COLUMN retention_max_posts INTEGER;
+
+CREATE TABLE saved_posts(
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    post_id UUID NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+    saved_at TIMESTAMP NOT NULL,
+    PRIMARY KEY(user_id, post_id)
+);
+
+-- +goose Down
+DROP TABLE saved_posts;
+ALTER TABLE feeds DROP COLUMN retention_max_posts;
+ALTER TABLE feeds DROP COLUMN retention_max_age;
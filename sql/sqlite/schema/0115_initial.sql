@@ -0,0 +1,123 @@
+-- +goose Up
+-- SQLite schema matching the Postgres migrations up to 0115_retention.sql.
+-- Later changes get a migration here with the same version as the Postgres one.
+CREATE TABLE users(
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    name TEXT UNIQUE NOT NULL,
+    password_hash TEXT,
+    role TEXT NOT NULL DEFAULT 'user' CHECK (role IN ('admin', 'user'))
+);
+
+CREATE TABLE feeds(
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    last_fetched_at TIMESTAMP,
+    name TEXT NOT NULL,
+    url TEXT UNIQUE NOT NULL,
+    user_id TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    fetch_full_text BOOLEAN NOT NULL DEFAULT FALSE,
+    fetch_interval INTEGER,
+    adaptive_interval BOOLEAN NOT NULL DEFAULT FALSE,
+    next_fetch_at TIMESTAMP,
+    ttl_minutes INTEGER,
+    skip_hours TEXT,
+    skip_days TEXT,
+    cache_max_age INTEGER,
+    retry_after TIMESTAMP,
+    parse_warnings TEXT,
+    -- NULL uses the global retention settings, 0 keeps posts forever
+    retention_max_age INTEGER,
+    retention_max_posts INTEGER
+);
+
+CREATE TABLE folders(
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    name TEXT NOT NULL,
+    user_id TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    UNIQUE(user_id, name)
+);
+
+CREATE TABLE feed_follows(
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    feed_id TEXT NOT NULL REFERENCES feeds (id) ON DELETE CASCADE,
+    folder_id TEXT REFERENCES folders (id) ON DELETE SET NULL,
+    display_name TEXT,
+    UNIQUE(user_id, feed_id)
+);
+
+CREATE TABLE posts(
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    title TEXT NOT NULL,
+    url TEXT NOT NULL UNIQUE,
+    description TEXT,
+    published_at TIMESTAMP NOT NULL,
+    feed_id TEXT NOT NULL REFERENCES feeds (id) ON DELETE CASCADE,
+    content TEXT,
+    author TEXT
+);
+
+CREATE TABLE filter_rules(
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    feed_id TEXT REFERENCES feeds (id) ON DELETE CASCADE,
+    pattern TEXT NOT NULL,
+    is_regex BOOLEAN NOT NULL DEFAULT FALSE,
+    field TEXT NOT NULL DEFAULT 'any' CHECK (field IN ('any', 'title', 'author')),
+    action TEXT NOT NULL CHECK (action IN ('hide', 'highlight', 'mark_read'))
+);
+
+CREATE TABLE post_reads(
+    user_id TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    post_id TEXT NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+    read_at TIMESTAMP NOT NULL,
+    PRIMARY KEY(user_id, post_id)
+);
+
+CREATE TABLE feed_url_history(
+    id TEXT PRIMARY KEY,
+    changed_at TIMESTAMP NOT NULL,
+    feed_id TEXT NOT NULL REFERENCES feeds (id) ON DELETE CASCADE,
+    old_url TEXT NOT NULL,
+    new_url TEXT NOT NULL,
+    merged BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE TABLE sessions(
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    last_used_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    user_id TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    token_hash TEXT UNIQUE NOT NULL
+);
+
+CREATE TABLE saved_posts(
+    user_id TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    post_id TEXT NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+    saved_at TIMESTAMP NOT NULL,
+    PRIMARY KEY(user_id, post_id)
+);
+
+-- +goose Down
+DROP TABLE saved_posts;
+DROP TABLE sessions;
+DROP TABLE feed_url_history;
+DROP TABLE post_reads;
+DROP TABLE filter_rules;
+DROP TABLE posts;
+DROP TABLE feed_follows;
+DROP TABLE folders;
+DROP TABLE feeds;
+DROP TABLE users;
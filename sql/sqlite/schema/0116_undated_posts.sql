@@ -0,0 +1,7 @@
+-- +goose Up
+-- Posts whose feed gave no usable date were stored as published in year 1,
+-- so retention pruned them on every pass; date them when they were first seen
+UPDATE posts SET published_at = created_at WHERE published_at < '0002-01-01';
+
+-- +goose Down
+-- The zero dates carried no information, so there is nothing to put back
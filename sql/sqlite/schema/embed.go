@@ -0,0 +1,9 @@
+// Package schema embeds the goose migrations for the SQLite backend.
+package schema
+
+import "embed"
+
+// Migrations holds the migration files, applied in version order.
+//
+//go:embed *.sql
+var Migrations embed.FS